@@ -0,0 +1,126 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Code represents the kind of an error, the clients read it from the code of the error extensions
+type Code string
+
+const (
+	// CodeUnauthenticated is the code of the requests without valid credentials
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	// CodeForbidden is the code of the requests the user is not allowed to make
+	CodeForbidden Code = "FORBIDDEN"
+	// CodeNotFound is the code of the requests for a missing record
+	CodeNotFound Code = "NOT_FOUND"
+	// CodeBadUserInput is the code of the requests with an invalid argument
+	CodeBadUserInput Code = "BAD_USER_INPUT"
+	// CodeConflict is the code of the requests conflicting with the current state of a record
+	CodeConflict Code = "CONFLICT"
+	// CodeInternal is the code of the failures of the server
+	CodeInternal Code = "INTERNAL"
+)
+
+// Error represents an error whose message can be shown to the clients
+type Error struct {
+	Code    Code
+	Message string
+	// Err is the cause of the error, it is logged but never shown to the clients
+	Err error
+}
+
+// Error returns the message of the error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with the code and the message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap returns an error with the code and the message caused by err
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Unauthenticated returns an error for the requests without valid credentials
+func Unauthenticated(message string) *Error {
+	return New(CodeUnauthenticated, message)
+}
+
+// Forbidden returns an error for the requests the user is not allowed to make
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+// NotFound returns an error for the requests for a missing record
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+// BadUserInput returns an error for the requests with an invalid argument
+func BadUserInput(message string) *Error {
+	return New(CodeBadUserInput, message)
+}
+
+// Conflict returns an error for the requests conflicting with the current state of a record
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+// Internal returns an error for a failure of the server caused by err
+func Internal(message string, err error) *Error {
+	return Wrap(CodeInternal, message, err)
+}
+
+// CodeOf returns the code of the error, an empty code when it is not typed
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+
+	return ""
+}
+
+// Presenter renders the typed errors with their code in the extensions
+// the cause of an internal error is logged, the other errors are rendered like before
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	var gqlErr *gqlerror.Error = graphql.DefaultErrorPresenter(ctx, err)
+
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		return gqlErr
+	}
+
+	if appErr.Code == CodeInternal && appErr.Err != nil {
+		log.Printf("%s: %v", appErr.Message, appErr.Err)
+	}
+
+	gqlErr.Message = appErr.Message
+	errcode.Set(gqlErr, string(appErr.Code))
+
+	return gqlErr
+}
+
+// Recover logs the panic of a resolver with its stack
+// and returns an internal error, so the clients never see the details
+func Recover(ctx context.Context, err interface{}) error {
+	log.Printf("panic: %v\n%s", err, debug.Stack())
+
+	return Internal("internal server error", nil)
+}
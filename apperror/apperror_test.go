@@ -0,0 +1,95 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// fieldContext returns a context resolving the field at the root of the operation
+func fieldContext(field string) context.Context {
+	return graphql.WithFieldContext(context.Background(), &graphql.FieldContext{
+		Field: graphql.CollectedField{Field: &ast.Field{Alias: field}},
+	})
+}
+
+func TestPresenter_Code(t *testing.T) {
+	// the resolvers may wrap the errors of the services
+	var err error = fmt.Errorf("resolve blog: %w", NotFound("blog not found"))
+
+	presented := Presenter(fieldContext("blog"), err)
+
+	if presented.Message != "blog not found" {
+		t.Errorf("expected the message of the typed error, got %q", presented.Message)
+	}
+	if code := presented.Extensions["code"]; code != "NOT_FOUND" {
+		t.Errorf("expected the NOT_FOUND code, got %v", code)
+	}
+	if path := presented.Path.String(); path != "blog" {
+		t.Errorf("expected the path of the field, got %q", path)
+	}
+}
+
+func TestPresenter_Internal(t *testing.T) {
+	var cause error = errors.New("connection refused by 10.0.0.1")
+
+	presented := Presenter(fieldContext("blogs"), Internal("get blogs failed", cause))
+
+	// the cause is only logged
+	if presented.Message != "get blogs failed" {
+		t.Errorf("expected the cause to be hidden, got %q", presented.Message)
+	}
+	if code := presented.Extensions["code"]; code != "INTERNAL" {
+		t.Errorf("expected the INTERNAL code, got %v", code)
+	}
+	if !errors.Is(presented, cause) {
+		t.Error("expected the cause to be kept in the chain")
+	}
+}
+
+func TestPresenter_Untyped(t *testing.T) {
+	presented := Presenter(fieldContext("blog"), errors.New("something else"))
+
+	if presented.Message != "something else" {
+		t.Errorf("expected the message to be kept, got %q", presented.Message)
+	}
+	if _, ok := presented.Extensions["code"]; ok {
+		t.Errorf("expected no code, got %v", presented.Extensions)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	err := Recover(fieldContext("blog"), "runtime error: invalid memory address")
+
+	if CodeOf(err) != CodeInternal {
+		t.Errorf("expected an internal error, got %v", CodeOf(err))
+	}
+	if err.Error() != "internal server error" {
+		t.Errorf("expected the panic to be hidden, got %q", err.Error())
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		code Code
+	}{
+		{Unauthenticated("access denied"), CodeUnauthenticated},
+		{Forbidden("access denied"), CodeForbidden},
+		{BadUserInput("id is invalid"), CodeBadUserInput},
+		{Conflict("email is already registered"), CodeConflict},
+		{fmt.Errorf("wrapped: %w", NotFound("blog not found")), CodeNotFound},
+		{errors.New("untyped"), ""},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		if code := CodeOf(test.err); code != test.code {
+			t.Errorf("%v: expected the code %q, got %q", test.err, test.code, code)
+		}
+	}
+}
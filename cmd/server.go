@@ -1,22 +1,94 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
 	"github.com/0x726f6f6b6965/go-simple-graphql/database"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/middleware"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
+	"github.com/0x726f6f6b6965/go-simple-graphql/ratelimit"
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
 	"github.com/joho/godotenv"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/go-chi/chi/v5"
 )
 
 const defaultPort = "8080"
 
+// tokenPruneInterval is how often the expired tokens are removed
+const tokenPruneInterval = time.Hour
+
+// blogPublishInterval is how often the scheduled blogs are checked for publication
+const blogPublishInterval = time.Minute
+
+// blogPurgeInterval is how often the trash is checked for the blogs to remove
+const blogPurgeInterval = time.Hour
+
+// defaultTrashRetention is how long the deleted blogs are kept in the trash
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// websocketKeepAliveInterval is how often the idle websocket connections are pinged
+const websocketKeepAliveInterval = 10 * time.Second
+
+// keyRotationInterval is how often the key directory is checked for new or expired keys
+const keyRotationInterval = time.Minute
+
+// defaultMaxDepth is the number of nested fields an operation can select
+const defaultMaxDepth = 10
+
+// defaultMaxComplexity is the cost budget of an operation
+const defaultMaxComplexity = 5000
+
+// queryLimits represents the limits checked before an operation is executed
+type queryLimits struct {
+	maxDepth      int
+	maxComplexity int
+	// reportCost adds the cost of the operation to the extensions of the response
+	reportCost bool
+}
+
+// defaultRequestRate is the number of requests a client can make per minute
+const defaultRequestRate = 300
+
+// defaultAuthRate is the number of times a client can sign in or sign up per minute
+const defaultAuthRate = 5
+
+// defaultWriteRate is the number of blogs or comments a client can write per minute
+const defaultWriteRate = 30
+
+// rateLimits represents the number of requests a client can make per minute
+type rateLimits struct {
+	requests int
+	// auth limits the login, the registration and the token refresh
+	auth int
+	// writes limits the new blogs and comments
+	writes int
+}
+
+// handlerConfig represents the settings of the GraphQL handler, they are read once when the server starts
+type handlerConfig struct {
+	limits      queryLimits
+	rates       rateLimits
+	loginPolicy service.LoginPolicy
+	// events delivers the changes to the subscriptions, the background jobs publish to it as well
+	events *service.Events
+}
+
 func main() {
 	godotenv.Load()
 	port := os.Getenv("PORT")
@@ -24,12 +96,21 @@ func main() {
 		port = defaultPort
 	}
 
-	var handler *chi.Mux = NewGraphQLHandler()
+	// load the signing keys, the HS256 secret is used without a key directory
+	if os.Getenv("JWT_KEY_DIR") != "" {
+		keys, err := openKeySet(os.Getenv("JWT_KEY_DIR"), os.Getenv("JWT_SIGNING_ALGORITHM"), os.Getenv("JWT_KEY_ROTATION_HOURS_COUNT"))
+		if err != nil {
+			log.Fatalf("Cannot load the signing keys: %v\n", err)
+		}
+		utils.UseKeySet(keys)
+		go keys.Run(context.Background(), keyRotationInterval)
+	}
 
 	// connect to the database
+	log.Println(os.Getenv("DATABASE_DRIVER"))
 	log.Println(os.Getenv("DATABASE_NAME"))
 	log.Println(os.Getenv("MONGO_URI"))
-	err := database.Connect(os.Getenv("DATABASE_NAME"))
+	store, err := openStore(os.Getenv("DATABASE_DRIVER"), os.Getenv("DATABASE_NAME"), os.Getenv("DATABASE_DSN"))
 	if err != nil {
 
 		log.Fatalf("Cannot connect to the database: %v\n", err)
@@ -37,24 +118,307 @@ func main() {
 
 	log.Println("Connected to the database")
 
+	retention, err := trashRetention(os.Getenv("BLOG_TRASH_RETENTION_DAYS_COUNT"))
+	if err != nil {
+		log.Fatalf("Cannot read the trash retention: %v\n", err)
+	}
+
+	// fail early when the limits or the login policy are misconfigured
+	config, err := readHandlerConfig()
+	if err != nil {
+		log.Fatalf("Cannot read the configuration: %v\n", err)
+	}
+
+	// remove the tokens that have expired in the background
+	go service.NewUserService(store.Users(), store.Tokens(), nil).PruneTokens(context.Background(), tokenPruneInterval)
+
+	// the background jobs publish to the events read by the subscriptions of the handler
+	var blogService *service.BlogService = service.NewBlogService(store.Blogs(), store.Comments(), store.Revisions(), store.Reactions(), config.events)
+
+	// publish the scheduled blogs when their publication time comes
+	go blogService.PublishScheduledBlogs(context.Background(), blogPublishInterval)
+
+	// empty the trash of the blogs deleted before the retention period
+	go blogService.PurgeTrashedBlogs(context.Background(), blogPurgeInterval, retention)
+
+	var handler *chi.Mux = NewGraphQLHandler(store, config)
+
 	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
 	log.Fatal(http.ListenAndServe(":"+port, handler))
 }
 
+// openStore returns the storage backend selected by the driver name
+func openStore(driver string, dbName string, dsn string) (database.Store, error) {
+	switch driver {
+	case "", "mongo":
+		// connect to the MongoDB database
+		if err := database.Connect(dbName); err != nil {
+			return nil, err
+		}
+
+		store := database.NewMongoStore(database.Mongo.Database)
+		if err := store.Migrate(context.Background()); err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "memory":
+		// keep all data inside the process
+		return database.NewMemoryStore(), nil
+	case database.SQLite:
+		// use a private in-memory database when no file is given
+		if dsn == "" {
+			dsn = ":memory:"
+		}
+		return database.OpenSQLStore(context.Background(), database.SQLite, dsn)
+	case database.Postgres:
+		return database.OpenSQLStore(context.Background(), database.Postgres, dsn)
+	}
+
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// openKeySet returns the signing keys of the directory
+// the keys are signed with EdDSA and rotated manually unless configured otherwise
+func openKeySet(dir string, algorithm string, rotationHours string) (*utils.KeySet, error) {
+	if algorithm == "" {
+		algorithm = utils.EdDSA
+	}
+
+	var rotation time.Duration
+	if rotationHours != "" {
+		hoursCount, err := strconv.Atoi(rotationHours)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key rotation period %q", rotationHours)
+		}
+		rotation = time.Hour * time.Duration(hoursCount)
+	}
+
+	return utils.NewKeySet(dir, algorithm, rotation)
+}
+
+// trashRetention returns how long the deleted blogs are kept in the trash
+// the default retention is used when no number of days is given
+func trashRetention(days string) (time.Duration, error) {
+	if days == "" {
+		return defaultTrashRetention, nil
+	}
+
+	daysCount, err := strconv.Atoi(days)
+	if err != nil || daysCount < 0 {
+		return 0, fmt.Errorf("invalid trash retention %q", days)
+	}
+
+	return 24 * time.Hour * time.Duration(daysCount), nil
+}
+
+// defaultHandlerConfig returns the settings used when the environment sets none
+func defaultHandlerConfig() handlerConfig {
+	return handlerConfig{
+		limits:      queryLimits{maxDepth: defaultMaxDepth, maxComplexity: defaultMaxComplexity, reportCost: true},
+		rates:       rateLimits{requests: defaultRequestRate, auth: defaultAuthRate, writes: defaultWriteRate},
+		loginPolicy: service.DefaultLoginPolicy,
+		events:      service.NewEvents(),
+	}
+}
+
+// readHandlerConfig returns the settings of the GraphQL handler read from the environment
+func readHandlerConfig() (handlerConfig, error) {
+	limits, err := readQueryLimits(os.Getenv("GRAPHQL_MAX_DEPTH_COUNT"), os.Getenv("GRAPHQL_MAX_COMPLEXITY_COUNT"), os.Getenv("GRAPHQL_REPORT_COST"))
+	if err != nil {
+		return handlerConfig{}, fmt.Errorf("query limits: %w", err)
+	}
+
+	rates, err := readRateLimits(os.Getenv("RATE_LIMIT_REQUESTS_COUNT"), os.Getenv("RATE_LIMIT_AUTH_COUNT"), os.Getenv("RATE_LIMIT_WRITES_COUNT"))
+	if err != nil {
+		return handlerConfig{}, fmt.Errorf("rate limits: %w", err)
+	}
+
+	loginPolicy, err := readLoginPolicy(os.Getenv("LOGIN_MAX_FAILURES_COUNT"), os.Getenv("LOGIN_MAX_IP_FAILURES_COUNT"), os.Getenv("LOGIN_LOCKOUT_MINUTES_COUNT"))
+	if err != nil {
+		return handlerConfig{}, fmt.Errorf("login policy: %w", err)
+	}
+
+	return handlerConfig{limits: limits, rates: rates, loginPolicy: loginPolicy, events: service.NewEvents()}, nil
+}
+
+// readQueryLimits returns the depth and the cost budget of the operations
+// the default limits are used when no number is given, the cost is reported unless disabled
+func readQueryLimits(depth string, complexity string, reportCost string) (queryLimits, error) {
+	depthCount, err := readCount(depth, defaultMaxDepth, "max depth")
+	if err != nil {
+		return queryLimits{}, err
+	}
+
+	complexityCount, err := readCount(complexity, defaultMaxComplexity, "max complexity")
+	if err != nil {
+		return queryLimits{}, err
+	}
+
+	var report bool = true
+	if reportCost != "" {
+		if report, err = strconv.ParseBool(reportCost); err != nil {
+			return queryLimits{}, fmt.Errorf("invalid cost report %q", reportCost)
+		}
+	}
+
+	return queryLimits{maxDepth: depthCount, maxComplexity: complexityCount, reportCost: report}, nil
+}
+
+// readRateLimits returns the number of requests a client can make per minute
+// the default rates are used when no number is given
+func readRateLimits(requests string, auth string, writes string) (rateLimits, error) {
+	requestCount, err := readCount(requests, defaultRequestRate, "request rate")
+	if err != nil {
+		return rateLimits{}, err
+	}
+
+	authCount, err := readCount(auth, defaultAuthRate, "authentication rate")
+	if err != nil {
+		return rateLimits{}, err
+	}
+
+	writeCount, err := readCount(writes, defaultWriteRate, "write rate")
+	if err != nil {
+		return rateLimits{}, err
+	}
+
+	return rateLimits{requests: requestCount, auth: authCount, writes: writeCount}, nil
+}
+
+// readLoginPolicy returns the protection of the logins against password guessing
+// the default policy is used when no number is given
+func readLoginPolicy(maxFailures string, maxIPFailures string, lockoutMinutes string) (service.LoginPolicy, error) {
+	var policy service.LoginPolicy = service.DefaultLoginPolicy
+
+	accountCount, err := readCount(maxFailures, policy.MaxAccountFailures, "max failed logins")
+	if err != nil {
+		return service.LoginPolicy{}, err
+	}
+
+	ipCount, err := readCount(maxIPFailures, policy.MaxIPFailures, "max failed logins of an IP address")
+	if err != nil {
+		return service.LoginPolicy{}, err
+	}
+
+	minutesCount, err := readCount(lockoutMinutes, int(policy.LockoutDuration/time.Minute), "lockout duration")
+	if err != nil {
+		return service.LoginPolicy{}, err
+	}
+
+	policy.MaxAccountFailures = accountCount
+	policy.MaxIPFailures = ipCount
+	policy.LockoutDuration = time.Minute * time.Duration(minutesCount)
+
+	return policy, nil
+}
+
+// readCount returns the positive number of the setting, or the default count when no number is given
+func readCount(value string, defaultCount int, name string) (int, error) {
+	if value == "" {
+		return defaultCount, nil
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return count, nil
+}
+
+// jwksHandler serves the public keys verifying the access tokens
+func jwksHandler(w http.ResponseWriter, r *http.Request) {
+	// no key is published while the tokens are signed with the HS256 secret
+	var set utils.JSONWebKeySet = utils.JSONWebKeySet{Keys: []utils.JSONWebKey{}}
+	if keys := utils.CurrentKeySet(); keys != nil {
+		set = keys.JWKS()
+	}
+
+	// the verifiers may cache the keys until the next key starts signing
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(utils.JWKSMaxAge/time.Second)))
+	json.NewEncoder(w).Encode(set)
+}
+
+// newGraphQLServer returns a GraphQL server serving the queries and the mutations over HTTP
+// and the subscriptions over websockets and server-sent events
+// the operations deeper or more expensive than the limits are rejected before they are executed
+// and the clients calling the sensitive fields too often are refused
+func newGraphQLServer(schema graphql.ExecutableSchema, userService *service.UserService, limits queryLimits, rates rateLimits) *handler.Server {
+	srv := handler.New(schema)
+
+	// the websockets speak both the graphql-ws and the graphql-transport-ws protocols
+	// and are authenticated when the connection is initialized
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: websocketKeepAliveInterval,
+		InitFunc:              middleware.NewWebsocketInit(userService),
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	// the server-sent events are requested with a POST, so they are checked first
+	srv.AddTransport(transport.SSE{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New(1000))
+
+	// the errors of the services are rendered with their code,
+	// and the panics of the resolvers are hidden behind an internal error
+	srv.SetErrorPresenter(apperror.Presenter)
+	srv.SetRecoverFunc(apperror.Recover)
+
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New(100),
+	})
+	srv.Use(middleware.DepthLimit{MaxDepth: limits.maxDepth})
+	srv.Use(extension.FixedComplexityLimit(limits.maxComplexity))
+
+	// the cost of the operation is reported in the extensions of the response
+	if limits.reportCost {
+		srv.AroundResponses(middleware.NewCostMiddleware())
+	}
+
+	// the password guessing and the spam are slowed down by stricter budgets
+	srv.AroundFields(middleware.NewFieldRateLimiter(map[string]ratelimit.Rate{
+		"Mutation.login":        ratelimit.PerMinute(rates.auth),
+		"Mutation.register":     ratelimit.PerMinute(rates.auth),
+		"Mutation.refreshToken": ratelimit.PerMinute(rates.auth),
+		"Mutation.newBlog":      ratelimit.PerMinute(rates.writes),
+		"Mutation.addComment":   ratelimit.PerMinute(rates.writes),
+	}))
+
+	// the input fields are checked against their constraints before the resolvers run
+	srv.Use(&middleware.Constraints{})
+
+	// the users referenced by the blogs and the comments are loaded in batches
+	srv.AroundResponses(middleware.NewLoadersMiddleware(userService))
+
+	return srv
+}
+
 // NewGraphQLHandler returns handler for GraphQL application
-func NewGraphQLHandler() *chi.Mux {
+func NewGraphQLHandler(store database.Store, config handlerConfig) *chi.Mux {
 	// create a new router
 	var router *chi.Mux = chi.NewRouter()
 
+	var userService *service.UserService = service.NewUserService(store.Users(), store.Tokens(), nil)
+
 	// use the middleware component
-	router.Use(middleware.NewMiddleware())
+	router.Use(middleware.NewMiddleware(userService))
 
 	// create a GraphQL server
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{}}))
+	srv := newGraphQLServer(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  graph.NewResolver(store, config.events, config.loginPolicy),
+		Directives: graph.NewDirectives(),
+		Complexity: graph.NewComplexity(),
+	}), userService, config.limits, config.rates)
 
 	// assign some handlers for the GraphQL server
 	router.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	router.Handle("/query", srv)
+	// the requests are counted for the authenticated user or the IP address
+	router.With(middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(ratelimit.PerMinute(config.rates.requests)))).Handle("/query", srv)
+	router.Get("/.well-known/jwks.json", jwksHandler)
 
 	// return the handler
 	return router
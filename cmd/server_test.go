@@ -1,21 +1,35 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/0x726f6f6b6965/go-simple-graphql/database"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
 	"github.com/0x726f6f6b6965/go-simple-graphql/mock"
 	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
 	"github.com/joho/godotenv"
 
+	"github.com/99designs/gqlgen/client"
+
 	"github.com/steinfletcher/apitest"
 )
 
+// store represents the storage backend used by the tests
+var store database.Store
+
 func TestMain(m *testing.M) {
 	setup()
 	code := m.Run()
@@ -23,21 +37,45 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// testConfig returns the default settings of the handler
+// the responses are compared without the cost, the tests of the query limits report it
+func testConfig() handlerConfig {
+	var config handlerConfig = defaultHandlerConfig()
+	config.limits.reportCost = false
+
+	return config
+}
+
 func setup() {
 	var err error = godotenv.Load("./../.env")
 	if err != nil {
 		fmt.Println("Error while loading .env file")
 	}
-	err = database.Connect(os.Getenv("TEST_DATABASE_NAME"))
+
+	// the tests run without outside services unless a driver is configured
+	var driver string = os.Getenv("TEST_DATABASE_DRIVER")
+	if driver == "" {
+		driver = "memory"
+	}
+
+	// make sure the tokens can be generated without the .env file
+	if os.Getenv("JWT_SECRET_KEY") == "" {
+		os.Setenv("JWT_SECRET_KEY", "secret")
+	}
+	if os.Getenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT") == "" {
+		os.Setenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT", "15")
+	}
+
+	store, err = openStore(driver, os.Getenv("TEST_DATABASE_NAME"), os.Getenv("TEST_DATABASE_DSN"))
 	if err != nil {
 		fmt.Printf("Cannot connect to the database: %v, %s\n", err, os.Getenv("TEST_DATABASE_NAME"))
-		return
+		os.Exit(1)
 	}
 	fmt.Printf("\033[1;33m%s\033[0m", "> Setup completed\n")
 }
 
 func teardown() {
-	database.Mongo.Client.Disconnect(context.Background())
+	store.Close(context.Background())
 	fmt.Printf("\033[1;33m%s\033[0m", "> Teardown completed")
 	fmt.Printf("\n")
 }
@@ -49,7 +87,7 @@ func TestSignup_Success(t *testing.T) {
 		// run the cleanup() function after the test is finished
 		Observe(cleanup).
 		// add an application to be tested
-		Handler(NewGraphQLHandler()).
+		Handler(NewGraphQLHandler(store, testConfig())).
 		// send a POST request for sign-up
 		Post("/query").
 		// define the query for sign-up
@@ -57,8 +95,8 @@ func TestSignup_Success(t *testing.T) {
             register(input:{
                 email:"test@test.com",
                 username:"test",
-                password:"123123"
-            })
+                password:"12312312"
+            }) { accessToken refreshToken }
         }`).
 		// expect the status code is equals to 200
 		Expect(t).
@@ -66,17 +104,65 @@ func TestSignup_Success(t *testing.T) {
 		End()
 }
 
+func TestSignup_Conflict(t *testing.T) {
+	t.Cleanup(func() { mock.CleanSeeders(store) })
+
+	// create a registered user
+	var user model.User = getUser()
+
+	// the email cannot be registered again, whatever its case
+	for _, email := range []string{*user.Email, strings.ToUpper(*user.Email)} {
+		apitest.New().
+			Handler(NewGraphQLHandler(store, testConfig())).
+			Post("/query").
+			GraphQLQuery(`mutation {
+				register(input: {email: "` + email + `", username: "other", password: "12312312"}) { accessToken }
+			}`).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"errors": [{"message": "email is already registered", "path": ["register"], "extensions": {"code": "CONFLICT"}}], "data": null}`).
+			End()
+	}
+}
+
+func TestSignup_Constraints(t *testing.T) {
+	// every broken constraint of the input is reported, with the path of the field
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`mutation {
+			register(input: {email: "not an email", username: "a!", password: "short"}) { accessToken }
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [
+				{"message": "input.username must have at least 3 characters", "path": ["register"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.username"}},
+				{"message": "input.username must match the pattern ^[A-Za-z0-9_.-]+$", "path": ["register"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.username"}},
+				{"message": "input.email must be a valid email", "path": ["register"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.email"}},
+				{"message": "input.password must have at least 8 characters", "path": ["register"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.password"}}
+			],
+			"data": null
+		}`).
+		End()
+
+	// nothing is registered
+	if _, err := store.Users().GetUserByEmail(context.Background(), "not an email"); !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("expected no user to be registered, got %v", err)
+	}
+}
+
 func TestLogin_Success(t *testing.T) {
 
 	// create a new user data
-	user := getUser()
+	user, password := getUserWithPassword()
 
 	// create a query to login
 	var query string = `mutation {
         login(input:{
-            email:"` + user.Email + `",
-            password:"` + user.Password + `"
-        })
+            email:"` + *user.Email + `",
+            password:"` + password + `"
+        }) { accessToken refreshToken }
     }`
 
 	// create a test
@@ -84,7 +170,7 @@ func TestLogin_Success(t *testing.T) {
 		// run the cleanup() function after the test is finished
 		Observe(cleanup).
 		// add an application to be tested
-		Handler(NewGraphQLHandler()).
+		Handler(NewGraphQLHandler(store, testConfig())).
 		// send a POST request for login
 		Post("/query").
 		// define the query for the login
@@ -102,7 +188,8 @@ func TestLogin_Failed(t *testing.T) {
 				"message": "login failed, invalid email or password",
 				"path": [
 					"login"
-				]
+				],
+				"extensions": {"code": "UNAUTHENTICATED"}
 			}
 		],
 		"data": null
@@ -112,12 +199,12 @@ func TestLogin_Failed(t *testing.T) {
 		login(input:{
 			email:"wrong@mail.com",
 			password:"123456"
-		})
+		}) { accessToken }
 	}`
 
 	apitest.New().
 		Observe(cleanup).
-		Handler(NewGraphQLHandler()).
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
 		GraphQLQuery(query).
 		Expect(t).
@@ -126,305 +213,623 @@ func TestLogin_Failed(t *testing.T) {
 		End()
 }
 
-func TestGetBlogs_Success(t *testing.T) {
-	// create a test
+func TestRefreshToken_Success(t *testing.T) {
+	// log in to get the first pair of tokens
+	user, password := getUserWithPassword()
+	var token model.AuthToken = login(t, user, password)
+
+	// exchange the refresh token for a new pair
+	var refreshed model.AuthToken = refreshToken(t, token.RefreshToken)
+
+	if refreshed.RefreshToken == token.RefreshToken {
+		t.Fatal("expected the refresh token to be rotated")
+	}
+
+	// the new access token can be used for authentication
 	apitest.New().
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for getting all blogs
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// define the query for getting all blogs
-		GraphQLQuery(`query { blogs { title } }`).
-		// expect the status code is equals to 200
+		Header("Authorization", "Bearer "+refreshed.AccessToken).
+		GraphQLQuery(`mutation { newBlog(input: {title: "title", content: "content"}) { title } }`).
 		Expect(t).
 		Status(http.StatusOK).
+		Body(`{"data": {"newBlog": {"title": "title"}}}`).
 		End()
 }
 
-func TestGetBlog_Success(t *testing.T) {
-	// create a blog data
-	var blog model.Blog = getBlog()
+func TestRefreshToken_ReuseRevokesSession(t *testing.T) {
+	user, password := getUserWithPassword()
 
-	// create a query for getting a blog by ID
-	var query string = `query {
-        blog(id:"` + blog.ID + `") {
-            title
-            content
-        }
-    }`
+	var (
+		token     model.AuthToken = login(t, user, password)
+		refreshed model.AuthToken = refreshToken(t, token.RefreshToken)
+	)
 
-	// create an expected result body
 	var result string = `{
-        "data": {
-            "blog": {
-                "title": "` + blog.Title + `",
-                "content": "` + blog.Content + `"
-            }
-        }
-    }`
+		"errors": [
+			{
+				"message": "refresh token has been revoked",
+				"path": [
+					"refreshToken"
+				],
+				"extensions": {"code": "UNAUTHENTICATED"}
+			}
+		],
+		"data": null
+	}`
 
-	// create a test
+	// using the first refresh token again is detected as a reuse
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(refreshTokenQuery(token.RefreshToken)).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(result).
+		End()
+
+	// the reuse revokes the tokens issued after the reused token as well
 	apitest.New().
-		// run the cleanup() function after the test is finished
 		Observe(cleanup).
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for getting a blog by ID
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// define the query for getting a blog by ID
-		GraphQLQuery(query).
-		// expect the status code is equals to 200
+		GraphQLQuery(refreshTokenQuery(refreshed.RefreshToken)).
 		Expect(t).
 		Status(http.StatusOK).
-		// expect the response body is equal to the expected result
 		Body(result).
 		End()
 }
 
-func TestGetBlog_Failed(t *testing.T) {
-	// create a query to get the blog by ID
-	var query string = `query {
-        blog(id:"62c24f3b4896bb25c21e49b9") {
-            title
-            content
-        }
-    }`
+func TestRefreshToken_Failed(t *testing.T) {
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(refreshTokenQuery("invalid")).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [
+				{
+					"message": "refresh token is invalid",
+					"path": [
+						"refreshToken"
+					],
+					"extensions": {"code": "UNAUTHENTICATED"}
+				}
+			],
+			"data": null
+		}`).
+		End()
+}
 
-	// create an expected result body
-	var result string = `{
-        "errors": [
-            {
-                "message": "blog not found",
-                "path": [
-                    "blog"
-                ]
-            }
-        ],
-        "data": null
-    }`
+func TestLogout_Success(t *testing.T) {
+	user, password := getUserWithPassword()
+	var token model.AuthToken = login(t, user, password)
 
-	// create a test
+	// log out of the session
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", "Bearer "+token.AccessToken).
+		GraphQLQuery(`mutation { logout(input: {refreshToken: "` + token.RefreshToken + `"}) }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"logout": true}}`).
+		End()
+
+	// the access token is no longer accepted
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", "Bearer "+token.AccessToken).
+		GraphQLQuery(`query { blogs { title } }`).
+		Expect(t).
+		Status(http.StatusForbidden).
+		End()
+
+	// the refresh token of the session is revoked as well
 	apitest.New().
-		// run the cleanup() function after the test is finished
 		Observe(cleanup).
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for getting a blog by ID
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// define the query for getting a blog by ID
-		GraphQLQuery(query).
-		// expect the status code is equals to 200
+		GraphQLQuery(refreshTokenQuery(token.RefreshToken)).
 		Expect(t).
 		Status(http.StatusOK).
-		// expect the response body is equal to the expected result
-		Body(result).
+		Body(`{
+			"errors": [
+				{
+					"message": "refresh token has been revoked",
+					"path": [
+						"refreshToken"
+					],
+					"extensions": {"code": "UNAUTHENTICATED"}
+				}
+			],
+			"data": null
+		}`).
 		End()
 }
 
-func TestCreateBlog_Success(t *testing.T) {
-	// create a new user data
-	var author model.User = getUser()
+func TestLogoutAllSessions_Success(t *testing.T) {
+	user, password := getUserWithPassword()
 
-	// generate a JWT token for authentication
-	var token string = getJWTToken(author)
+	var (
+		first  model.AuthToken = login(t, user, password)
+		second model.AuthToken = login(t, user, password)
+	)
 
-	// create a query for creating a new blog
-	var query string = `mutation {
-        newBlog(input:{
-            title:"my blog",
-            content:"this is the content"
-        }) {
-            title
-            content
-        }
-    }`
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", "Bearer "+first.AccessToken).
+		GraphQLQuery(`mutation { logoutAllSessions }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"logoutAllSessions": true}}`).
+		End()
 
-	// create an expected result body
-	var result string = `{
-        "data": {
-            "newBlog": {
-                "title": "my blog",
-                "content": "this is the content"
-            }
-        }
-    }`
+	// the access tokens of every session are no longer accepted
+	for _, token := range []model.AuthToken{first, second} {
+		apitest.New().
+			Handler(NewGraphQLHandler(store, testConfig())).
+			Post("/query").
+			Header("Authorization", "Bearer "+token.AccessToken).
+			GraphQLQuery(`query { blogs { title } }`).
+			Expect(t).
+			Status(http.StatusForbidden).
+			End()
+	}
+
+	// a new login starts a valid session
+	var token model.AuthToken = login(t, user, password)
 
-	// create a test
 	apitest.New().
-		// run the cleanup() function after the test is finished
 		Observe(cleanup).
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for creating a new blog
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// attach the JWT token to the Authorization header
-		Header("Authorization", token).
-		// define the query for creating a new blog
-		GraphQLQuery(query).
-		// expect the status code is equals to 200
+		Header("Authorization", "Bearer "+token.AccessToken).
+		GraphQLQuery(`query { blogs { title } }`).
 		Expect(t).
 		Status(http.StatusOK).
-		// expect the response body is equal to the expected result
-		Body(result).
 		End()
 }
 
-func TestCreateBlog_Failed(t *testing.T) {
-	// create a query for creating a new blog
-	var query string = `mutation {
-        newBlog(input:{
-            title:"my blog",
-            content:"this is the content"
-        }) {
-            title
-            content
-        }
-    }`
+func TestLogout_Failed(t *testing.T) {
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`mutation { logout }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [
+				{
+					"message": "access denied",
+					"path": [
+						"logout"
+					],
+					"extensions": {"code": "UNAUTHENTICATED"}
+				}
+			],
+			"data": null
+		}`).
+		End()
+}
 
-	// create an expected result body
-	var result string = `{
-        "errors": [
-            {
-                "message": "access denied",
-                "path": [
-                    "newBlog"
-                ]
-            }
-        ],
-        "data": null
-    }`
+func TestCreateBlog_ReaderForbidden(t *testing.T) {
+	var token string = getJWTToken(getUserWithRole(model.RoleReader))
 
-	// create a test
 	apitest.New().
-		// run the cleanup() function after the test is finished
 		Observe(cleanup).
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for creating a new blog
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// define the query for creating a new blog
-		GraphQLQuery(query).
-		// expect the status code is equals to 200
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { newBlog(input: {title: "title", content: "content"}) { title } }`).
 		Expect(t).
 		Status(http.StatusOK).
-		// expect the response body is equal to the expected result
-		Body(result).
+		Body(`{
+			"errors": [
+				{
+					"message": "access denied, the AUTHOR role is required",
+					"path": [
+						"newBlog"
+					],
+					"extensions": {"code": "FORBIDDEN"}
+				}
+			],
+			"data": null
+		}`).
 		End()
 }
 
-func TestEditBlog_Success(t *testing.T) {
-	// create a new blog data
-	var blog model.Blog = getBlog()
-	// generate a JWT token for authentication
-	var token string = getJWTToken(*blog.Author)
-
-	// create a query for updating a blog
-	var query string = `mutation {
-        editBlog(input:{
-            blogId:"` + blog.ID + `"
-            title:"my blog",
-            content:"this is the content"
-        }) {
-            title
-            content
-        }
-    }`
+func TestEditBlog_Editor(t *testing.T) {
+	var (
+		blog  model.Blog = getBlog()
+		token string     = getJWTToken(getUserWithRole(model.RoleEditor))
+	)
 
-	// create an expected result body
-	var result string = `{
-        "data": {
-            "editBlog": {
-                "title": "my blog",
-                "content": "this is the content"
-            }
-        }
-    }`
+	// editors can edit the blogs of every author
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation {
+			editBlog(input: {blogId: "` + blog.ID + `", title: "edited", content: "content"}) {
+				title
+				author { id }
+			}
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"editBlog": {"title": "edited", "author": {"id": "` + blog.Author.ID + `"}}}}`).
+		End()
 
-	// create a test
+	// but only admins can delete them
 	apitest.New().
-		// run the cleanup() function after the test is finished
 		Observe(cleanup).
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for updating a blog
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// attach the JWT token to the Authorization header
 		Header("Authorization", token).
-		// define the query for updating a blog
-		GraphQLQuery(query).
-		// expect the status code is equals to 200
+		GraphQLQuery(`mutation { deleteBlog(input: {blogId: "` + blog.ID + `"}) }`).
 		Expect(t).
 		Status(http.StatusOK).
-		// expect the response body is equal to the expected result
-		Body(result).
+		Body(`{"errors": [{"message": "access denied", "path": ["deleteBlog"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`).
 		End()
 }
 
-func TestEditBlog_Failed(t *testing.T) {
-	// create a new blog data
-	var blog model.Blog = getBlog()
+func TestDeleteBlog_Admin(t *testing.T) {
+	var (
+		blog  model.Blog = getBlog()
+		token string     = getJWTToken(getUserWithRole(model.RoleAdmin))
+	)
 
-	// create a query for updating a blog
-	var query string = `mutation {
-        editBlog(input:{
-            blogId:"` + blog.ID + `"
-            title:"my blog",
-            content:"this is the content"
-        }) {
-            title
-            content
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { deleteBlog(input: {blogId: "` + blog.ID + `"}) }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"deleteBlog": true}}`).
+		End()
+}
+
+func TestSetUserRole_Success(t *testing.T) {
+	var (
+		user  model.User = getUser()
+		token string     = getJWTToken(getUserWithRole(model.RoleAdmin))
+	)
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { setUserRole(input: {userId: "` + user.ID + `", role: EDITOR}) { id role } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"setUserRole": {"id": "` + user.ID + `", "role": "EDITOR"}}}`).
+		End()
+}
+
+func TestSetUserRole_Forbidden(t *testing.T) {
+	var user model.User = getUser()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(user)).
+		GraphQLQuery(`mutation { setUserRole(input: {userId: "` + user.ID + `", role: ADMIN}) { role } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [
+				{
+					"message": "access denied, the ADMIN role is required",
+					"path": [
+						"setUserRole"
+					],
+					"extensions": {"code": "FORBIDDEN"}
+				}
+			],
+			"data": null
+		}`).
+		End()
+}
+
+func TestGetBlog_AuthorEmailVisibility(t *testing.T) {
+	var (
+		blog  model.Blog = getBlog()
+		query string     = `query { blog(id: "` + blog.ID + `") { author { username email } } }`
+	)
+
+	// the blog only stores a snapshot of the author
+	author, err := store.Users().GetUserByID(context.Background(), blog.Author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the email is hidden from the other users
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"author": {"username": "` + blog.Author.Username + `", "email": null}}}}`).
+		End()
+
+	// but visible to the author and the admins
+	for _, viewer := range []model.User{*author.Model(), getUserWithRole(model.RoleAdmin)} {
+		apitest.New().
+			Handler(NewGraphQLHandler(store, testConfig())).
+			Post("/query").
+			Header("Authorization", getJWTToken(viewer)).
+			GraphQLQuery(query).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"data": {"blog": {"author": {"username": "` + blog.Author.Username + `", "email": "` + author.Email + `"}}}}`).
+			End()
+	}
+
+	// the password is not part of the schema
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blog(id: "` + blog.ID + `") { author { password } } }`).
+		Expect(t).
+		Status(http.StatusUnprocessableEntity).
+		End()
+}
+
+func TestJWKS_Success(t *testing.T) {
+	// no public key is published while the tokens are signed with the HS256 secret
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Get("/.well-known/jwks.json").
+		Expect(t).
+		Status(http.StatusOK).
+		Header("Cache-Control", "public, max-age=300").
+		Body(`{"keys": []}`).
+		End()
+}
+
+func TestGetBlogs_Success(t *testing.T) {
+	// create a test
+	apitest.New().
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for getting all blogs
+		Post("/query").
+		// define the query for getting all blogs
+		GraphQLQuery(`query { blogs { title } }`).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+func TestGetBlogsConnection_Success(t *testing.T) {
+	// create three blogs
+	for i := 0; i < 3; i++ {
+		getBlog()
+	}
+
+	// create a query for the first page of blogs
+	var query string = `query {
+        blogsConnection(first: 2) {
+            edges { cursor node { title } }
+            pageInfo { hasNextPage hasPreviousPage endCursor }
         }
     }`
 
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for getting a page of blogs
+		Post("/query").
+		// define the query for getting a page of blogs
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the first page has two blogs and a next page
+		Assert(func(res *http.Response, req *http.Request) error {
+			var body struct {
+				Data struct {
+					BlogsConnection model.BlogConnection `json:"blogsConnection"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				return err
+			}
+
+			var connection model.BlogConnection = body.Data.BlogsConnection
+			if len(connection.Edges) != 2 {
+				return fmt.Errorf("expected 2 edges, got %d", len(connection.Edges))
+			}
+			if !connection.PageInfo.HasNextPage || connection.PageInfo.HasPreviousPage {
+				return fmt.Errorf("unexpected page info: %+v", connection.PageInfo)
+			}
+			if connection.PageInfo.EndCursor == nil || *connection.PageInfo.EndCursor != connection.Edges[1].Cursor {
+				return errors.New("end cursor does not match the last edge")
+			}
+			return nil
+		}).
+		End()
+}
+
+func TestGetBlogsConnection_InvalidCursor(t *testing.T) {
 	// create an expected result body
 	var result string = `{
         "errors": [
             {
-                "message": "access denied",
+                "message": "cursor is invalid",
                 "path": [
-                    "editBlog"
-                ]
+                    "blogsConnection"
+                ],
+                "extensions": {"code": "BAD_USER_INPUT"}
             }
         ],
         "data": null
     }`
 
-	// create a test
 	apitest.New().
-		// run the cleanup() function after the test is finished
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blogsConnection(after: "???") { edges { cursor } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(result).
+		End()
+}
+
+func TestGetBlogs_FilterAndOrder(t *testing.T) {
+	// create a blog with a known title and another one
+	var blog model.Blog = getBlog()
+	getBlog()
+
+	// create a query to filter blogs by the title
+	var query string = `query {
+        blogs(filter: { titleContains: "` + blog.Title[2:6] + `" }, orderBy: { field: TITLE, direction: ASC }) {
+            title
+        }
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "data": {
+            "blogs": [
+                { "title": "` + blog.Title + `" }
+            ]
+        }
+    }`
+
+	apitest.New().
 		Observe(cleanup).
-		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for updating a blog
+		Handler(NewGraphQLHandler(store, testConfig())).
 		Post("/query").
-		// define the query for updating a blog
 		GraphQLQuery(query).
-		// expect the status code is equals to 200
 		Expect(t).
 		Status(http.StatusOK).
-		// expect the response body is equal to the expected result
 		Body(result).
 		End()
 }
 
-func TestDeleteBlog_Success(t *testing.T) {
-	// create a new blog data
+func TestGetBlogsConnection_CursorOfAnotherOrder(t *testing.T) {
+	getBlog()
+
+	// get a cursor created for the default order
+	var cursor string
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blogsConnection(first: 1) { pageInfo { endCursor } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Assert(func(res *http.Response, req *http.Request) error {
+			var body struct {
+				Data struct {
+					BlogsConnection model.BlogConnection `json:"blogsConnection"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				return err
+			}
+			cursor = *body.Data.BlogsConnection.PageInfo.EndCursor
+			return nil
+		}).
+		End()
+
+	// create an expected result body
+	var result string = `{
+        "errors": [
+            {
+                "message": "cursor does not match the order",
+                "path": [
+                    "blogsConnection"
+                ],
+                "extensions": {"code": "BAD_USER_INPUT"}
+            }
+        ],
+        "data": null
+    }`
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blogsConnection(first: 1, after: "` + cursor + `", orderBy: { field: TITLE, direction: ASC }) { edges { cursor } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(result).
+		End()
+}
+
+func TestSearchBlogs_Success(t *testing.T) {
+	// create a blog data
 	var blog model.Blog = getBlog()
 
-	// generate a JWT token for authentication
-	var token string = getJWTToken(*blog.Author)
+	// create a query to search the blog by its title
+	var query string = `query {
+        searchBlogs(query: "` + blog.Title + `") {
+            edges { node { id } titleHighlight snippet }
+        }
+    }`
 
-	// create a query for deleting a blog
-	var query string = `mutation {
-        deleteBlog(input:{
-            blogId:"` + blog.ID + `"
-        })
+	// create an expected result body
+	var result string = `{
+        "data": {
+            "searchBlogs": {
+                "edges": [
+                    {
+                        "node": { "id": "` + blog.ID + `" },
+                        "titleHighlight": "<mark>` + blog.Title + `</mark>",
+                        "snippet": "` + blog.Content + `"
+                    }
+                ]
+            }
+        }
+    }`
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(result).
+		End()
+}
+
+func TestGetBlog_Success(t *testing.T) {
+	// create a blog data
+	var blog model.Blog = getBlog()
+
+	// create a query for getting a blog by ID
+	var query string = `query {
+        blog(id:"` + blog.ID + `") {
+            title
+            content
+        }
     }`
 
 	// create an expected result body
 	var result string = `{
         "data": {
-            "deleteBlog": true
+            "blog": {
+                "title": "` + blog.Title + `",
+                "content": "` + blog.Content + `"
+            }
         }
     }`
 
@@ -433,12 +838,10 @@ func TestDeleteBlog_Success(t *testing.T) {
 		// run the cleanup() function after the test is finished
 		Observe(cleanup).
 		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for deleting a blog
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for getting a blog by ID
 		Post("/query").
-		// attach the JWT token to the Authorization header
-		Header("Authorization", token).
-		// define the query for deleting a blog
+		// define the query for getting a blog by ID
 		GraphQLQuery(query).
 		// expect the status code is equals to 200
 		Expect(t).
@@ -448,25 +851,24 @@ func TestDeleteBlog_Success(t *testing.T) {
 		End()
 }
 
-func TestDeleteBlog_Failed(t *testing.T) {
-	// create a new blog data
-	var blog model.Blog = getBlog()
-
-	// create a query for deleting a blog
-	var query string = `mutation {
-        deleteBlog(input:{
-            blogId:"` + blog.ID + `"
-        })
+func TestGetBlog_Failed(t *testing.T) {
+	// create a query to get the blog by ID
+	var query string = `query {
+        blog(id:"62c24f3b4896bb25c21e49b9") {
+            title
+            content
+        }
     }`
 
 	// create an expected result body
 	var result string = `{
         "errors": [
             {
-                "message": "access denied",
+                "message": "blog not found",
                 "path": [
-                    "deleteBlog"
-                ]
+                    "blog"
+                ],
+                "extensions": {"code": "NOT_FOUND"}
             }
         ],
         "data": null
@@ -477,10 +879,10 @@ func TestDeleteBlog_Failed(t *testing.T) {
 		// run the cleanup() function after the test is finished
 		Observe(cleanup).
 		// add an application to be tested
-		Handler(NewGraphQLHandler()).
-		// send a POST request for deleting a blog
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for getting a blog by ID
 		Post("/query").
-		// define the query for deleting a blog
+		// define the query for getting a blog by ID
 		GraphQLQuery(query).
 		// expect the status code is equals to 200
 		Expect(t).
@@ -490,29 +892,1703 @@ func TestDeleteBlog_Failed(t *testing.T) {
 		End()
 }
 
-func cleanup(res *http.Response, req *http.Request, apiTest *apitest.APITest) {
-	if http.StatusOK == res.StatusCode {
-		mock.CleanSeeders()
-	}
-}
-
-func getJWTToken(user model.User) string {
-	// generate JWT token
-	token, err := utils.GenerateNewAccessToken(user.ID)
-
-	// if token generation failed, return an error
-	if err != nil {
-		panic(err)
-	}
+func TestCreateBlog_Success(t *testing.T) {
+	// create a new user data
+	var author model.User = getUser()
 
-	// return the JWT token for the authorization header
-	return "Bearer " + token
-}
+	// generate a JWT token for authentication
+	var token string = getJWTToken(author)
+
+	// create a query for creating a new blog
+	var query string = `mutation {
+        newBlog(input:{
+            title:"my blog",
+            content:"this is the content"
+        }) {
+            title
+            content
+        }
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "data": {
+            "newBlog": {
+                "title": "my blog",
+                "content": "this is the content"
+            }
+        }
+    }`
+
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for creating a new blog
+		Post("/query").
+		// attach the JWT token to the Authorization header
+		Header("Authorization", token).
+		// define the query for creating a new blog
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the response body is equal to the expected result
+		Body(result).
+		End()
+}
+
+func TestCreateBlog_Failed(t *testing.T) {
+	// create a query for creating a new blog
+	var query string = `mutation {
+        newBlog(input:{
+            title:"my blog",
+            content:"this is the content"
+        }) {
+            title
+            content
+        }
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "errors": [
+            {
+                "message": "access denied",
+                "path": [
+                    "newBlog"
+                ],
+                "extensions": {"code": "UNAUTHENTICATED"}
+            }
+        ],
+        "data": null
+    }`
+
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for creating a new blog
+		Post("/query").
+		// define the query for creating a new blog
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the response body is equal to the expected result
+		Body(result).
+		End()
+}
+
+func TestEditBlog_Success(t *testing.T) {
+	// create a new blog data
+	var blog model.Blog = getBlog()
+	// generate a JWT token for authentication
+	var token string = getJWTToken(*blog.Author)
+
+	// create a query for updating a blog
+	var query string = `mutation {
+        editBlog(input:{
+            blogId:"` + blog.ID + `"
+            title:"my blog",
+            content:"this is the content"
+        }) {
+            title
+            content
+        }
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "data": {
+            "editBlog": {
+                "title": "my blog",
+                "content": "this is the content"
+            }
+        }
+    }`
+
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for updating a blog
+		Post("/query").
+		// attach the JWT token to the Authorization header
+		Header("Authorization", token).
+		// define the query for updating a blog
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the response body is equal to the expected result
+		Body(result).
+		End()
+}
+
+func TestEditBlog_Failed(t *testing.T) {
+	// create a new blog data
+	var blog model.Blog = getBlog()
+
+	// create a query for updating a blog
+	var query string = `mutation {
+        editBlog(input:{
+            blogId:"` + blog.ID + `"
+            title:"my blog",
+            content:"this is the content"
+        }) {
+            title
+            content
+        }
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "errors": [
+            {
+                "message": "access denied",
+                "path": [
+                    "editBlog"
+                ],
+                "extensions": {"code": "UNAUTHENTICATED"}
+            }
+        ],
+        "data": null
+    }`
+
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for updating a blog
+		Post("/query").
+		// define the query for updating a blog
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the response body is equal to the expected result
+		Body(result).
+		End()
+}
+
+func TestDeleteBlog_Success(t *testing.T) {
+	// create a new blog data
+	var blog model.Blog = getBlog()
+
+	// generate a JWT token for authentication
+	var token string = getJWTToken(*blog.Author)
+
+	// create a query for deleting a blog
+	var query string = `mutation {
+        deleteBlog(input:{
+            blogId:"` + blog.ID + `"
+        })
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "data": {
+            "deleteBlog": true
+        }
+    }`
+
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for deleting a blog
+		Post("/query").
+		// attach the JWT token to the Authorization header
+		Header("Authorization", token).
+		// define the query for deleting a blog
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the response body is equal to the expected result
+		Body(result).
+		End()
+}
+
+func TestDeleteBlog_Failed(t *testing.T) {
+	// create a new blog data
+	var blog model.Blog = getBlog()
+
+	// create a query for deleting a blog
+	var query string = `mutation {
+        deleteBlog(input:{
+            blogId:"` + blog.ID + `"
+        })
+    }`
+
+	// create an expected result body
+	var result string = `{
+        "errors": [
+            {
+                "message": "access denied",
+                "path": [
+                    "deleteBlog"
+                ],
+                "extensions": {"code": "UNAUTHENTICATED"}
+            }
+        ],
+        "data": null
+    }`
+
+	// create a test
+	apitest.New().
+		// run the cleanup() function after the test is finished
+		Observe(cleanup).
+		// add an application to be tested
+		Handler(NewGraphQLHandler(store, testConfig())).
+		// send a POST request for deleting a blog
+		Post("/query").
+		// define the query for deleting a blog
+		GraphQLQuery(query).
+		// expect the status code is equals to 200
+		Expect(t).
+		Status(http.StatusOK).
+		// expect the response body is equal to the expected result
+		Body(result).
+		End()
+}
+
+func TestDeleteBlog_Trash(t *testing.T) {
+	var (
+		blog  model.Blog = getBlog()
+		token string     = getJWTToken(*blog.Author)
+		query string     = `query { blog(id: "` + blog.ID + `") { title } }`
+	)
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { deleteBlog(input: {blogId: "` + blog.ID + `"}) }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"deleteBlog": true}}`).
+		End()
+
+	// the deleted blog is only listed in the trash of its author
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "blog not found", "path": ["blog"], "extensions": {"code": "NOT_FOUND"}}], "data": null}`).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`query { trashedBlogs { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"trashedBlogs": [{"id": "` + blog.ID + `"}]}}`).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { restoreBlog(input: {blogId: "` + blog.ID + `"}) { title deletedAt } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"restoreBlog": {"title": "` + blog.Title + `", "deletedAt": null}}}`).
+		End()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"title": "` + blog.Title + `"}}}`).
+		End()
+}
+
+func TestRestoreBlog_NotOwner(t *testing.T) {
+	var blog model.Blog = getBlog()
+
+	if err := store.Blogs().TrashBlog(context.Background(), blog.ID, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// another author cannot restore the blog
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`mutation { restoreBlog(input: {blogId: "` + blog.ID + `"}) { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "blog not found", "path": ["restoreBlog"], "extensions": {"code": "NOT_FOUND"}}], "data": null}`).
+		End()
+}
+
+func TestCreateBlog_Tags(t *testing.T) {
+	var token string = getJWTToken(getUser())
+
+	// the tags are stored as sorted slugs without duplicates
+	tagged := createBlog(t, token, `{title: "tagged", content: "content", tags: ["Go Lang", "  graphql ", "go-lang"]}`)
+	if fmt.Sprint(tagged.Tags) != "[go-lang graphql]" {
+		t.Fatalf("unexpected tags: %v", tagged.Tags)
+	}
+
+	other := createBlog(t, token, `{title: "other", content: "content", tags: ["GraphQL"]}`)
+
+	// only the published blogs are counted
+	publishBlog(t, token, tagged.ID)
+	publishBlog(t, token, other.ID)
+
+	// the most used tag comes first
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { tags { tag count } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"tags": [{"tag": "graphql", "count": 2}, {"tag": "go-lang", "count": 1}]}}`).
+		End()
+
+	// the tag of the query is normalized as well
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blogsByTag(tag: "Go Lang", first: 10) { edges { node { title } } pageInfo { hasNextPage } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blogsByTag": {"edges": [{"node": {"title": "tagged"}}], "pageInfo": {"hasNextPage": false}}}}`).
+		End()
+}
+
+func TestCreateBlog_InvalidTag(t *testing.T) {
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`mutation { newBlog(input: {title: "title", content: "content", tags: ["!!!"]}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "tag is invalid", "path": ["newBlog"], "extensions": {"code": "BAD_USER_INPUT"}}], "data": null}`).
+		End()
+}
+
+func TestCreateBlog_Constraints(t *testing.T) {
+	// the variables are checked like the literal values
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`mutation ($input: NewBlog!) { newBlog(input: $input) { id } }`, map[string]interface{}{
+			"input": map[string]interface{}{"title": strings.Repeat("é", 201), "content": ""},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [
+				{"message": "input.title must have at most 200 characters", "path": ["newBlog"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.title"}},
+				{"message": "input.content must have at least 1 character", "path": ["newBlog"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.content"}}
+			],
+			"data": null
+		}`).
+		End()
+}
+
+func TestCreateBlog_Draft(t *testing.T) {
+	var (
+		author model.User = getUser()
+		token  string     = getJWTToken(author)
+		blog   model.Blog = createBlog(t, token, `{title: "draft", content: "content"}`)
+		query  string     = `query { blog(id: "` + blog.ID + `") { title status } }`
+	)
+
+	if blog.Status != model.BlogStatusDraft {
+		t.Fatalf("expected a draft, got %s", blog.Status)
+	}
+
+	// the draft is hidden from the other users
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "blog not found", "path": ["blog"], "extensions": {"code": "NOT_FOUND"}}], "data": null}`).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`query { blogs { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blogs": []}}`).
+		End()
+
+	// the author sees the draft
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"title": "draft", "status": "DRAFT"}}}`).
+		End()
+}
+
+func TestScheduleBlog_Success(t *testing.T) {
+	var (
+		token     string     = getJWTToken(getUser())
+		blog      model.Blog = createBlog(t, token, `{title: "scheduled", content: "content"}`)
+		publishAt time.Time  = time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	)
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation {
+			scheduleBlog(input: {blogId: "` + blog.ID + `", publishAt: "` + publishAt.Format(time.RFC3339) + `"}) { status publishAt }
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"scheduleBlog": {"status": "SCHEDULED", "publishAt": "` + publishAt.Format(time.RFC3339) + `"}}}`).
+		End()
+
+	// the scheduler publishes the blog once its time has come
+	published, err := store.Blogs().PublishScheduledBlogs(context.Background(), publishAt)
+	if err != nil || len(published) != 1 {
+		t.Fatalf("expected one blog to be published, got %d (%v)", len(published), err)
+	}
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blog(id: "` + blog.ID + `") { status publishAt publishedAt } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"status": "PUBLISHED", "publishAt": null, "publishedAt": "` + publishAt.Format(time.RFC3339) + `"}}}`).
+		End()
+}
+
+func TestScheduleBlog_Failed(t *testing.T) {
+	var (
+		token string     = getJWTToken(getUser())
+		blog  model.Blog = createBlog(t, token, `{title: "scheduled", content: "content"}`)
+	)
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation {
+			scheduleBlog(input: {blogId: "` + blog.ID + `", publishAt: "2000-01-01T00:00:00Z"}) { status }
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "publication time must be in the future", "path": ["scheduleBlog"], "extensions": {"code": "BAD_USER_INPUT"}}], "data": null}`).
+		End()
+}
+
+func TestPublishBlog_NotOwner(t *testing.T) {
+	var blog model.Blog = getBlog()
+
+	// another author cannot withdraw the blog
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`mutation { unpublishBlog(input: {blogId: "` + blog.ID + `"}) { status } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "blog not found", "path": ["unpublishBlog"], "extensions": {"code": "NOT_FOUND"}}], "data": null}`).
+		End()
+}
+
+func TestEditBlog_Revisions(t *testing.T) {
+	var (
+		token string     = getJWTToken(getUser())
+		blog  model.Blog = createBlog(t, token, `{title: "first", content: "one\ntwo"}`)
+	)
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { editBlog(input: {blogId: "` + blog.ID + `", title: "second", content: "one\n2\nthree"}) { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"editBlog": {"title": "second"}}}`).
+		End()
+
+	// every edit appends a revision, newest first
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`query { blog(id: "` + blog.ID + `") { revisions { version title titleChanged linesAdded linesRemoved } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"revisions": [
+			{"version": 2, "title": "second", "titleChanged": true, "linesAdded": 2, "linesRemoved": 1},
+			{"version": 1, "title": "first", "titleChanged": false, "linesAdded": 2, "linesRemoved": 0}
+		]}}}`).
+		End()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`query { blogRevisionDiff(id: "` + blog.ID + `", fromVersion: 1, toVersion: 2) { fromTitle toTitle diff } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blogRevisionDiff": {"fromTitle": "first", "toTitle": "second", "diff": " one\n-two\n+2\n+three\n"}}}`).
+		End()
+}
+
+func TestRestoreBlogRevision_Success(t *testing.T) {
+	var (
+		token string     = getJWTToken(getUser())
+		blog  model.Blog = createBlog(t, token, `{title: "first", content: "first content"}`)
+	)
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { editBlog(input: {blogId: "` + blog.ID + `", title: "second", content: "second content"}) { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	// the restored version is recorded as a new revision
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { restoreBlogRevision(input: {blogId: "` + blog.ID + `", version: 1}) { title content } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"restoreBlogRevision": {"title": "first", "content": "first content"}}}`).
+		End()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`query { blogRevision(id: "` + blog.ID + `", version: 3) { title content } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blogRevision": {"title": "first", "content": "first content"}}}`).
+		End()
+}
+
+func TestBlogRevision_Failed(t *testing.T) {
+	var blog model.Blog = getBlog()
+
+	// the revisions are hidden from the other users
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`query { blogRevision(id: "` + blog.ID + `", version: 1) { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "access denied", "path": ["blogRevision"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`).
+		End()
+
+	// the blog only has the version it was created with
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(*blog.Author)).
+		GraphQLQuery(`query { blogRevision(id: "` + blog.ID + `", version: 2) { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "revision not found", "path": ["blogRevision"], "extensions": {"code": "NOT_FOUND"}}], "data": null}`).
+		End()
+}
+
+func TestReactToBlog_Success(t *testing.T) {
+	var (
+		blog  model.Blog = getBlog()
+		token string     = getJWTToken(getUser())
+		react string     = `mutation { reactToBlog(input: {blogId: "` + blog.ID + `", kind: LIKE}) { id } }`
+		query string     = `query { blog(id: "` + blog.ID + `") { reactions { total counts { kind count reacted } } } }`
+	)
+
+	// reacting twice with the same kind counts once
+	for i := 0; i < 2; i++ {
+		apitest.New().
+			Handler(NewGraphQLHandler(store, testConfig())).
+			Post("/query").
+			Header("Authorization", token).
+			GraphQLQuery(react).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"data": {"reactToBlog": {"id": "` + blog.ID + `"}}}`).
+			End()
+	}
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"reactions": {"total": 1, "counts": [
+			{"kind": "LIKE", "count": 1, "reacted": true},
+			{"kind": "LOVE", "count": 0, "reacted": false},
+			{"kind": "LAUGH", "count": 0, "reacted": false},
+			{"kind": "INSIGHTFUL", "count": 0, "reacted": false},
+			{"kind": "SAD", "count": 0, "reacted": false}
+		]}}}}`).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { removeReaction(input: {blogId: "` + blog.ID + `", kind: LIKE}) { reactions { total } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"removeReaction": {"reactions": {"total": 0}}}}`).
+		End()
+
+	// anonymous viewers see the counts
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blog(id: "` + blog.ID + `") { reactions { total } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {"reactions": {"total": 0}}}}`).
+		End()
+}
+
+func TestReactToBlog_Failed(t *testing.T) {
+	var (
+		draft model.Blog = createBlog(t, getJWTToken(getUser()), `{title: "draft", content: "content"}`)
+		react string     = `mutation { reactToBlog(input: {blogId: "` + draft.ID + `", kind: LOVE}) { id } }`
+	)
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(react).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "access denied", "path": ["reactToBlog"], "extensions": {"code": "UNAUTHENTICATED"}}], "data": null}`).
+		End()
+
+	// the drafts of other authors cannot be reacted to
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(react).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "blog not found", "path": ["reactToBlog"], "extensions": {"code": "NOT_FOUND"}}], "data": null}`).
+		End()
+}
+
+func TestUpdateProfile_Success(t *testing.T) {
+	var user model.User = getUser()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(user)).
+		GraphQLQuery(`mutation {
+			updateProfile(input: {displayName: " Jane ", bio: "writer", avatarUrl: "https://example.com/jane.png"}) {
+				displayName
+				bio
+				avatarUrl
+			}
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"updateProfile": {"displayName": "Jane", "bio": "writer", "avatarUrl": "https://example.com/jane.png"}}}`).
+		End()
+
+	// the missing fields are cleared
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(user)).
+		GraphQLQuery(`mutation { updateProfile(input: {bio: "editor"}) { displayName bio avatarUrl } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"updateProfile": {"displayName": null, "bio": "editor", "avatarUrl": null}}}`).
+		End()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { user(id: "` + user.ID + `") { bio } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"user": {"bio": "editor"}}}`).
+		End()
+}
+
+func TestUpdateProfile_Failed(t *testing.T) {
+	var token string = getJWTToken(getUser())
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { updateProfile(input: {avatarUrl: "javascript:alert(1)"}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "input.avatarUrl must be an absolute http or https URL", "path": ["updateProfile"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.avatarUrl"}}], "data": null}`).
+		End()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { updateProfile(input: {displayName: "` + strings.Repeat("a", 51) + `"}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "input.displayName must have at most 50 characters", "path": ["updateProfile"], "extensions": {"code": "BAD_USER_INPUT", "field": "input.displayName"}}], "data": null}`).
+		End()
+}
+
+func TestFollowUser_Success(t *testing.T) {
+	var (
+		follower model.User = getUser()
+		author   model.User = getUser()
+		token    string     = getJWTToken(follower)
+	)
+
+	// following the user twice keeps a single follow
+	for i := 0; i < 2; i++ {
+		apitest.New().
+			Handler(NewGraphQLHandler(store, testConfig())).
+			Post("/query").
+			Header("Authorization", token).
+			GraphQLQuery(`mutation { followUser(input: {userId: "` + author.ID + `"}) { id } }`).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"data": {"followUser": {"id": "` + author.ID + `"}}}`).
+			End()
+	}
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query {
+			user(id: "` + author.ID + `") {
+				followers { edges { node { id } } pageInfo { hasNextPage } }
+			}
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"user": {"followers": {"edges": [{"node": {"id": "` + follower.ID + `"}}], "pageInfo": {"hasNextPage": false}}}}}`).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { unfollowUser(input: {userId: "` + author.ID + `"}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"unfollowUser": {"id": "` + author.ID + `"}}}`).
+		End()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { user(id: "` + follower.ID + `") { following { edges { node { id } } } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"user": {"following": {"edges": []}}}}`).
+		End()
+}
+
+func TestFollowUser_Failed(t *testing.T) {
+	var user model.User = getUser()
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(user)).
+		GraphQLQuery(`mutation { followUser(input: {userId: "` + user.ID + `"}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "cannot follow yourself", "path": ["followUser"], "extensions": {"code": "BAD_USER_INPUT"}}], "data": null}`).
+		End()
+}
+
+func TestFeed_FollowedAuthors(t *testing.T) {
+	var (
+		follower model.User = getUser()
+		author   model.User = getUser()
+		token    string     = getJWTToken(follower)
+		older    model.Blog = createBlog(t, getJWTToken(author), `{title: "older", content: "content"}`)
+		newer    model.Blog = createBlog(t, getJWTToken(author), `{title: "newer", content: "content"}`)
+		feed     string     = `query { feed { edges { node { id } } } }`
+	)
+
+	// the blogs of the authors nobody follows are not in the feed
+	getBlog()
+
+	publishBlog(t, getJWTToken(author), older.ID)
+	publishBlog(t, getJWTToken(author), newer.ID)
+	createBlog(t, getJWTToken(author), `{title: "draft", content: "content"}`)
+
+	// the feed of a user following nobody is empty
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(feed).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"feed": {"edges": []}}}`).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { followUser(input: {userId: "` + author.ID + `"}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(feed).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"feed": {"edges": [{"node": {"id": "` + newer.ID + `"}}, {"node": {"id": "` + older.ID + `"}}]}}}`).
+		End()
+
+	// the drafts of the author are only listed for the author
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { user(id: "` + author.ID + `") { blogs { edges { node { id } } } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"user": {"blogs": {"edges": [{"node": {"id": "` + newer.ID + `"}}, {"node": {"id": "` + older.ID + `"}}]}}}}`).
+		End()
+
+	// the feed needs an authenticated viewer
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(feed).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "access denied", "path": ["feed"], "extensions": {"code": "UNAUTHENTICATED"}}], "data": null}`).
+		End()
+}
+
+func TestAddComment_Success(t *testing.T) {
+	var (
+		blog    model.Blog    = getBlog()
+		comment model.Comment = getComment(blog)
+		user    model.User    = getUser()
+	)
+
+	// reply to the comment
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(user)).
+		GraphQLQuery(`mutation {
+			addComment(input: {blogId: "` + blog.ID + `", parentId: "` + comment.ID + `", content: "reply"}) {
+				parentId
+				depth
+				content
+				author { id }
+			}
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"addComment": {"parentId": "` + comment.ID + `", "depth": 1, "content": "reply", "author": {"id": "` + user.ID + `"}}}}`).
+		End()
+
+	// the reply is listed below its parent and counted with the blog
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query {
+			blog(id: "` + blog.ID + `") {
+				commentCount
+				comments(first: 10) {
+					edges { node { id replyCount replies { edges { node { content } } } } }
+					pageInfo { hasNextPage }
+				}
+			}
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blog": {
+			"commentCount": 2,
+			"comments": {
+				"edges": [{"node": {"id": "` + comment.ID + `", "replyCount": 1, "replies": {"edges": [{"node": {"content": "reply"}}]}}}],
+				"pageInfo": {"hasNextPage": false}
+			}
+		}}}`).
+		End()
+}
+
+func TestAddComment_DepthLimit(t *testing.T) {
+	var (
+		blog     model.Blog    = getBlog()
+		comment  model.Comment = getComment(blog)
+		parentID string        = comment.ID
+	)
+
+	// nest replies down to the deepest level
+	for depth := 1; depth <= service.MaxCommentDepth; depth++ {
+		reply, err := store.Comments().CreateComment(context.Background(), model.Comment{
+			BlogID:    blog.ID,
+			ParentID:  &parentID,
+			Depth:     depth,
+			Content:   "reply",
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		parentID = reply.ID
+	}
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(`mutation {
+			addComment(input: {blogId: "` + blog.ID + `", parentId: "` + parentID + `", content: "too deep"}) { id }
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "replies are nested too deeply", "path": ["addComment"], "extensions": {"code": "BAD_USER_INPUT"}}], "data": null}`).
+		End()
+}
+
+func TestEditComment_NotAuthor(t *testing.T) {
+	var (
+		blog    model.Blog    = getBlog()
+		comment model.Comment = getComment(blog)
+	)
+
+	// even the author of the blog cannot edit the comments of others
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(*blog.Author)).
+		GraphQLQuery(`mutation {
+			editComment(input: {commentId: "` + comment.ID + `", content: "edited"}) { id }
+		}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "access denied", "path": ["editComment"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`).
+		End()
+}
+
+func TestDeleteComment_Moderation(t *testing.T) {
+	var (
+		blog    model.Blog    = getBlog()
+		comment model.Comment = getComment(blog)
+		query   string        = `mutation { deleteComment(input: {commentId: "` + comment.ID + `"}) }`
+	)
+
+	// another user cannot delete the comment
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "access denied", "path": ["deleteComment"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`).
+		End()
+
+	// the author of the blog can delete the comment
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", getJWTToken(*blog.Author)).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"deleteComment": true}}`).
+		End()
+}
+
+func TestBlogs_BatchedAuthors(t *testing.T) {
+	var users *countingUsers = &countingUsers{UserRepository: store.Users()}
+
+	for i := 0; i < 5; i++ {
+		getBlog()
+	}
+
+	// the authors of every blog are read together
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(&countingStore{Store: store, users: users}, testConfig())).
+		Post("/query").
+		GraphQLQuery(`query { blogs { author { id username } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Assert(func(res *http.Response, req *http.Request) error {
+			if lookups := atomic.LoadInt32(&users.lookups); lookups != 1 {
+				return fmt.Errorf("expected a single lookup of the users, got %d", lookups)
+			}
+			return nil
+		}).
+		End()
+}
+
+func TestSubscription_CommentAdded(t *testing.T) {
+	t.Cleanup(func() { mock.CleanSeeders(store) })
+
+	var (
+		blog    model.Blog     = getBlog()
+		user    model.User     = getUser()
+		handler http.Handler   = NewGraphQLHandler(store, testConfig())
+		gql     *client.Client = client.New(handler, client.Path("/query"))
+	)
+
+	// the websocket is authenticated when the connection is initialized
+	subscription := gql.WebsocketWithPayload(
+		`subscription { commentAdded(blogId: "`+blog.ID+`") { content author { id } } }`,
+		map[string]interface{}{"Authorization": getJWTToken(user)},
+	)
+	defer subscription.Close()
+
+	var event struct {
+		CommentAdded struct {
+			Content string
+			Author  struct{ ID string }
+		}
+	}
+
+	receiveEvent(t, func() error { return subscription.Next(&event) }, func() {
+		var response map[string]interface{}
+		gql.MustPost(
+			`mutation { addComment(input: {blogId: "`+blog.ID+`", content: "live"}) { id } }`,
+			&response,
+			client.AddHeader("Authorization", getJWTToken(user)),
+		)
+	})
+
+	if event.CommentAdded.Content != "live" || event.CommentAdded.Author.ID != user.ID {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+}
+
+func TestSubscription_BlogUpdatedOverSSE(t *testing.T) {
+	t.Cleanup(func() { mock.CleanSeeders(store) })
+
+	var (
+		author  model.User   = getUser()
+		token   string       = getJWTToken(author)
+		blog    model.Blog   = createBlog(t, token, `{title: "title", content: "content"}`)
+		handler http.Handler = NewGraphQLHandler(store, testConfig())
+	)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	body, _ := json.Marshal(map[string]string{"query": `subscription { blogUpdated(id: "` + blog.ID + `") { title status } }`})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the drafts are only streamed to the viewers who can read them
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/query", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "text/event-stream")
+	request.Header.Set("Authorization", token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var (
+		reader *bufio.Reader = bufio.NewReader(response.Body)
+		event  string
+	)
+
+	receiveEvent(t, func() error {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(line, "data: ") {
+				event = strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+				return nil
+			}
+		}
+	}, func() {
+		var response map[string]interface{}
+		client.New(handler, client.Path("/query")).MustPost(
+			`mutation { editBlog(input: {blogId: "`+blog.ID+`", title: "edited", content: "content"}) { id } }`,
+			&response,
+			client.AddHeader("Authorization", token),
+		)
+	})
+
+	if event != `{"data":{"blogUpdated":{"title":"edited","status":"DRAFT"}}}` {
+		t.Fatalf("unexpected event: %s", event)
+	}
+}
+
+func TestSubscription_BlogCreatedWhenPublished(t *testing.T) {
+	t.Cleanup(func() { mock.CleanSeeders(store) })
+
+	// a blog is written every time the event is awaited
+	var config handlerConfig = testConfig()
+	config.rates.writes = 1000
+
+	var (
+		token     string          = getJWTToken(getUser())
+		handler   http.Handler    = NewGraphQLHandler(store, config)
+		gql       *client.Client  = client.New(handler, client.Path("/query"))
+		published map[string]bool = make(map[string]bool)
+	)
+
+	// the anonymous viewers cannot read the drafts, so they learn about a blog when it is published
+	subscription := gql.Websocket(`subscription { blogCreated { id status } }`)
+	defer subscription.Close()
+
+	var event struct {
+		BlogCreated struct {
+			ID     string
+			Status model.BlogStatus
+		}
+	}
+
+	receiveEvent(t, func() error { return subscription.Next(&event) }, func() {
+		var created struct {
+			NewBlog struct{ ID string }
+		}
+		gql.MustPost(`mutation { newBlog(input: {title: "title", content: "content"}) { id } }`, &created, client.AddHeader("Authorization", token))
+
+		var response map[string]interface{}
+		gql.MustPost(`mutation { publishBlog(input: {blogId: "`+created.NewBlog.ID+`"}) { id } }`, &response, client.AddHeader("Authorization", token))
+		published[created.NewBlog.ID] = true
+	})
+
+	if !published[event.BlogCreated.ID] || event.BlogCreated.Status != model.BlogStatusPublished {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+}
+
+func TestSubscription_InvalidToken(t *testing.T) {
+	// the connection is refused when the token is invalid
+	subscription := client.New(NewGraphQLHandler(store, testConfig()), client.Path("/query")).WebsocketWithPayload(
+		`subscription { blogCreated { id } }`,
+		map[string]interface{}{"Authorization": "Bearer invalid"},
+	)
+	defer subscription.Close()
+
+	var event map[string]interface{}
+	if err := subscription.Next(&event); err == nil {
+		t.Fatal("expected the connection to be refused")
+	}
+}
+
+func TestQueryLimits_CostReported(t *testing.T) {
+	var config handlerConfig = testConfig()
+	config.limits.reportCost = true
+
+	// the cost of every item of the page is multiplied by the size of the page
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, config)).
+		Post("/query").
+		GraphQLQuery(`query { blogsConnection(first: 5) { edges { node { title } } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"data": {"blogsConnection": {"edges": []}},
+			"extensions": {"cost": {"complexity": 17, "complexityLimit": 5000, "depth": 4, "depthLimit": 10}}
+		}`).
+		End()
+}
+
+func TestQueryLimits_DepthExceeded(t *testing.T) {
+	var config handlerConfig = testConfig()
+	config.limits.maxDepth = 3
+
+	// the fragments count towards the depth of the fields they are spread in
+	apitest.New().
+		Handler(NewGraphQLHandler(store, config)).
+		Post("/query").
+		GraphQLQuery(`query { blogsConnection { edges { ...edge } } } fragment edge on BlogEdge { node { title } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [{
+				"message": "operation has depth 4, which exceeds the limit of 3",
+				"extensions": {"code": "DEPTH_LIMIT_EXCEEDED"}
+			}],
+			"data": null
+		}`).
+		End()
+}
+
+func TestQueryLimits_ComplexityExceeded(t *testing.T) {
+	var query string = `query {
+		blogsConnection(first: 100) { edges { node {
+			comments(first: 100) { edges { node {
+				replies(first: 100) { edges { node { content } } }
+			} } }
+		} } }
+	}`
+
+	// the nested pages are rejected before any blog is read
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [{
+				"message": "operation has complexity 3040402, which exceeds the limit of 5000",
+				"extensions": {"code": "COMPLEXITY_LIMIT_EXCEEDED"}
+			}],
+			"data": null
+		}`).
+		End()
+
+	// the budget is configurable
+	var config handlerConfig = testConfig()
+	config.limits.maxComplexity = 5000000
+
+	apitest.New().
+		Observe(cleanup).
+		Handler(NewGraphQLHandler(store, config)).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"blogsConnection": {"edges": []}}}`).
+		End()
+}
+
+func TestRateLimit_Login(t *testing.T) {
+	var config handlerConfig = testConfig()
+	config.rates.auth = 2
+
+	var (
+		handler http.Handler = NewGraphQLHandler(store, config)
+		query   string       = `mutation { login(input: {email: "wrong@mail.com", password: "123456"}) { accessToken } }`
+	)
+
+	for i := 0; i < 2; i++ {
+		apitest.New().
+			Handler(handler).
+			Post("/query").
+			GraphQLQuery(query).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"errors": [{"message": "login failed, invalid email or password", "path": ["login"], "extensions": {"code": "UNAUTHENTICATED"}}], "data": null}`).
+			End()
+	}
+
+	// the attempts are refused before the password is checked
+	apitest.New().
+		Handler(handler).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{
+			"errors": [{
+				"message": "too many login attempts, retry in 30 seconds",
+				"path": ["login"],
+				"extensions": {"code": "RATE_LIMITED", "limit": 2, "retryAfter": 30}
+			}],
+			"data": null
+		}`).
+		End()
+}
+
+func TestRateLimit_Requests(t *testing.T) {
+	var config handlerConfig = testConfig()
+	config.rates.requests = 2
+
+	var (
+		handler http.Handler = NewGraphQLHandler(store, config)
+		query   string       = `query { tags { tag } }`
+	)
+
+	for _, remaining := range []string{"1", "0"} {
+		apitest.New().
+			Handler(handler).
+			Post("/query").
+			GraphQLQuery(query).
+			Expect(t).
+			Status(http.StatusOK).
+			Header("RateLimit-Limit", "2").
+			Header("RateLimit-Remaining", remaining).
+			Header("RateLimit-Policy", "2;w=60").
+			End()
+	}
+
+	apitest.New().
+		Handler(handler).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusTooManyRequests).
+		Header("Retry-After", "30").
+		Header("RateLimit-Remaining", "0").
+		Body(`{
+			"errors": [{
+				"message": "too many requests, retry in 30 seconds",
+				"extensions": {"code": "RATE_LIMITED", "limit": 2, "retryAfter": 30}
+			}],
+			"data": null
+		}`).
+		End()
+
+	// the signed-in users have their own budget
+	apitest.New().
+		Observe(cleanup).
+		Handler(handler).
+		Post("/query").
+		Header("Authorization", getJWTToken(getUser())).
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Header("RateLimit-Remaining", "1").
+		End()
+}
+
+func TestLogin_Lockout(t *testing.T) {
+	var config handlerConfig = testConfig()
+	config.loginPolicy.MaxAccountFailures = 3
+	config.rates.auth = 100
+	t.Cleanup(func() { mock.CleanSeeders(store) })
+
+	var (
+		handler  http.Handler = NewGraphQLHandler(store, config)
+		failed   string       = `{"errors": [{"message": "login failed, invalid email or password", "path": ["login"], "extensions": {"code": "UNAUTHENTICATED"}}], "data": null}`
+		locked   string       = `{"errors": [{"message": "login locked after too many failed attempts, try again later", "path": ["login"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`
+		query    func(email string, password string) string
+		admin    model.User = getUserWithRole(model.RoleAdmin)
+		user, pw            = getUserWithPassword()
+	)
+
+	query = func(email string, password string) string {
+		return `mutation { login(input: {email: "` + email + `", password: "` + password + `"}) { accessToken } }`
+	}
+
+	// the registered and the unknown emails are answered and locked alike
+	for _, email := range []string{*user.Email, "unknown@mail.com"} {
+		for i := 0; i < 3; i++ {
+			apitest.New().
+				Handler(handler).
+				Post("/query").
+				GraphQLQuery(query(email, "wrong password")).
+				Expect(t).
+				Status(http.StatusOK).
+				Body(failed).
+				End()
+		}
+
+		// the right password is refused while the email is locked
+		apitest.New().
+			Handler(handler).
+			Post("/query").
+			GraphQLQuery(query(email, pw)).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(locked).
+			End()
+	}
+
+	apitest.New().
+		Handler(handler).
+		Post("/query").
+		Header("Authorization", getJWTToken(admin)).
+		GraphQLQuery(`mutation { unlockUser(input: {userId: "` + user.ID + `"}) { id } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"unlockUser": {"id": "` + user.ID + `"}}}`).
+		End()
+
+	// the user can log in again
+	login(t, user, pw)
+
+	// the lockout and the unlock are recorded with the normalized email, newest first
+	var email string = strings.ToLower(*user.Email)
+	apitest.New().
+		Handler(handler).
+		Post("/query").
+		Header("Authorization", getJWTToken(admin)).
+		GraphQLQuery(`query { auditRecords(userId: "` + user.ID + `") { action email user { id } actor { id } } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"auditRecords": [
+			{"action": "LOGIN_UNLOCKED", "email": "` + email + `", "user": {"id": "` + user.ID + `"}, "actor": {"id": "` + admin.ID + `"}},
+			{"action": "LOGIN_LOCKED", "email": "` + email + `", "user": {"id": "` + user.ID + `"}, "actor": null}
+		]}}`).
+		End()
+
+	// the records are only visible to the admins
+	apitest.New().
+		Handler(handler).
+		Post("/query").
+		Header("Authorization", getJWTToken(user)).
+		GraphQLQuery(`query { auditRecords { action } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "access denied, the ADMIN role is required", "path": ["auditRecords"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`).
+		End()
+}
+
+func TestLogin_IPLockout(t *testing.T) {
+	var config handlerConfig = testConfig()
+	config.loginPolicy.MaxIPFailures = 2
+	t.Cleanup(func() { mock.CleanSeeders(store) })
+
+	var (
+		next     http.Handler = NewGraphQLHandler(store, config)
+		user, pw              = getUserWithPassword()
+	)
+
+	// the requests of the tests have no remote address, so every request comes from the same one
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = "192.0.2.1:1234"
+		next.ServeHTTP(w, r)
+	})
+
+	// the failures are counted for the IP address across the emails
+	for _, email := range []string{"first@mail.com", "second@mail.com"} {
+		apitest.New().
+			Handler(handler).
+			Post("/query").
+			GraphQLQuery(`mutation { login(input: {email: "` + email + `", password: "wrong password"}) { accessToken } }`).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"errors": [{"message": "login failed, invalid email or password", "path": ["login"], "extensions": {"code": "UNAUTHENTICATED"}}], "data": null}`).
+			End()
+	}
+
+	apitest.New().
+		Handler(handler).
+		Post("/query").
+		GraphQLQuery(`mutation { login(input: {email: "` + *user.Email + `", password: "` + pw + `"}) { accessToken } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"errors": [{"message": "login locked after too many failed attempts, try again later", "path": ["login"], "extensions": {"code": "FORBIDDEN"}}], "data": null}`).
+		End()
+
+	records, err := store.Audit().ListAuditRecords(context.Background(), "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Action != model.AuditActionIPLocked || records[0].IP == "" {
+		t.Fatalf("expected the lockout of the IP address to be recorded, got %+v", records)
+	}
+}
+
+func cleanup(res *http.Response, req *http.Request, apiTest *apitest.APITest) {
+	if http.StatusOK == res.StatusCode {
+		mock.CleanSeeders(store)
+	}
+}
+
+// countingStore represents a store counting the lookups of the users
+type countingStore struct {
+	database.Store
+	users *countingUsers
+}
+
+// Users returns the counting user repository
+func (s *countingStore) Users() database.UserRepository {
+	return s.users
+}
+
+// countingUsers represents a user repository counting the lookups of the users
+type countingUsers struct {
+	database.UserRepository
+	lookups int32
+}
+
+// GetUserByID counts the lookup and returns the user
+func (u *countingUsers) GetUserByID(ctx context.Context, id string) (*database.User, error) {
+	atomic.AddInt32(&u.lookups, 1)
+	return u.UserRepository.GetUserByID(ctx, id)
+}
+
+// GetUsersByIDs counts the lookup and returns the users
+func (u *countingUsers) GetUsersByIDs(ctx context.Context, ids []string) ([]*database.User, error) {
+	atomic.AddInt32(&u.lookups, 1)
+	return u.UserRepository.GetUsersByIDs(ctx, ids)
+}
+
+// receiveEvent repeats the trigger until the subscription receives an event
+// the subscription may only be listening after the first triggers
+func receiveEvent(t *testing.T, next func() error, trigger func()) {
+	var received chan error = make(chan error, 1)
+	go func() { received <- next() }()
+
+	var ticker *time.Ticker = time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var timeout <-chan time.Time = time.After(5 * time.Second)
+
+	trigger()
+	for {
+		select {
+		case err := <-received:
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-ticker.C:
+			trigger()
+		case <-timeout:
+			t.Fatal("expected an event")
+		}
+	}
+}
+
+func getJWTToken(user model.User) string {
+	// generate JWT token
+	token, err := utils.GenerateNewAccessToken(user.ID)
+
+	// if token generation failed, return an error
+	if err != nil {
+		panic(err)
+	}
+
+	// return the JWT token for the authorization header
+	return "Bearer " + token
+}
+
+// refreshTokenQuery returns the mutation exchanging the refresh token
+func refreshTokenQuery(token string) string {
+	return `mutation {
+		refreshToken(input: {refreshToken: "` + token + `"}) {
+			accessToken
+			refreshToken
+		}
+	}`
+}
+
+// login logs the user in and returns the issued tokens
+func login(t *testing.T, user model.User, password string) model.AuthToken {
+	return authenticate(t, "login", `mutation {
+		login(input: {email: "`+*user.Email+`", password: "`+password+`"}) {
+			accessToken
+			refreshToken
+		}
+	}`)
+}
+
+// refreshToken exchanges the refresh token and returns the issued tokens
+func refreshToken(t *testing.T, token string) model.AuthToken {
+	return authenticate(t, "refreshToken", refreshTokenQuery(token))
+}
+
+// authenticate sends a mutation returning tokens and decodes them
+func authenticate(t *testing.T, field string, query string) model.AuthToken {
+	var token model.AuthToken
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		GraphQLQuery(query).
+		Expect(t).
+		Status(http.StatusOK).
+		Assert(func(res *http.Response, req *http.Request) error {
+			var body struct {
+				Data map[string]*model.AuthToken `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				return err
+			}
+			if body.Data[field] == nil {
+				return fmt.Errorf("expected %s to return tokens", field)
+			}
+			token = *body.Data[field]
+			return nil
+		}).
+		End()
+
+	return token
+}
+
+// getUserWithRole creates a new user with the role
+func getUserWithRole(role model.Role) model.User {
+	var user model.User = getUser()
+
+	if _, err := store.Users().UpdateUserRole(context.Background(), user.ID, role, time.Now()); err != nil {
+		panic(err)
+	}
+
+	user.Role = role
+	return user
+}
 
 func getBlog() model.Blog {
 
 	// create a new blog data for testing
-	blog, err := mock.SeedBlog()
+	blog, err := mock.SeedBlog(store)
 
 	// if blog creation failed, return an error
 	if err != nil {
@@ -523,10 +2599,74 @@ func getBlog() model.Blog {
 	return blog
 }
 
+// createBlog creates a new blog with the input and returns it
+func createBlog(t *testing.T, token string, input string) model.Blog {
+	var blog model.Blog
+
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { newBlog(input: ` + input + `) { id title tags status } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Assert(func(res *http.Response, req *http.Request) error {
+			var body struct {
+				Data struct {
+					NewBlog *model.Blog `json:"newBlog"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				return err
+			}
+			if body.Data.NewBlog == nil {
+				return errors.New("expected newBlog to return the blog")
+			}
+			blog = *body.Data.NewBlog
+			return nil
+		}).
+		End()
+
+	return blog
+}
+
+// publishBlog publishes the blog
+func publishBlog(t *testing.T, token string, id string) {
+	apitest.New().
+		Handler(NewGraphQLHandler(store, testConfig())).
+		Post("/query").
+		Header("Authorization", token).
+		GraphQLQuery(`mutation { publishBlog(input: {blogId: "` + id + `"}) { status } }`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"data": {"publishBlog": {"status": "PUBLISHED"}}}`).
+		End()
+}
+
+// getComment creates a new comment on the blog
+func getComment(blog model.Blog) model.Comment {
+	comment, err := mock.SeedComment(store, blog)
+	if err != nil {
+		panic(err)
+	}
+
+	return comment
+}
+
+// getUserWithPassword creates a new user and returns its unencrypted password
+func getUserWithPassword() (model.User, string) {
+	user, password, err := mock.SeedUserWithPassword(store)
+	if err != nil {
+		panic(err)
+	}
+
+	return user, password
+}
+
 func getUser() model.User {
 
 	// create new user data for testing
-	user, err := mock.SeedUser()
+	user, err := mock.SeedUser(store)
 
 	// if user creation failed, return an error
 	if err != nil {
@@ -0,0 +1,255 @@
+package database
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/search"
+)
+
+// BlogSortField represents the field used to sort blogs
+type BlogSortField string
+
+const (
+	// SortByCreatedAt sorts blogs by the creation time
+	SortByCreatedAt BlogSortField = "createdAt"
+	// SortByUpdatedAt sorts blogs by the last edit time, blogs never edited come first
+	SortByUpdatedAt BlogSortField = "updatedAt"
+	// SortByTitle sorts blogs by the title
+	SortByTitle BlogSortField = "title"
+)
+
+// BlogFilter represents the conditions a blog must match
+// empty fields are ignored
+type BlogFilter struct {
+	AuthorID string
+	// AuthorIDs selects the blogs of any of the authors
+	AuthorIDs     []string
+	TitleContains string
+	// Tag selects the blogs with the tag slug
+	Tag    string
+	Status model.BlogStatus
+	// Visibility selects the blogs a viewer can read, nil selects every blog
+	Visibility    *BlogVisibility
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Edited        *bool
+}
+
+// BlogVisibility represents the blogs a viewer can read:
+// the published blogs and every blog of the author
+type BlogVisibility struct {
+	// AuthorID is the ID of the viewer, empty for an anonymous viewer
+	AuthorID string
+}
+
+// allows reports whether the blog can be read
+func (v *BlogVisibility) allows(blog *model.Blog) bool {
+	if v == nil || blog.Status == model.BlogStatusPublished {
+		return true
+	}
+
+	return v.AuthorID != "" && blog.Author != nil && blog.Author.ID == v.AuthorID
+}
+
+// BlogCursor represents the position of a blog in the sort order
+// blogs are sorted by the sort field and then by the ID
+type BlogCursor struct {
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	Title     string
+	ID        string
+}
+
+// BlogQuery represents a range of blogs
+// blogs are sorted newest first unless another order is set
+type BlogQuery struct {
+	// Filter selects the blogs
+	Filter BlogFilter
+	// SortBy is the sort field, the creation time by default
+	SortBy BlogSortField
+	// Ascending sorts the blogs in the ascending order of the sort field
+	Ascending bool
+	// After excludes the cursor and the blogs placed before it
+	After *BlogCursor
+	// Before excludes the cursor and the blogs placed after it
+	Before *BlogCursor
+	// Limit is the maximum number of blogs, zero means no limit
+	Limit int
+}
+
+// CursorOf returns the position of the blog in the sort order
+func CursorOf(blog *model.Blog) BlogCursor {
+	return BlogCursor{
+		CreatedAt: blog.CreatedAt,
+		UpdatedAt: blog.UpdatedAt,
+		Title:     blog.Title,
+		ID:        blog.ID,
+	}
+}
+
+// sortField returns the sort field of the query
+func (q BlogQuery) sortField() BlogSortField {
+	if q.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return q.SortBy
+}
+
+// compareCursors returns -1, 0 or +1 when a is lower, equal or greater than b
+// in the ascending order of the field
+func compareCursors(a BlogCursor, b BlogCursor, field BlogSortField) int {
+	var result int
+
+	switch field {
+	case SortByTitle:
+		result = strings.Compare(a.Title, b.Title)
+	case SortByUpdatedAt:
+		result = compareOptionalTimes(a.UpdatedAt, b.UpdatedAt)
+	default:
+		result = compareTimes(a.CreatedAt, b.CreatedAt)
+	}
+
+	if result != 0 {
+		return result
+	}
+
+	return strings.Compare(a.ID, b.ID)
+}
+
+// compareTimes returns -1, 0 or +1 when a is before, equal or after b
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// compareOptionalTimes compares two optional times, a missing time comes first
+func compareOptionalTimes(a *time.Time, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return compareTimes(*a, *b)
+}
+
+// less reports whether the blog at a is placed before the blog at b
+func (q BlogQuery) less(a BlogCursor, b BlogCursor) bool {
+	if q.Ascending {
+		return compareCursors(a, b, q.sortField()) < 0
+	}
+	return compareCursors(a, b, q.sortField()) > 0
+}
+
+// contains reports whether the position is inside the range of the query
+func (q BlogQuery) contains(position BlogCursor) bool {
+	if q.After != nil && !q.less(*q.After, position) {
+		return false
+	}
+
+	if q.Before != nil && !q.less(position, *q.Before) {
+		return false
+	}
+
+	return true
+}
+
+// matches reports whether the blog matches the filter
+func (f BlogFilter) matches(blog *model.Blog) bool {
+	if f.AuthorID != "" && (blog.Author == nil || blog.Author.ID != f.AuthorID) {
+		return false
+	}
+
+	if len(f.AuthorIDs) > 0 && (blog.Author == nil || !hasString(f.AuthorIDs, blog.Author.ID)) {
+		return false
+	}
+
+	if f.TitleContains != "" && !strings.Contains(strings.ToLower(blog.Title), strings.ToLower(f.TitleContains)) {
+		return false
+	}
+
+	if f.Tag != "" && !hasTag(blog.Tags, f.Tag) {
+		return false
+	}
+
+	if f.Status != "" && blog.Status != f.Status {
+		return false
+	}
+
+	if !f.Visibility.allows(blog) {
+		return false
+	}
+
+	if f.CreatedAfter != nil && !blog.CreatedAt.After(*f.CreatedAfter) {
+		return false
+	}
+
+	if f.CreatedBefore != nil && !blog.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+
+	if f.UpdatedAfter != nil && (blog.UpdatedAt == nil || !blog.UpdatedAt.After(*f.UpdatedAfter)) {
+		return false
+	}
+
+	if f.UpdatedBefore != nil && (blog.UpdatedAt == nil || !blog.UpdatedAt.Before(*f.UpdatedBefore)) {
+		return false
+	}
+
+	if f.Edited != nil && *f.Edited != (blog.UpdatedAt != nil) {
+		return false
+	}
+
+	return true
+}
+
+// hasTag reports whether the tag is one of the tags
+func hasTag(tags []string, tag string) bool {
+	return hasString(tags, tag)
+}
+
+// hasString reports whether the value is one of the values
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTagCounts sorts the tags by usage, the most used first, and then by name
+func sortTagCounts(counts []*model.TagCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+}
+
+// pageHits returns the hits of a page of search results
+func pageHits(hits []search.Hit, offset int, limit int) []search.Hit {
+	if offset >= len(hits) {
+		return []search.Hit{}
+	}
+
+	hits = hits[offset:]
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// CommentQuery represents a range of comments replying to the same parent
+// comments are sorted oldest first
+type CommentQuery struct {
+	BlogID string
+	// ParentID selects the replies of a comment, nil selects the top-level comments
+	ParentID *string
+	// After excludes the cursor and the comments placed before it
+	After *CommentCursor
+	// Limit is the maximum number of comments, zero means no limit
+	Limit int
+}
+
+// CommentCursor represents the position of a comment in the sort order
+// comments are sorted by the creation time and then by the ID
+type CommentCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CommentCursorOf returns the position of the comment
+func CommentCursorOf(comment *model.Comment) CommentCursor {
+	return CommentCursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
+
+// follows reports whether the comment is placed after the cursor
+func (c CommentCursor) follows(comment *model.Comment) bool {
+	if !comment.CreatedAt.Equal(c.CreatedAt) {
+		return comment.CreatedAt.After(c.CreatedAt)
+	}
+	return comment.ID > c.ID
+}
+
+// sameParent reports whether both parents are the same comment or both are top-level
+func sameParent(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
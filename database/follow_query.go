@@ -0,0 +1,58 @@
+package database
+
+import "time"
+
+// FollowQuery represents a range of the follows of a user
+// follows are sorted by the start time, the last started first
+type FollowQuery struct {
+	// FollowerID selects the follows started by the user
+	FollowerID string
+	// FolloweeID selects the follows of the user
+	FolloweeID string
+	// After excludes the cursor and the follows placed before it
+	After *FollowCursor
+	// Limit is the maximum number of follows, zero means no limit
+	Limit int
+}
+
+// FollowCursor represents the position of a follow in the sort order
+// follows are sorted by the start time and then by the ID of the listed user
+type FollowCursor struct {
+	CreatedAt time.Time
+	UserID    string
+}
+
+// listedUserID returns the ID of the user listed by the query for the follow:
+// the followed user for the follows started by a user, the follower otherwise
+func (q FollowQuery) listedUserID(follow Follow) string {
+	if q.FollowerID != "" {
+		return follow.FolloweeID
+	}
+	return follow.FollowerID
+}
+
+// CursorOf returns the position of the follow listed by the query
+func (q FollowQuery) CursorOf(follow Follow) FollowCursor {
+	return FollowCursor{CreatedAt: follow.CreatedAt, UserID: q.listedUserID(follow)}
+}
+
+// matches reports whether the follow is selected by the query
+func (q FollowQuery) matches(follow Follow) bool {
+	if q.FollowerID != "" && follow.FollowerID != q.FollowerID {
+		return false
+	}
+
+	if q.FolloweeID != "" && follow.FolloweeID != q.FolloweeID {
+		return false
+	}
+
+	return q.After == nil || q.After.precedes(q.CursorOf(follow))
+}
+
+// precedes reports whether the cursor is placed before the position
+func (c FollowCursor) precedes(position FollowCursor) bool {
+	if !c.CreatedAt.Equal(position.CreatedAt) {
+		return c.CreatedAt.After(position.CreatedAt)
+	}
+	return c.UserID > position.UserID
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore represents an in-process storage backend
+// the data is lost when the process exits
+type MemoryStore struct {
+	users     *MemoryUserRepository
+	blogs     *MemoryBlogRepository
+	tokens    *MemoryTokenRepository
+	comments  *MemoryCommentRepository
+	revisions *MemoryRevisionRepository
+	reactions *MemoryReactionRepository
+	follows   *MemoryFollowRepository
+	attempts  *MemoryLoginAttemptRepository
+	audit     *MemoryAuditRepository
+}
+
+// NewMemoryStore returns an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	var revisions *MemoryRevisionRepository = NewMemoryRevisionRepository()
+
+	return &MemoryStore{
+		users:     NewMemoryUserRepository(),
+		blogs:     NewMemoryBlogRepository(revisions),
+		tokens:    NewMemoryTokenRepository(),
+		comments:  NewMemoryCommentRepository(),
+		revisions: revisions,
+		reactions: NewMemoryReactionRepository(),
+		follows:   NewMemoryFollowRepository(),
+		attempts:  NewMemoryLoginAttemptRepository(),
+		audit:     NewMemoryAuditRepository(),
+	}
+}
+
+// Users returns the user repository
+func (s *MemoryStore) Users() UserRepository {
+	return s.users
+}
+
+// Blogs returns the blog repository
+func (s *MemoryStore) Blogs() BlogRepository {
+	return s.blogs
+}
+
+// Tokens returns the refresh token repository
+func (s *MemoryStore) Tokens() TokenRepository {
+	return s.tokens
+}
+
+// Comments returns the comment repository
+func (s *MemoryStore) Comments() CommentRepository {
+	return s.comments
+}
+
+// Revisions returns the blog revision repository
+func (s *MemoryStore) Revisions() RevisionRepository {
+	return s.revisions
+}
+
+// Reactions returns the reaction repository
+func (s *MemoryStore) Reactions() ReactionRepository {
+	return s.reactions
+}
+
+// Follows returns the follow repository
+func (s *MemoryStore) Follows() FollowRepository {
+	return s.follows
+}
+
+// LoginAttempts returns the failed login repository
+func (s *MemoryStore) LoginAttempts() LoginAttemptRepository {
+	return s.attempts
+}
+
+// Audit returns the audit record repository
+func (s *MemoryStore) Audit() AuditRepository {
+	return s.audit
+}
+
+// Drop removes all data from the store
+func (s *MemoryStore) Drop(ctx context.Context) error {
+	s.users.clear()
+	s.blogs.clear()
+	s.tokens.clear()
+	s.comments.table.clear()
+	s.revisions.table.clear()
+	s.reactions.table.clear()
+	s.follows.table.clear()
+	s.attempts.table.clear()
+	s.audit.table.clear()
+	return nil
+}
+
+// Close releases the resources held by the store
+func (s *MemoryStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// memoryTable represents a concurrency-safe table of records
+// the records are kept in the insertion order
+type memoryTable[T any] struct {
+	mu      sync.RWMutex
+	records []T
+}
+
+// clear removes all records from the table
+func (t *memoryTable[T]) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.records = nil
+}
+
+// newObjectID returns a new ID in the same format as MongoDB ObjectIDs
+func newObjectID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// validObjectID reports whether the ID has the format of a MongoDB ObjectID
+func validObjectID(id string) bool {
+	_, err := primitive.ObjectIDFromHex(id)
+	return err == nil
+}
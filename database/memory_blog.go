@@ -0,0 +1,389 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/search"
+)
+
+// MemoryBlogRepository stores blogs in memory
+type MemoryBlogRepository struct {
+	table memoryTable[model.Blog]
+	// trash holds the deleted blogs, it is guarded by the lock of the table
+	trash []model.Blog
+	index *search.Index
+	// revisions records the versions of the blogs while the table is locked
+	revisions *MemoryRevisionRepository
+}
+
+// NewMemoryBlogRepository returns an empty in-memory blog repository
+// which records the versions of the blogs in the given revision repository
+func NewMemoryBlogRepository(revisions *MemoryRevisionRepository) *MemoryBlogRepository {
+	return &MemoryBlogRepository{index: search.NewIndex(), revisions: revisions}
+}
+
+// ListBlogs returns the blogs matching the query
+func (r *MemoryBlogRepository) ListBlogs(ctx context.Context, query BlogQuery) ([]*model.Blog, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	blogs := make([]*model.Blog, 0)
+	for i := range r.table.records {
+		var blog *model.Blog = &r.table.records[i]
+
+		if query.Filter.matches(blog) && query.contains(CursorOf(blog)) {
+			blogs = append(blogs, copyBlog(blog))
+		}
+	}
+
+	sort.Slice(blogs, func(i, j int) bool {
+		return query.less(CursorOf(blogs[i]), CursorOf(blogs[j]))
+	})
+
+	if query.Limit > 0 && len(blogs) > query.Limit {
+		blogs = blogs[:query.Limit]
+	}
+
+	return blogs, nil
+}
+
+// SearchBlogs returns the published blogs whose title or content match the text, the most relevant first
+func (r *MemoryBlogRepository) SearchBlogs(ctx context.Context, text string, offset int, limit int) ([]BlogSearchHit, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	var hits []search.Hit = pageHits(r.index.Search(text), offset, limit)
+
+	results := make([]BlogSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		if index := r.indexOf(hit.ID, nil); index >= 0 {
+			results = append(results, BlogSearchHit{Blog: copyBlog(&r.table.records[index]), Score: hit.Score})
+		}
+	}
+
+	return results, nil
+}
+
+// GetBlogByID returns the blog with the given ID
+func (r *MemoryBlogRepository) GetBlogByID(ctx context.Context, id string) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	index := r.indexOf(id, nil)
+	if index < 0 {
+		return nil, ErrNotFound
+	}
+
+	return copyBlog(&r.table.records[index]), nil
+}
+
+// CreateBlog stores a new blog and returns the stored record
+func (r *MemoryBlogRepository) CreateBlog(ctx context.Context, blog model.Blog) (*model.Blog, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	blog.ID = newObjectID()
+	blog.Author = AuthorSnapshot(blog.Author)
+	r.table.records = append(r.table.records, *copyBlog(&blog))
+	r.revisions.record(nil, &blog, blog.Author)
+	r.reindex(&blog)
+
+	return copyBlog(&blog), nil
+}
+
+// UpdateBlog changes the title and the content of a blog
+func (r *MemoryBlogRepository) UpdateBlog(ctx context.Context, id string, authorID *string, update BlogUpdate) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(id, authorID)
+	if index < 0 {
+		return nil, ErrNotFound
+	}
+
+	var (
+		blog     *model.Blog = &r.table.records[index]
+		previous *model.Blog = copyBlog(blog)
+	)
+
+	*blog = *editedBlog(*blog, update)
+	r.revisions.record(previous, blog, update.Editor)
+	r.reindex(blog)
+
+	return copyBlog(blog), nil
+}
+
+// TrashBlog moves a blog to the trash
+func (r *MemoryBlogRepository) TrashBlog(ctx context.Context, id string, authorID *string, deletedAt time.Time) error {
+	if !validObjectID(id) {
+		return ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(id, authorID)
+	if index < 0 {
+		return ErrNotFound
+	}
+
+	var blog model.Blog = r.table.records[index]
+	blog.DeletedAt = &deletedAt
+
+	r.trash = append(r.trash, blog)
+	r.table.records = append(r.table.records[:index], r.table.records[index+1:]...)
+	r.index.Remove(id)
+
+	return nil
+}
+
+// RestoreBlog brings a blog back from the trash
+func (r *MemoryBlogRepository) RestoreBlog(ctx context.Context, id string, authorID *string) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	for i := range r.trash {
+		var blog model.Blog = r.trash[i]
+
+		if blog.ID != id {
+			continue
+		}
+
+		if authorID != nil && (blog.Author == nil || blog.Author.ID != *authorID) {
+			return nil, ErrNotFound
+		}
+
+		blog.DeletedAt = nil
+		r.trash = append(r.trash[:i], r.trash[i+1:]...)
+		r.table.records = append(r.table.records, blog)
+		r.reindex(&blog)
+
+		return copyBlog(&blog), nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// ListTrashedBlogs returns the blogs of the author in the trash, the last deleted first
+func (r *MemoryBlogRepository) ListTrashedBlogs(ctx context.Context, authorID string) ([]*model.Blog, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	blogs := make([]*model.Blog, 0)
+	for i := range r.trash {
+		if blog := &r.trash[i]; blog.Author != nil && blog.Author.ID == authorID {
+			blogs = append(blogs, copyBlog(blog))
+		}
+	}
+
+	sort.Slice(blogs, func(i, j int) bool {
+		if !blogs[i].DeletedAt.Equal(*blogs[j].DeletedAt) {
+			return blogs[i].DeletedAt.After(*blogs[j].DeletedAt)
+		}
+		return blogs[i].ID > blogs[j].ID
+	})
+
+	return blogs, nil
+}
+
+// ListExpiredBlogs returns the IDs of the blogs moved to the trash before the given time
+func (r *MemoryBlogRepository) ListExpiredBlogs(ctx context.Context, before time.Time) ([]string, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	var expired []string = []string{}
+	for i := range r.trash {
+		if r.trash[i].DeletedAt.Before(before) {
+			expired = append(expired, r.trash[i].ID)
+		}
+	}
+
+	return expired, nil
+}
+
+// PurgeTrashedBlog removes the blog when it was moved to the trash before the given time
+func (r *MemoryBlogRepository) PurgeTrashedBlog(ctx context.Context, id string, before time.Time) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	for i := range r.trash {
+		if r.trash[i].ID == id && r.trash[i].DeletedAt.Before(before) {
+			r.trash = append(r.trash[:i], r.trash[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// ListTags returns the tags of the blogs with their usage, the most used first
+func (r *MemoryBlogRepository) ListTags(ctx context.Context) ([]*model.TagCount, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	var usage map[string]int = make(map[string]int)
+	for i := range r.table.records {
+		if r.table.records[i].Status != model.BlogStatusPublished {
+			continue
+		}
+		for _, tag := range r.table.records[i].Tags {
+			usage[tag]++
+		}
+	}
+
+	counts := make([]*model.TagCount, 0, len(usage))
+	for tag, count := range usage {
+		counts = append(counts, &model.TagCount{Tag: tag, Count: count})
+	}
+	sortTagCounts(counts)
+
+	return counts, nil
+}
+
+// UpdateBlogStatus changes the publication state of a blog
+func (r *MemoryBlogRepository) UpdateBlogStatus(ctx context.Context, id string, authorID *string, update BlogStatusUpdate) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(id, authorID)
+	if index < 0 {
+		return nil, ErrNotFound
+	}
+
+	var blog *model.Blog = &r.table.records[index]
+	applyStatusUpdate(blog, update)
+	r.reindex(blog)
+
+	return copyBlog(blog), nil
+}
+
+// PublishScheduledBlogs publishes the scheduled blogs whose publication time has come
+func (r *MemoryBlogRepository) PublishScheduledBlogs(ctx context.Context, now time.Time) ([]ScheduledPublication, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	var published []ScheduledPublication
+	for i := range r.table.records {
+		var blog *model.Blog = &r.table.records[i]
+
+		if blog.Status != model.BlogStatusScheduled || blog.PublishAt == nil || blog.PublishAt.After(now) {
+			continue
+		}
+
+		var first bool = blog.PublishedAt == nil
+		applyStatusUpdate(blog, BlogStatusUpdate{Status: model.BlogStatusPublished, PublishedAt: blog.PublishAt})
+		r.reindex(blog)
+		published = append(published, ScheduledPublication{Blog: copyBlog(blog), First: first})
+	}
+
+	return published, nil
+}
+
+// reindex keeps the search index in line with the blog, only the published blogs are searched
+// the caller must hold the lock of the table
+func (r *MemoryBlogRepository) reindex(blog *model.Blog) {
+	if blog.Status == model.BlogStatusPublished {
+		r.index.Add(blog.ID, blog.Title, blog.Content)
+		return
+	}
+	r.index.Remove(blog.ID)
+}
+
+// applyStatusUpdate changes the publication state of the blog
+func applyStatusUpdate(blog *model.Blog, update BlogStatusUpdate) {
+	blog.Status = update.Status
+	blog.PublishAt = nil
+	if update.PublishAt != nil {
+		var publishAt time.Time = *update.PublishAt
+		blog.PublishAt = &publishAt
+	}
+	if update.PublishedAt != nil {
+		var publishedAt time.Time = *update.PublishedAt
+		blog.PublishedAt = &publishedAt
+	}
+}
+
+// indexOf returns the position of the blog with the given ID
+// when authorID is set, the blog must also be owned by that author
+// the caller must hold the lock of the table
+func (r *MemoryBlogRepository) indexOf(id string, authorID *string) int {
+	for i := range r.table.records {
+		var blog *model.Blog = &r.table.records[i]
+
+		if blog.ID != id {
+			continue
+		}
+
+		if authorID != nil && (blog.Author == nil || blog.Author.ID != *authorID) {
+			return -1
+		}
+
+		return i
+	}
+
+	return -1
+}
+
+// clear removes all blogs
+func (r *MemoryBlogRepository) clear() {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	r.table.records = nil
+	r.trash = nil
+	r.index.Clear()
+}
+
+// copyBlog returns a deep copy of the blog
+// so callers cannot change the stored record
+func copyBlog(blog *model.Blog) *model.Blog {
+	var copied model.Blog = *blog
+
+	if blog.Author != nil {
+		var author model.User = *blog.Author
+		copied.Author = &author
+	}
+
+	if blog.UpdatedAt != nil {
+		var updatedAt = *blog.UpdatedAt
+		copied.UpdatedAt = &updatedAt
+	}
+
+	copied.Tags = append([]string{}, blog.Tags...)
+
+	if blog.PublishAt != nil {
+		var publishAt = *blog.PublishAt
+		copied.PublishAt = &publishAt
+	}
+
+	if blog.PublishedAt != nil {
+		var publishedAt = *blog.PublishedAt
+		copied.PublishedAt = &publishedAt
+	}
+
+	if blog.DeletedAt != nil {
+		var deletedAt = *blog.DeletedAt
+		copied.DeletedAt = &deletedAt
+	}
+
+	return &copied
+}
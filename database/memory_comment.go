@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// MemoryCommentRepository stores comments in memory
+type MemoryCommentRepository struct {
+	table memoryTable[model.Comment]
+}
+
+// NewMemoryCommentRepository returns an empty in-memory comment repository
+func NewMemoryCommentRepository() *MemoryCommentRepository {
+	return &MemoryCommentRepository{}
+}
+
+// ListComments returns the comments matching the query, oldest first
+func (r *MemoryCommentRepository) ListComments(ctx context.Context, query CommentQuery) ([]*model.Comment, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	comments := make([]*model.Comment, 0)
+	for i := range r.table.records {
+		var comment *model.Comment = &r.table.records[i]
+
+		if comment.BlogID != query.BlogID || !sameParent(comment.ParentID, query.ParentID) {
+			continue
+		}
+
+		if query.After != nil && !query.After.follows(comment) {
+			continue
+		}
+
+		comments = append(comments, copyComment(comment))
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		return CommentCursorOf(comments[i]).follows(comments[j])
+	})
+
+	if query.Limit > 0 && len(comments) > query.Limit {
+		comments = comments[:query.Limit]
+	}
+
+	return comments, nil
+}
+
+// GetCommentByID returns the comment with the given ID
+func (r *MemoryCommentRepository) GetCommentByID(ctx context.Context, id string) (*model.Comment, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	index := r.indexOf(id)
+	if index < 0 {
+		return nil, ErrNotFound
+	}
+
+	return copyComment(&r.table.records[index]), nil
+}
+
+// CountComments returns the number of comments of the blog, including the replies
+func (r *MemoryCommentRepository) CountComments(ctx context.Context, blogID string) (int, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	var count int
+	for i := range r.table.records {
+		if r.table.records[i].BlogID == blogID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// CountReplies returns the number of direct replies of the comment
+func (r *MemoryCommentRepository) CountReplies(ctx context.Context, commentID string) (int, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	var count int
+	for i := range r.table.records {
+		if parentID := r.table.records[i].ParentID; parentID != nil && *parentID == commentID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// CreateComment stores a new comment and returns the stored record
+func (r *MemoryCommentRepository) CreateComment(ctx context.Context, comment model.Comment) (*model.Comment, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	comment.ID = newObjectID()
+	comment.Author = AuthorSnapshot(comment.Author)
+	r.table.records = append(r.table.records, *copyComment(&comment))
+
+	return copyComment(&comment), nil
+}
+
+// UpdateComment changes the content of a comment
+func (r *MemoryCommentRepository) UpdateComment(ctx context.Context, id string, content string, updatedAt time.Time) (*model.Comment, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(id)
+	if index < 0 {
+		return nil, ErrNotFound
+	}
+
+	var comment *model.Comment = &r.table.records[index]
+	comment.Content = content
+	comment.UpdatedAt = &updatedAt
+
+	return copyComment(comment), nil
+}
+
+// DeleteComment removes a comment and all of its replies
+func (r *MemoryCommentRepository) DeleteComment(ctx context.Context, id string) error {
+	if !validObjectID(id) {
+		return ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	if r.indexOf(id) < 0 {
+		return ErrNotFound
+	}
+
+	// collect the comment and its replies level by level
+	var removed map[string]bool = map[string]bool{id: true}
+	for found := true; found; {
+		found = false
+		for i := range r.table.records {
+			var comment *model.Comment = &r.table.records[i]
+			if !removed[comment.ID] && comment.ParentID != nil && removed[*comment.ParentID] {
+				removed[comment.ID] = true
+				found = true
+			}
+		}
+	}
+
+	r.remove(func(comment *model.Comment) bool { return removed[comment.ID] })
+
+	return nil
+}
+
+// DeleteBlogComments removes all comments of the blog
+func (r *MemoryCommentRepository) DeleteBlogComments(ctx context.Context, blogID string) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	r.remove(func(comment *model.Comment) bool { return comment.BlogID == blogID })
+
+	return nil
+}
+
+// remove drops the comments matching the condition
+// the caller must hold the lock of the table
+func (r *MemoryCommentRepository) remove(matches func(comment *model.Comment) bool) {
+	var kept []model.Comment
+	for i := range r.table.records {
+		if !matches(&r.table.records[i]) {
+			kept = append(kept, r.table.records[i])
+		}
+	}
+	r.table.records = kept
+}
+
+// indexOf returns the position of the comment with the given ID
+// the caller must hold the lock of the table
+func (r *MemoryCommentRepository) indexOf(id string) int {
+	for i := range r.table.records {
+		if r.table.records[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// copyComment returns a deep copy of the comment
+// so callers cannot change the stored record
+func copyComment(comment *model.Comment) *model.Comment {
+	var copied model.Comment = *comment
+
+	if comment.Author != nil {
+		var author model.User = *comment.Author
+		copied.Author = &author
+	}
+
+	if comment.ParentID != nil {
+		var parentID = *comment.ParentID
+		copied.ParentID = &parentID
+	}
+
+	if comment.UpdatedAt != nil {
+		var updatedAt = *comment.UpdatedAt
+		copied.UpdatedAt = &updatedAt
+	}
+
+	copied.Replies = nil
+
+	return &copied
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"sort"
+)
+
+// MemoryFollowRepository stores the follows between users in memory
+type MemoryFollowRepository struct {
+	table memoryTable[Follow]
+}
+
+// NewMemoryFollowRepository returns an empty in-memory follow repository
+func NewMemoryFollowRepository() *MemoryFollowRepository {
+	return &MemoryFollowRepository{}
+}
+
+// Follow stores the follow, it reports false when the follower already follows the user
+func (r *MemoryFollowRepository) Follow(ctx context.Context, follow Follow) (bool, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	if r.indexOf(follow.FollowerID, follow.FolloweeID) >= 0 {
+		return false, nil
+	}
+
+	r.table.records = append(r.table.records, follow)
+
+	return true, nil
+}
+
+// Unfollow removes the follow, it reports false when there was none
+func (r *MemoryFollowRepository) Unfollow(ctx context.Context, followerID string, followeeID string) (bool, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(followerID, followeeID)
+	if index < 0 {
+		return false, nil
+	}
+
+	r.table.records = append(r.table.records[:index], r.table.records[index+1:]...)
+
+	return true, nil
+}
+
+// ListFollows returns the follows matching the query, the last started first
+func (r *MemoryFollowRepository) ListFollows(ctx context.Context, query FollowQuery) ([]Follow, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	follows := make([]Follow, 0)
+	for i := range r.table.records {
+		if query.matches(r.table.records[i]) {
+			follows = append(follows, r.table.records[i])
+		}
+	}
+
+	sort.Slice(follows, func(i, j int) bool {
+		return query.CursorOf(follows[i]).precedes(query.CursorOf(follows[j]))
+	})
+
+	if query.Limit > 0 && len(follows) > query.Limit {
+		follows = follows[:query.Limit]
+	}
+
+	return follows, nil
+}
+
+// indexOf returns the position of the follow
+// the caller must hold the lock of the table
+func (r *MemoryFollowRepository) indexOf(followerID string, followeeID string) int {
+	for i := range r.table.records {
+		var follow *Follow = &r.table.records[i]
+
+		if follow.FollowerID == followerID && follow.FolloweeID == followeeID {
+			return i
+		}
+	}
+
+	return -1
+}
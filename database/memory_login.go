@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// MemoryLoginAttemptRepository stores the failed logins in memory
+type MemoryLoginAttemptRepository struct {
+	table memoryTable[LoginFailures]
+}
+
+// NewMemoryLoginAttemptRepository returns an empty in-memory failed login repository
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{}
+}
+
+// GetLoginFailures returns the failures of the key, it fails with ErrNotFound when there are none
+func (r *MemoryLoginAttemptRepository) GetLoginFailures(ctx context.Context, key string) (*LoginFailures, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	index := r.indexOf(key)
+	if index < 0 {
+		return nil, ErrNotFound
+	}
+
+	var failures LoginFailures = r.table.records[index]
+	return &failures, nil
+}
+
+// RecordLoginFailure counts a failed login of the key and returns the updated record
+func (r *MemoryLoginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, at time.Time, since time.Time) (*LoginFailures, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(key)
+	if index < 0 {
+		r.table.records = append(r.table.records, LoginFailures{Key: key})
+		index = len(r.table.records) - 1
+	}
+
+	var failures *LoginFailures = &r.table.records[index]
+	if failures.LastFailedAt.Before(since) {
+		failures.Count = 0
+	}
+	failures.Count++
+	failures.LastFailedAt = at
+
+	var updated LoginFailures = *failures
+	return &updated, nil
+}
+
+// LockLogin refuses the logins of the key until the given time
+func (r *MemoryLoginAttemptRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(key)
+	if index < 0 {
+		r.table.records = append(r.table.records, LoginFailures{Key: key})
+		index = len(r.table.records) - 1
+	}
+	r.table.records[index].LockedUntil = &until
+
+	return nil
+}
+
+// ResetLoginFailures forgets the failures and the lock of the key
+func (r *MemoryLoginAttemptRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	if index := r.indexOf(key); index >= 0 {
+		r.table.records = append(r.table.records[:index], r.table.records[index+1:]...)
+	}
+
+	return nil
+}
+
+// indexOf returns the position of the failures of the key
+// the caller must hold the lock of the table
+func (r *MemoryLoginAttemptRepository) indexOf(key string) int {
+	for i := range r.table.records {
+		if r.table.records[i].Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// MemoryAuditRepository stores the security events in memory
+type MemoryAuditRepository struct {
+	table memoryTable[AuditRecord]
+}
+
+// NewMemoryAuditRepository returns an empty in-memory audit record repository
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+// CreateAuditRecord stores a new record and returns its ID
+func (r *MemoryAuditRepository) CreateAuditRecord(ctx context.Context, record AuditRecord) (string, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	record.ID = newObjectID()
+	r.table.records = append(r.table.records, record)
+
+	return record.ID, nil
+}
+
+// ListAuditRecords returns the latest records, newest first
+func (r *MemoryAuditRepository) ListAuditRecords(ctx context.Context, userID string, limit int) ([]AuditRecord, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	// the records are read from the last stored, so the records created at the same time stay newest first
+	records := make([]AuditRecord, 0)
+	for i := len(r.table.records) - 1; i >= 0; i-- {
+		if userID == "" || r.table.records[i].UserID == userID {
+			records = append(records, r.table.records[i])
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.After(records[j].CreatedAt)
+	})
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
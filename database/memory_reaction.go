@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// MemoryReactionRepository stores the reactions to blogs in memory
+type MemoryReactionRepository struct {
+	table memoryTable[Reaction]
+}
+
+// NewMemoryReactionRepository returns an empty in-memory reaction repository
+func NewMemoryReactionRepository() *MemoryReactionRepository {
+	return &MemoryReactionRepository{}
+}
+
+// AddReaction stores the reaction, it reports false when the user already reacted with the kind
+func (r *MemoryReactionRepository) AddReaction(ctx context.Context, reaction Reaction) (bool, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	if r.indexOf(reaction.BlogID, reaction.UserID, reaction.Kind) >= 0 {
+		return false, nil
+	}
+
+	r.table.records = append(r.table.records, reaction)
+
+	return true, nil
+}
+
+// RemoveReaction removes the reaction of the user with the kind, it reports false when there was none
+func (r *MemoryReactionRepository) RemoveReaction(ctx context.Context, blogID string, userID string, kind model.ReactionKind) (bool, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	index := r.indexOf(blogID, userID, kind)
+	if index < 0 {
+		return false, nil
+	}
+
+	r.table.records = append(r.table.records[:index], r.table.records[index+1:]...)
+
+	return true, nil
+}
+
+// CountReactions returns the number of reactions of each kind to the blog
+func (r *MemoryReactionRepository) CountReactions(ctx context.Context, blogID string) (map[model.ReactionKind]int, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	var counts map[model.ReactionKind]int = make(map[model.ReactionKind]int)
+	for i := range r.table.records {
+		if r.table.records[i].BlogID == blogID {
+			counts[r.table.records[i].Kind]++
+		}
+	}
+
+	return counts, nil
+}
+
+// ListUserReactions returns the kinds the user reacted with to the blog
+func (r *MemoryReactionRepository) ListUserReactions(ctx context.Context, blogID string, userID string) ([]model.ReactionKind, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	kinds := make([]model.ReactionKind, 0)
+	for i := range r.table.records {
+		if r.table.records[i].BlogID == blogID && r.table.records[i].UserID == userID {
+			kinds = append(kinds, r.table.records[i].Kind)
+		}
+	}
+
+	return kinds, nil
+}
+
+// DeleteBlogReactions removes the reactions to the blog
+func (r *MemoryReactionRepository) DeleteBlogReactions(ctx context.Context, blogID string) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	var kept []Reaction
+	for i := range r.table.records {
+		if r.table.records[i].BlogID != blogID {
+			kept = append(kept, r.table.records[i])
+		}
+	}
+	r.table.records = kept
+
+	return nil
+}
+
+// indexOf returns the position of the reaction of the user with the kind
+// the caller must hold the lock of the table
+func (r *MemoryReactionRepository) indexOf(blogID string, userID string, kind model.ReactionKind) int {
+	for i := range r.table.records {
+		var reaction *Reaction = &r.table.records[i]
+
+		if reaction.BlogID == blogID && reaction.UserID == userID && reaction.Kind == kind {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"sort"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// MemoryRevisionRepository stores blog revisions in memory
+type MemoryRevisionRepository struct {
+	table memoryTable[model.BlogRevision]
+}
+
+// NewMemoryRevisionRepository returns an empty in-memory revision repository
+func NewMemoryRevisionRepository() *MemoryRevisionRepository {
+	return &MemoryRevisionRepository{}
+}
+
+// ListRevisions returns the revisions of the blog, newest first
+func (r *MemoryRevisionRepository) ListRevisions(ctx context.Context, blogID string) ([]*model.BlogRevision, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	revisions := make([]*model.BlogRevision, 0)
+	for i := range r.table.records {
+		if r.table.records[i].BlogID == blogID {
+			revisions = append(revisions, copyRevision(&r.table.records[i]))
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Version > revisions[j].Version
+	})
+
+	return revisions, nil
+}
+
+// GetRevision returns the revision of the blog with the given version
+func (r *MemoryRevisionRepository) GetRevision(ctx context.Context, blogID string, version int) (*model.BlogRevision, error) {
+	if !validObjectID(blogID) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	for i := range r.table.records {
+		var revision *model.BlogRevision = &r.table.records[i]
+		if revision.BlogID == blogID && revision.Version == version {
+			return copyRevision(revision), nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// record appends the revision of the edit of the blog
+// previous is the version replaced by the edit, nil when the blog has just been created
+// the blogs created before the revisions were recorded get previous as their first revision
+func (r *MemoryRevisionRepository) record(previous *model.Blog, blog *model.Blog, editor *model.User) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	var latest int
+	for i := range r.table.records {
+		if stored := &r.table.records[i]; stored.BlogID == blog.ID && stored.Version > latest {
+			latest = stored.Version
+		}
+	}
+
+	if previous != nil && latest == 0 {
+		latest = 1
+		r.table.records = append(r.table.records, newRevision(nil, previous, previous.Author, latest))
+	}
+
+	r.table.records = append(r.table.records, newRevision(previous, blog, editor, latest+1))
+}
+
+// DeleteBlogRevisions removes the revisions of the blog
+func (r *MemoryRevisionRepository) DeleteBlogRevisions(ctx context.Context, blogID string) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	var kept []model.BlogRevision
+	for i := range r.table.records {
+		if r.table.records[i].BlogID != blogID {
+			kept = append(kept, r.table.records[i])
+		}
+	}
+	r.table.records = kept
+
+	return nil
+}
+
+// copyRevision returns a deep copy of the revision
+// so callers cannot change the stored record
+func copyRevision(revision *model.BlogRevision) *model.BlogRevision {
+	var copied model.BlogRevision = *revision
+
+	if revision.Editor != nil {
+		var editor model.User = *revision.Editor
+		copied.Editor = &editor
+	}
+
+	return &copied
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// MemoryTokenRepository stores refresh tokens and revoked access tokens in memory
+type MemoryTokenRepository struct {
+	table   memoryTable[RefreshToken]
+	revoked memoryTable[RevokedToken]
+}
+
+// NewMemoryTokenRepository returns an empty in-memory refresh token repository
+func NewMemoryTokenRepository() *MemoryTokenRepository {
+	return &MemoryTokenRepository{}
+}
+
+// CreateRefreshToken stores a new refresh token and returns its ID
+func (r *MemoryTokenRepository) CreateRefreshToken(ctx context.Context, token RefreshToken) (string, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	token.ID = newObjectID()
+	if token.FamilyID == "" {
+		token.FamilyID = token.ID
+	}
+	r.table.records = append(r.table.records, token)
+
+	return token.ID, nil
+}
+
+// GetRefreshTokenByHash returns the refresh token with the given hash
+func (r *MemoryTokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	for i := range r.table.records {
+		if r.table.records[i].TokenHash == hash {
+			var token RefreshToken = r.table.records[i]
+			return &token, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// RotateRefreshToken revokes a token replaced by another token of its family
+func (r *MemoryTokenRepository) RotateRefreshToken(ctx context.Context, id string, replacedBy string, at time.Time) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	for i := range r.table.records {
+		var token *RefreshToken = &r.table.records[i]
+		if token.ID == id && token.RevokedAt == nil {
+			token.RevokedAt = &at
+			token.ReplacedBy = replacedBy
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// RevokeTokenFamily revokes every token of the family
+func (r *MemoryTokenRepository) RevokeTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	for i := range r.table.records {
+		var token *RefreshToken = &r.table.records[i]
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+
+	return nil
+}
+
+// RevokeAccessToken records the ID of an access token that must no longer be accepted
+func (r *MemoryTokenRepository) RevokeAccessToken(ctx context.Context, token RevokedToken) error {
+	r.revoked.mu.Lock()
+	defer r.revoked.mu.Unlock()
+
+	r.putRevoked(token)
+
+	return nil
+}
+
+// RevokeUserSessions revokes every refresh token of the user
+// and every access token issued to the user until the given time
+func (r *MemoryTokenRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time, expiresAt time.Time) error {
+	r.table.mu.Lock()
+	for i := range r.table.records {
+		var token *RefreshToken = &r.table.records[i]
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	r.table.mu.Unlock()
+
+	r.revoked.mu.Lock()
+	defer r.revoked.mu.Unlock()
+
+	r.putRevoked(RevokedToken{ID: userRevocationID(userID), UserID: userID, RevokedAt: at, ExpiresAt: expiresAt})
+
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the access token has been revoked
+func (r *MemoryTokenRepository) IsAccessTokenRevoked(ctx context.Context, id string, userID string, issuedAt time.Time) (bool, error) {
+	r.revoked.mu.RLock()
+	defer r.revoked.mu.RUnlock()
+
+	for _, revoked := range r.revoked.records {
+		if revoked.revokes(id, userID, issuedAt) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// PruneTokens removes the refresh tokens and revocations that expired before the given time
+func (r *MemoryTokenRepository) PruneTokens(ctx context.Context, before time.Time) error {
+	r.table.mu.Lock()
+	var tokens []RefreshToken = r.table.records[:0]
+	for _, token := range r.table.records {
+		if !token.ExpiresAt.Before(before) {
+			tokens = append(tokens, token)
+		}
+	}
+	r.table.records = tokens
+	r.table.mu.Unlock()
+
+	r.revoked.mu.Lock()
+	defer r.revoked.mu.Unlock()
+
+	var revoked []RevokedToken = r.revoked.records[:0]
+	for _, token := range r.revoked.records {
+		if !token.ExpiresAt.Before(before) {
+			revoked = append(revoked, token)
+		}
+	}
+	r.revoked.records = revoked
+
+	return nil
+}
+
+// putRevoked adds or replaces a revocation, the caller must hold the lock
+func (r *MemoryTokenRepository) putRevoked(token RevokedToken) {
+	for i := range r.revoked.records {
+		if r.revoked.records[i].ID == token.ID {
+			r.revoked.records[i] = token
+			return
+		}
+	}
+
+	r.revoked.records = append(r.revoked.records, token)
+}
+
+// clear removes all refresh tokens and revocations
+func (r *MemoryTokenRepository) clear() {
+	r.table.clear()
+	r.revoked.clear()
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// MemoryUserRepository stores users in memory
+type MemoryUserRepository struct {
+	table memoryTable[User]
+}
+
+// NewMemoryUserRepository returns an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{}
+}
+
+// CreateUser stores a new user and returns its ID
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user User) (string, error) {
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	// the email is checked under the lock, so concurrent registrations cannot both store it
+	for i := range r.table.records {
+		if strings.EqualFold(r.table.records[i].Email, user.Email) {
+			return "", ErrDuplicate
+		}
+	}
+
+	user.ID = newObjectID()
+	r.table.records = append(r.table.records, user)
+
+	return user.ID, nil
+}
+
+// GetUserByID returns the user with the given ID
+func (r *MemoryUserRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	return r.find(func(user *User) bool { return user.ID == id })
+}
+
+// GetUsersByIDs returns the users with the given IDs in any order
+// the IDs without a user are left out
+func (r *MemoryUserRepository) GetUsersByIDs(ctx context.Context, ids []string) ([]*User, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	users := make([]*User, 0, len(ids))
+	for i := range r.table.records {
+		if hasString(ids, r.table.records[i].ID) {
+			var user User = r.table.records[i]
+			users = append(users, &user)
+		}
+	}
+
+	return users, nil
+}
+
+// GetUserByEmail returns the user with the given email
+func (r *MemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return r.find(func(user *User) bool { return strings.EqualFold(user.Email, email) })
+}
+
+// UpdateUserRole changes the role of a user and returns the stored record
+func (r *MemoryUserRepository) UpdateUserRole(ctx context.Context, id string, role model.Role, updatedAt time.Time) (*User, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	for i := range r.table.records {
+		var user *User = &r.table.records[i]
+		if user.ID == id {
+			user.Role = role
+			user.UpdatedAt = &updatedAt
+
+			var updated User = *user
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// UpdateUserProfile replaces the profile of a user and returns the stored record
+func (r *MemoryUserRepository) UpdateUserProfile(ctx context.Context, id string, profile UserProfile, updatedAt time.Time) (*User, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	r.table.mu.Lock()
+	defer r.table.mu.Unlock()
+
+	for i := range r.table.records {
+		var user *User = &r.table.records[i]
+		if user.ID == id {
+			user.DisplayName = profile.DisplayName
+			user.Bio = profile.Bio
+			user.AvatarURL = profile.AvatarURL
+			user.UpdatedAt = &updatedAt
+
+			var updated User = *user
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// find returns a copy of the first user matching the predicate
+func (r *MemoryUserRepository) find(match func(user *User) bool) (*User, error) {
+	r.table.mu.RLock()
+	defer r.table.mu.RUnlock()
+
+	for i := range r.table.records {
+		if match(&r.table.records[i]) {
+			var user User = r.table.records[i]
+			return &user, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// clear removes all users
+func (r *MemoryUserRepository) clear() {
+	r.table.clear()
+}
@@ -50,3 +50,140 @@ func GetCollection(name string) *mongo.Collection {
 	// return the collection from the MongoDB database
 	return Mongo.Database.Collection(name)
 }
+
+// MongoStore represents the MongoDB storage backend
+type MongoStore struct {
+	database  *mongo.Database
+	users     *MongoUserRepository
+	blogs     *MongoBlogRepository
+	tokens    *MongoTokenRepository
+	comments  *MongoCommentRepository
+	revisions *MongoRevisionRepository
+	reactions *MongoReactionRepository
+	follows   *MongoFollowRepository
+	attempts  *MongoLoginAttemptRepository
+	audit     *MongoAuditRepository
+}
+
+// NewMongoStore returns a store backed by the given MongoDB database
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{
+		database:  db,
+		users:     NewMongoUserRepository(db),
+		blogs:     NewMongoBlogRepository(db),
+		tokens:    NewMongoTokenRepository(db),
+		comments:  NewMongoCommentRepository(db),
+		revisions: NewMongoRevisionRepository(db),
+		reactions: NewMongoReactionRepository(db),
+		follows:   NewMongoFollowRepository(db),
+		attempts:  NewMongoLoginAttemptRepository(db),
+		audit:     NewMongoAuditRepository(db),
+	}
+}
+
+// CreateIndexes creates the indexes used by the repositories
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if err := s.users.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.blogs.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.tokens.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.comments.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.revisions.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.reactions.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.follows.createIndexes(ctx); err != nil {
+		return err
+	}
+
+	return s.audit.createIndexes(ctx)
+}
+
+// Migrate creates the indexes and upgrades the documents stored by older versions
+func (s *MongoStore) Migrate(ctx context.Context) error {
+	if err := s.CreateIndexes(ctx); err != nil {
+		return err
+	}
+
+	// the emails used to be stored as they were typed
+	if err := s.users.normalizeEmails(ctx); err != nil {
+		return err
+	}
+
+	// the blogs used to store the credentials of their author
+	if err := s.blogs.removeAuthorCredentials(ctx); err != nil {
+		return err
+	}
+
+	return s.blogs.publishLegacyBlogs(ctx)
+}
+
+// Users returns the user repository
+func (s *MongoStore) Users() UserRepository {
+	return s.users
+}
+
+// Blogs returns the blog repository
+func (s *MongoStore) Blogs() BlogRepository {
+	return s.blogs
+}
+
+// Tokens returns the refresh token repository
+func (s *MongoStore) Tokens() TokenRepository {
+	return s.tokens
+}
+
+// Comments returns the comment repository
+func (s *MongoStore) Comments() CommentRepository {
+	return s.comments
+}
+
+// Revisions returns the blog revision repository
+func (s *MongoStore) Revisions() RevisionRepository {
+	return s.revisions
+}
+
+// Reactions returns the reaction repository
+func (s *MongoStore) Reactions() ReactionRepository {
+	return s.reactions
+}
+
+// Follows returns the follow repository
+func (s *MongoStore) Follows() FollowRepository {
+	return s.follows
+}
+
+// LoginAttempts returns the failed login repository
+func (s *MongoStore) LoginAttempts() LoginAttemptRepository {
+	return s.attempts
+}
+
+// Audit returns the audit record repository
+func (s *MongoStore) Audit() AuditRepository {
+	return s.audit
+}
+
+// Drop removes all collections from the database
+func (s *MongoStore) Drop(ctx context.Context) error {
+	return s.database.Drop(ctx)
+}
+
+// Close disconnects the MongoDB client
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.database.Client().Disconnect(ctx)
+}
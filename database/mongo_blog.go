@@ -0,0 +1,745 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errEditConflict is returned when the other edits of a blog kept taking the version of an edit
+var errEditConflict = errors.New("the blog is edited concurrently")
+
+// MongoBlogRepository stores blogs in the "blogs" collection
+// the revisions of the blogs are stored in the "blog_revisions" collection next to their edits
+type MongoBlogRepository struct {
+	collection *mongo.Collection
+	revisions  *mongo.Collection
+}
+
+// mongoVersionedBlog is a blog document together with the version of its latest revision
+// the blogs edited before the versions were stored have no version
+type mongoVersionedBlog struct {
+	model.Blog `bson:",inline"`
+	Version    *int `bson:"version"`
+}
+
+// NewMongoBlogRepository returns a blog repository for the given database
+func NewMongoBlogRepository(db *mongo.Database) *MongoBlogRepository {
+	return &MongoBlogRepository{
+		collection: db.Collection(utils.BLOG_COLLECTION),
+		revisions:  db.Collection(utils.REVISION_COLLECTION),
+	}
+}
+
+// createIndexes creates the indexes of the "blogs" collection
+func (r *MongoBlogRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// keyset pagination sorts by the sort field and the ID
+		{Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+		// blogs are filtered by author
+		{Keys: bson.D{{Key: "author._id", Value: 1}}},
+		// blogs are listed by tag, newest first, and the tags are counted
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+		// the scheduler looks up the scheduled blogs by publication time
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "publishAt", Value: 1}}},
+		// the trash is listed by author and purged by deletion time
+		{Keys: bson.D{{Key: "author._id", Value: 1}, {Key: "deletedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "deletedAt", Value: 1}}},
+		// blogs are searched by title and content, the title weighs more
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().
+				SetName("blogs_text").
+				SetWeights(bson.D{{Key: "title", Value: 2}, {Key: "content", Value: 1}}),
+		},
+	})
+
+	return err
+}
+
+// ListBlogs returns the blogs matching the query
+func (r *MongoBlogRepository) ListBlogs(ctx context.Context, query BlogQuery) ([]*model.Blog, error) {
+	var (
+		field       string               = string(query.sortField())
+		direction   int                  = -1
+		conditions  bson.A               = mongoBlogFilter(query.Filter)
+		findOptions *options.FindOptions = options.Find()
+	)
+
+	if query.Ascending {
+		direction = 1
+	}
+
+	// blogs placed after the cursor follow the sort direction
+	if query.After != nil {
+		condition, err := mongoCursorCondition(*query.After, field, direction)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	// blogs placed before the cursor go against the sort direction
+	if query.Before != nil {
+		condition, err := mongoCursorCondition(*query.Before, field, -direction)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	var filter primitive.D = bson.D{}
+	if len(conditions) > 0 {
+		filter = bson.D{{Key: "$and", Value: conditions}}
+	}
+
+	findOptions.SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}})
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	blogs := make([]*model.Blog, 0)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return nil, err
+	}
+
+	return blogs, nil
+}
+
+// SearchBlogs returns the published blogs whose title or content match the text, the most relevant first
+func (r *MongoBlogRepository) SearchBlogs(ctx context.Context, text string, offset int, limit int) ([]BlogSearchHit, error) {
+	var (
+		filter primitive.D = bson.D{
+			{Key: "$text", Value: bson.D{{Key: "$search", Value: text}}},
+			{Key: "status", Value: model.BlogStatusPublished},
+			{Key: "deletedAt", Value: nil},
+		}
+		score       primitive.D          = bson.D{{Key: "$meta", Value: "textScore"}}
+		findOptions *options.FindOptions = options.Find()
+	)
+
+	findOptions.SetProjection(bson.D{{Key: "score", Value: score}})
+	findOptions.SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}})
+	findOptions.SetSkip(int64(offset))
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// the score is returned next to the fields of the blog
+	var documents []struct {
+		Blog  model.Blog `bson:",inline"`
+		Score float64    `bson:"score"`
+	}
+
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	hits := make([]BlogSearchHit, 0, len(documents))
+	for i := range documents {
+		hits = append(hits, BlogSearchHit{Blog: &documents[i].Blog, Score: documents[i].Score})
+	}
+
+	return hits, nil
+}
+
+// GetBlogByID returns the blog with the given ID
+func (r *MongoBlogRepository) GetBlogByID(ctx context.Context, id string) (*model.Blog, error) {
+	blogID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	blog := &model.Blog{}
+
+	if err := r.collection.FindOne(ctx, mongoOwnedBlog(blogID, nil)).Decode(blog); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return blog, nil
+}
+
+// CreateBlog stores a new blog together with its first revision and returns the stored record
+func (r *MongoBlogRepository) CreateBlog(ctx context.Context, blog model.Blog) (*model.Blog, error) {
+	// the ID is generated by MongoDB
+	// and only the snapshot of the author is stored
+	var document primitive.D = bson.D{
+		{Key: "title", Value: blog.Title},
+		{Key: "content", Value: blog.Content},
+		{Key: "tags", Value: mongoTags(blog.Tags)},
+		{Key: "status", Value: blog.Status},
+		{Key: "publishAt", Value: blog.PublishAt},
+		{Key: "publishedAt", Value: blog.PublishedAt},
+		{Key: "author", Value: mongoAuthorSnapshot(blog.Author)},
+		{Key: "createdAt", Value: blog.CreatedAt},
+		{Key: "updatedAt", Value: blog.UpdatedAt},
+		{Key: "version", Value: 1},
+	}
+
+	result, err := r.collection.InsertOne(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+
+	var blogID primitive.ObjectID = result.InsertedID.(primitive.ObjectID)
+	blog.ID = blogID.Hex()
+
+	// the blog is removed again when its first revision cannot be stored
+	if _, err := r.revisions.InsertOne(ctx, mongoRevision(newRevision(nil, &blog, blog.Author, 1))); err != nil {
+		if _, deleteErr := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: blogID}}); deleteErr != nil {
+			return nil, fmt.Errorf("%w, and removing the blog failed: %v", err, deleteErr)
+		}
+		return nil, err
+	}
+
+	return r.GetBlogByID(ctx, blog.ID)
+}
+
+// UpdateBlog changes the title and the content of a blog together with its next revision
+// the blog is only replaced when no other edit took its version in the meantime,
+// so the revision is compared with the version the edit replaces
+func (r *MongoBlogRepository) UpdateBlog(ctx context.Context, id string, authorID *string, update BlogUpdate) (*model.Blog, error) {
+	blogID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	for attempt := 1; ; attempt++ {
+		var previous mongoVersionedBlog
+
+		if err := r.collection.FindOne(ctx, mongoOwnedBlog(blogID, authorID)).Decode(&previous); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+
+		var (
+			query     primitive.D = mongoOwnedBlog(blogID, authorID)
+			latest    int
+			revisions []interface{}
+		)
+
+		// the blogs edited before the versions were stored continue from their latest revision
+		if previous.Version != nil {
+			latest = *previous.Version
+			query = append(query, bson.E{Key: "version", Value: latest})
+		} else {
+			if latest, err = r.latestRevision(ctx, id); err != nil {
+				return nil, err
+			}
+			query = append(query, bson.E{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}})
+		}
+
+		// the blogs created before the revisions were recorded
+		// get their previous version as the first revision
+		if latest == 0 {
+			latest = 1
+			revisions = append(revisions, mongoRevision(newRevision(nil, &previous.Blog, previous.Author, latest)))
+		}
+
+		revisions = append(revisions, mongoRevision(newRevision(&previous.Blog, editedBlog(previous.Blog, update), update.Editor, latest+1)))
+
+		result, err := r.collection.UpdateOne(ctx, query, bson.D{{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "title", Value: update.Title},
+				{Key: "content", Value: update.Content},
+				{Key: "tags", Value: mongoTags(update.Tags)},
+				{Key: "updatedAt", Value: update.UpdatedAt},
+				{Key: "version", Value: latest + 1},
+			},
+		}})
+		if err != nil {
+			return nil, err
+		}
+
+		// another edit took the version, the blog is read again
+		if result.MatchedCount == 0 {
+			if attempt < revisionAttempts {
+				continue
+			}
+			return nil, errEditConflict
+		}
+
+		if _, err := r.revisions.InsertMany(ctx, revisions); err != nil {
+			return nil, r.revertBlog(ctx, blogID, &previous, latest+1, err)
+		}
+
+		return r.GetBlogByID(ctx, id)
+	}
+}
+
+// latestRevision returns the version of the latest revision of the blog, 0 when it has none
+func (r *MongoBlogRepository) latestRevision(ctx context.Context, blogID string) (int, error) {
+	latest := &model.BlogRevision{}
+
+	err := r.revisions.FindOne(
+		ctx,
+		bson.D{{Key: "blogId", Value: blogID}},
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}),
+	).Decode(latest)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+
+	return latest.Version, nil
+}
+
+// revertBlog brings back the version replaced by an edit whose revision could not be stored
+// the blog is left as it is when another edit followed, cause is returned in every case
+func (r *MongoBlogRepository) revertBlog(ctx context.Context, blogID primitive.ObjectID, previous *mongoVersionedBlog, version int, cause error) error {
+	var fields primitive.D = bson.D{
+		{Key: "title", Value: previous.Title},
+		{Key: "content", Value: previous.Content},
+		{Key: "tags", Value: mongoTags(previous.Tags)},
+		{Key: "updatedAt", Value: previous.UpdatedAt},
+	}
+
+	// the version is only stored once the blog is edited
+	var update primitive.D = bson.D{{Key: "$unset", Value: bson.D{{Key: "version", Value: ""}}}}
+	if previous.Version != nil {
+		fields = append(fields, bson.E{Key: "version", Value: *previous.Version})
+		update = nil
+	}
+	update = append(update, bson.E{Key: "$set", Value: fields})
+
+	_, err := r.collection.UpdateOne(ctx, bson.D{{Key: "_id", Value: blogID}, {Key: "version", Value: version}}, update)
+	if err != nil {
+		return fmt.Errorf("%w, and reverting the blog failed: %v", cause, err)
+	}
+
+	return cause
+}
+
+// TrashBlog moves a blog to the trash
+func (r *MongoBlogRepository) TrashBlog(ctx context.Context, id string, authorID *string, deletedAt time.Time) error {
+	blogID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		mongoOwnedBlog(blogID, authorID),
+		bson.D{{Key: "$set", Value: bson.D{{Key: "deletedAt", Value: deletedAt}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RestoreBlog brings a blog back from the trash
+func (r *MongoBlogRepository) RestoreBlog(ctx context.Context, id string, authorID *string) (*model.Blog, error) {
+	blogID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var query primitive.D = bson.D{
+		{Key: "_id", Value: blogID},
+		{Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}},
+	}
+
+	if authorID != nil {
+		query = append(query, bson.E{Key: "author._id", Value: *authorID})
+	}
+
+	updateResult := r.collection.FindOneAndUpdate(
+		ctx,
+		query,
+		bson.D{{Key: "$set", Value: bson.D{{Key: "deletedAt", Value: nil}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var blog *model.Blog = &model.Blog{}
+
+	if err := updateResult.Decode(blog); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return blog, nil
+}
+
+// ListTrashedBlogs returns the blogs of the author in the trash, the last deleted first
+func (r *MongoBlogRepository) ListTrashedBlogs(ctx context.Context, authorID string) ([]*model.Blog, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.D{
+			{Key: "author._id", Value: authorID},
+			{Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}},
+		},
+		options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}, {Key: "_id", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	blogs := make([]*model.Blog, 0)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return nil, err
+	}
+
+	return blogs, nil
+}
+
+// ListExpiredBlogs returns the IDs of the blogs moved to the trash before the given time
+func (r *MongoBlogRepository) ListExpiredBlogs(ctx context.Context, before time.Time) ([]string, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.D{{Key: "deletedAt", Value: bson.D{{Key: "$lt", Value: before}}}},
+		options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(documents))
+	for _, document := range documents {
+		ids = append(ids, document.ID.Hex())
+	}
+
+	return ids, nil
+}
+
+// PurgeTrashedBlog removes the blog when it was moved to the trash before the given time
+func (r *MongoBlogRepository) PurgeTrashedBlog(ctx context.Context, id string, before time.Time) error {
+	blogID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.D{
+		{Key: "_id", Value: blogID},
+		{Key: "deletedAt", Value: bson.D{{Key: "$lt", Value: before}}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListTags returns the tags of the published blogs with their usage, the most used first
+func (r *MongoBlogRepository) ListTags(ctx context.Context) ([]*model.TagCount, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "status", Value: model.BlogStatusPublished}, {Key: "deletedAt", Value: nil}}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$tags"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "tag", Value: "$_id"}, {Key: "count", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]*model.TagCount, 0)
+
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// UpdateBlogStatus changes the publication state of a blog
+func (r *MongoBlogRepository) UpdateBlogStatus(ctx context.Context, id string, authorID *string, update BlogStatusUpdate) (*model.Blog, error) {
+	blogID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var fields primitive.D = bson.D{
+		{Key: "status", Value: update.Status},
+		{Key: "publishAt", Value: update.PublishAt},
+	}
+
+	if update.PublishedAt != nil {
+		fields = append(fields, bson.E{Key: "publishedAt", Value: *update.PublishedAt})
+	}
+
+	updateResult := r.collection.FindOneAndUpdate(
+		ctx,
+		mongoOwnedBlog(blogID, authorID),
+		bson.D{{Key: "$set", Value: fields}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var blog *model.Blog = &model.Blog{}
+
+	if err := updateResult.Decode(blog); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return blog, nil
+}
+
+// PublishScheduledBlogs publishes the scheduled blogs whose publication time has come
+func (r *MongoBlogRepository) PublishScheduledBlogs(ctx context.Context, now time.Time) ([]ScheduledPublication, error) {
+	var published []ScheduledPublication
+
+	// the blogs are published one by one, so every published blog is returned once
+	// with the state it had before, which tells whether it was published before
+	for {
+		updateResult := r.collection.FindOneAndUpdate(
+			ctx,
+			bson.D{
+				{Key: "status", Value: model.BlogStatusScheduled},
+				{Key: "publishAt", Value: bson.D{{Key: "$lte", Value: now}}},
+				{Key: "deletedAt", Value: nil},
+			},
+			// the blogs are published at their publication time
+			mongo.Pipeline{{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: model.BlogStatusPublished},
+				{Key: "publishedAt", Value: "$publishAt"},
+				{Key: "publishAt", Value: nil},
+			}}}},
+			options.FindOneAndUpdate().SetReturnDocument(options.Before),
+		)
+
+		var blog *model.Blog = &model.Blog{}
+
+		if err := updateResult.Decode(blog); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return published, nil
+			}
+			return published, err
+		}
+
+		var first bool = blog.PublishedAt == nil
+		applyStatusUpdate(blog, BlogStatusUpdate{Status: model.BlogStatusPublished, PublishedAt: blog.PublishAt})
+		published = append(published, ScheduledPublication{Blog: blog, First: first})
+	}
+}
+
+// publishLegacyBlogs publishes the blogs stored before the statuses were added
+// they were visible to every user
+func (r *MongoBlogRepository) publishLegacyBlogs(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.D{{Key: "status", Value: bson.D{{Key: "$exists", Value: false}}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.D{
+			{Key: "status", Value: model.BlogStatusPublished},
+			{Key: "publishedAt", Value: "$createdAt"},
+		}}}},
+	)
+
+	return err
+}
+
+// removeAuthorCredentials reduces the authors stored by the blogs created
+// before the snapshots were used to their snapshot
+func (r *MongoBlogRepository) removeAuthorCredentials(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.D{{Key: "author.password", Value: bson.D{{Key: "$exists", Value: true}}}},
+		bson.D{{Key: "$unset", Value: bson.D{
+			{Key: "author.password", Value: ""},
+			{Key: "author.email", Value: ""},
+			{Key: "author.role", Value: ""},
+			{Key: "author.createdAt", Value: ""},
+			{Key: "author.updatedAt", Value: ""},
+		}}},
+	)
+
+	return err
+}
+
+// mongoAuthorSnapshot returns the author document stored with a blog
+func mongoAuthorSnapshot(author *model.User) interface{} {
+	if author == nil {
+		return nil
+	}
+
+	return bson.D{
+		{Key: "_id", Value: author.ID},
+		{Key: "username", Value: author.Username},
+	}
+}
+
+// mongoTags returns the tags stored with a blog, a blog without tags stores an empty array
+func mongoTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// mongoOwnedBlog returns the condition selecting a blog outside the trash
+// when authorID is set, the blog must also be owned by that author
+func mongoOwnedBlog(blogID primitive.ObjectID, authorID *string) primitive.D {
+	// a missing deletion time matches null
+	var query primitive.D = bson.D{{Key: "_id", Value: blogID}, {Key: "deletedAt", Value: nil}}
+
+	if authorID != nil {
+		query = append(query, bson.E{Key: "author._id", Value: *authorID})
+	}
+
+	return query
+}
+
+// mongoBlogFilter translates the filter into MongoDB conditions
+// the values are always compared as values, never read as operators
+// the blogs in the trash are left out
+func mongoBlogFilter(filter BlogFilter) bson.A {
+	var conditions bson.A = bson.A{bson.D{{Key: "deletedAt", Value: nil}}}
+
+	if filter.AuthorID != "" {
+		conditions = append(conditions, bson.D{{Key: "author._id", Value: filter.AuthorID}})
+	}
+
+	if len(filter.AuthorIDs) > 0 {
+		conditions = append(conditions, bson.D{{Key: "author._id", Value: bson.D{{Key: "$in", Value: filter.AuthorIDs}}}})
+	}
+
+	if filter.Tag != "" {
+		// the tags are matched with the multikey index
+		conditions = append(conditions, bson.D{{Key: "tags", Value: filter.Tag}})
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, bson.D{{Key: "status", Value: filter.Status}})
+	}
+
+	if filter.Visibility != nil {
+		var visible bson.A = bson.A{bson.D{{Key: "status", Value: model.BlogStatusPublished}}}
+		if filter.Visibility.AuthorID != "" {
+			visible = append(visible, bson.D{{Key: "author._id", Value: filter.Visibility.AuthorID}})
+		}
+		conditions = append(conditions, bson.D{{Key: "$or", Value: visible}})
+	}
+
+	if filter.TitleContains != "" {
+		var pattern primitive.Regex = primitive.Regex{Pattern: regexp.QuoteMeta(filter.TitleContains), Options: "i"}
+		conditions = append(conditions, bson.D{{Key: "title", Value: pattern}})
+	}
+
+	var ranges = []struct {
+		field    string
+		operator string
+		value    *time.Time
+	}{
+		{"createdAt", "$gt", filter.CreatedAfter},
+		{"createdAt", "$lt", filter.CreatedBefore},
+		{"updatedAt", "$gt", filter.UpdatedAfter},
+		{"updatedAt", "$lt", filter.UpdatedBefore},
+	}
+
+	for _, r := range ranges {
+		if r.value != nil {
+			conditions = append(conditions, bson.D{{Key: r.field, Value: bson.D{{Key: r.operator, Value: *r.value}}}})
+		}
+	}
+
+	if filter.Edited != nil {
+		// a blog that was never edited has no update time
+		var edited primitive.D = bson.D{{Key: "updatedAt", Value: nil}}
+		if *filter.Edited {
+			edited = bson.D{{Key: "updatedAt", Value: bson.D{{Key: "$ne", Value: nil}}}}
+		}
+		conditions = append(conditions, edited)
+	}
+
+	return conditions
+}
+
+// mongoCursorCondition returns a filter for the blogs placed after the cursor
+// in the given direction, 1 for greater values and -1 for lower values
+// a missing update time is lower than any time
+func mongoCursorCondition(cursor BlogCursor, field string, direction int) (primitive.D, error) {
+	blogID, err := primitive.ObjectIDFromHex(cursor.ID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var operator string = "$lt"
+	if direction > 0 {
+		operator = "$gt"
+	}
+
+	var value interface{}
+	switch BlogSortField(field) {
+	case SortByTitle:
+		value = cursor.Title
+	case SortByUpdatedAt:
+		if cursor.UpdatedAt != nil {
+			value = *cursor.UpdatedAt
+		}
+	default:
+		value = cursor.CreatedAt
+	}
+
+	var (
+		sameValue primitive.D = bson.D{
+			{Key: field, Value: value},
+			{Key: "_id", Value: bson.D{{Key: operator, Value: blogID}}},
+		}
+		alternatives bson.A = bson.A{sameValue}
+	)
+
+	switch {
+	case value == nil && direction > 0:
+		// every blog with a value is greater than a missing value
+		alternatives = append(alternatives, bson.D{{Key: field, Value: bson.D{{Key: "$ne", Value: nil}}}})
+	case value != nil && direction > 0:
+		alternatives = append(alternatives, bson.D{{Key: field, Value: bson.D{{Key: operator, Value: value}}}})
+	case value != nil:
+		// a missing value is lower than any value
+		alternatives = append(alternatives, bson.D{{Key: field, Value: bson.D{{Key: operator, Value: value}}}})
+		if BlogSortField(field) == SortByUpdatedAt {
+			alternatives = append(alternatives, bson.D{{Key: field, Value: nil}})
+		}
+	}
+
+	return bson.D{{Key: "$or", Value: alternatives}}, nil
+}
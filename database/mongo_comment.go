@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoCommentRepository stores comments in the "comments" collection
+type MongoCommentRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoCommentRepository returns a comment repository for the given database
+func NewMongoCommentRepository(db *mongo.Database) *MongoCommentRepository {
+	return &MongoCommentRepository{collection: db.Collection(utils.COMMENT_COLLECTION)}
+}
+
+// createIndexes creates the indexes of the "comments" collection
+func (r *MongoCommentRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// comments are listed per parent, oldest first
+		{Keys: bson.D{{Key: "blogId", Value: 1}, {Key: "parentId", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+		// replies are counted and removed with their parent
+		{Keys: bson.D{{Key: "parentId", Value: 1}}},
+	})
+
+	return err
+}
+
+// ListComments returns the comments matching the query, oldest first
+func (r *MongoCommentRepository) ListComments(ctx context.Context, query CommentQuery) ([]*model.Comment, error) {
+	var (
+		filter      primitive.D          = bson.D{{Key: "blogId", Value: query.BlogID}, {Key: "parentId", Value: query.ParentID}}
+		findOptions *options.FindOptions = options.Find()
+	)
+
+	if query.After != nil {
+		commentID, err := primitive.ObjectIDFromHex(query.After.ID)
+		if err != nil {
+			return nil, ErrInvalidID
+		}
+
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "createdAt", Value: bson.D{{Key: "$gt", Value: query.After.CreatedAt}}}},
+			bson.D{{Key: "createdAt", Value: query.After.CreatedAt}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: commentID}}}},
+		}})
+	}
+
+	findOptions.SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]*model.Comment, 0)
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetCommentByID returns the comment with the given ID
+func (r *MongoCommentRepository) GetCommentByID(ctx context.Context, id string) (*model.Comment, error) {
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	comment := &model.Comment{}
+
+	if err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: commentID}}).Decode(comment); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// CountComments returns the number of comments of the blog, including the replies
+func (r *MongoCommentRepository) CountComments(ctx context.Context, blogID string) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.D{{Key: "blogId", Value: blogID}})
+	return int(count), err
+}
+
+// CountReplies returns the number of direct replies of the comment
+func (r *MongoCommentRepository) CountReplies(ctx context.Context, commentID string) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.D{{Key: "parentId", Value: commentID}})
+	return int(count), err
+}
+
+// CreateComment stores a new comment and returns the stored record
+func (r *MongoCommentRepository) CreateComment(ctx context.Context, comment model.Comment) (*model.Comment, error) {
+	// the ID is generated by MongoDB
+	// and only the snapshot of the author is stored
+	var document primitive.D = bson.D{
+		{Key: "blogId", Value: comment.BlogID},
+		{Key: "parentId", Value: comment.ParentID},
+		{Key: "depth", Value: comment.Depth},
+		{Key: "content", Value: comment.Content},
+		{Key: "author", Value: mongoAuthorSnapshot(comment.Author)},
+		{Key: "createdAt", Value: comment.CreatedAt},
+		{Key: "updatedAt", Value: comment.UpdatedAt},
+	}
+
+	result, err := r.collection.InsertOne(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetCommentByID(ctx, result.InsertedID.(primitive.ObjectID).Hex())
+}
+
+// UpdateComment changes the content of a comment
+func (r *MongoCommentRepository) UpdateComment(ctx context.Context, id string, content string, updatedAt time.Time) (*model.Comment, error) {
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	updateResult := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "_id", Value: commentID}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "content", Value: content},
+			{Key: "updatedAt", Value: updatedAt},
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var comment *model.Comment = &model.Comment{}
+
+	if err := updateResult.Decode(comment); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// DeleteComment removes a comment and all of its replies
+func (r *MongoCommentRepository) DeleteComment(ctx context.Context, id string) error {
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: commentID}})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount < 1 {
+		return ErrNotFound
+	}
+
+	// remove the replies level by level
+	var parents []string = []string{id}
+	for len(parents) > 0 {
+		var filter primitive.D = bson.D{{Key: "parentId", Value: bson.D{{Key: "$in", Value: parents}}}}
+
+		ids, err := r.collection.Distinct(ctx, "_id", filter)
+		if err != nil {
+			return err
+		}
+
+		if _, err := r.collection.DeleteMany(ctx, filter); err != nil {
+			return err
+		}
+
+		parents = parents[:0]
+		for _, replyID := range ids {
+			if objectID, ok := replyID.(primitive.ObjectID); ok {
+				parents = append(parents, objectID.Hex())
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteBlogComments removes all comments of the blog
+func (r *MongoCommentRepository) DeleteBlogComments(ctx context.Context, blogID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.D{{Key: "blogId", Value: blogID}})
+	return err
+}
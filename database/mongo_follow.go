@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoFollowRepository stores the follows between users in the "follows" collection
+type MongoFollowRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoFollowRepository returns a follow repository for the given database
+func NewMongoFollowRepository(db *mongo.Database) *MongoFollowRepository {
+	return &MongoFollowRepository{collection: db.Collection(utils.FOLLOW_COLLECTION)}
+}
+
+// createIndexes creates the indexes of the "follows" collection
+func (r *MongoFollowRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// a user follows another user at most once
+			Keys:    bson.D{{Key: "followerId", Value: 1}, {Key: "followeeId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "followerId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "followeeId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	})
+
+	return err
+}
+
+// Follow stores the follow, it reports false when the follower already follows the user
+func (r *MongoFollowRepository) Follow(ctx context.Context, follow Follow) (bool, error) {
+	// the unique index rejects a second follow of the same user
+	_, err := r.collection.InsertOne(ctx, follow)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Unfollow removes the follow, it reports false when there was none
+func (r *MongoFollowRepository) Unfollow(ctx context.Context, followerID string, followeeID string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.D{
+		{Key: "followerId", Value: followerID},
+		{Key: "followeeId", Value: followeeID},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// ListFollows returns the follows matching the query, the last started first
+func (r *MongoFollowRepository) ListFollows(ctx context.Context, query FollowQuery) ([]Follow, error) {
+	var (
+		filter bson.D = bson.D{}
+		// the listed user breaks the ties between the follows started at the same time
+		listed string = "followerId"
+	)
+
+	if query.FollowerID != "" {
+		listed = "followeeId"
+		filter = append(filter, bson.E{Key: "followerId", Value: query.FollowerID})
+	}
+
+	if query.FolloweeID != "" {
+		filter = append(filter, bson.E{Key: "followeeId", Value: query.FolloweeID})
+	}
+
+	if query.After != nil {
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "createdAt", Value: bson.D{{Key: "$lt", Value: query.After.CreatedAt}}}},
+			bson.D{
+				{Key: "createdAt", Value: query.After.CreatedAt},
+				{Key: listed, Value: bson.D{{Key: "$lt", Value: query.After.UserID}}},
+			},
+		}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: listed, Value: -1}})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := make([]Follow, 0)
+
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	return follows, nil
+}
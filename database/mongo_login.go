@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoLoginAttemptRepository stores the failed logins in the "login_failures" collection
+// the documents are identified by their key
+type MongoLoginAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoLoginAttemptRepository returns a failed login repository for the given database
+func NewMongoLoginAttemptRepository(db *mongo.Database) *MongoLoginAttemptRepository {
+	return &MongoLoginAttemptRepository{collection: db.Collection(utils.LOGIN_FAILURE_COLLECTION)}
+}
+
+// GetLoginFailures returns the failures of the key, it fails with ErrNotFound when there are none
+func (r *MongoLoginAttemptRepository) GetLoginFailures(ctx context.Context, key string) (*LoginFailures, error) {
+	var failures *LoginFailures = &LoginFailures{}
+
+	if err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: key}}).Decode(failures); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return failures, nil
+}
+
+// RecordLoginFailure counts a failed login of the key and returns the updated record
+func (r *MongoLoginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, at time.Time, since time.Time) (*LoginFailures, error) {
+	// the count is changed by the database, so the concurrent failures are all counted
+	// a missing date is lower than any date, so a new document starts at one
+	var update mongo.Pipeline = mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "count", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$lt", Value: bson.A{"$lastFailedAt", since}}},
+				1,
+				bson.D{{Key: "$add", Value: bson.A{"$count", 1}}},
+			}}}},
+			{Key: "lastFailedAt", Value: at},
+		}}},
+	}
+
+	var failures *LoginFailures = &LoginFailures{}
+
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "_id", Value: key}},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(failures)
+	if err != nil {
+		return nil, err
+	}
+
+	return failures, nil
+}
+
+// LockLogin refuses the logins of the key until the given time
+func (r *MongoLoginAttemptRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.D{{Key: "_id", Value: key}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "lockedUntil", Value: until}}}},
+		options.Update().SetUpsert(true),
+	)
+
+	return err
+}
+
+// ResetLoginFailures forgets the failures and the lock of the key
+func (r *MongoLoginAttemptRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: key}})
+	return err
+}
+
+// MongoAuditRepository stores the security events in the "audit_records" collection
+type MongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAuditRepository returns an audit record repository for the given database
+func NewMongoAuditRepository(db *mongo.Database) *MongoAuditRepository {
+	return &MongoAuditRepository{collection: db.Collection(utils.AUDIT_COLLECTION)}
+}
+
+// createIndexes creates the indexes of the "audit_records" collection
+func (r *MongoAuditRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+
+	return err
+}
+
+// CreateAuditRecord stores a new record and returns its ID
+func (r *MongoAuditRepository) CreateAuditRecord(ctx context.Context, record AuditRecord) (string, error) {
+	record.ID = newObjectID()
+
+	if _, err := r.collection.InsertOne(ctx, record); err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+// ListAuditRecords returns the latest records, newest first
+func (r *MongoAuditRepository) ListAuditRecords(ctx context.Context, userID string, limit int) ([]AuditRecord, error) {
+	var filter bson.D = bson.D{}
+	if userID != "" {
+		filter = append(filter, bson.E{Key: "userId", Value: userID})
+	}
+
+	// the IDs grow with time, so they order the records created at the same time
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]AuditRecord, 0)
+
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoReactionRepository stores the reactions to blogs in the "blog_reactions" collection
+type MongoReactionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoReactionRepository returns a reaction repository for the given database
+func NewMongoReactionRepository(db *mongo.Database) *MongoReactionRepository {
+	return &MongoReactionRepository{collection: db.Collection(utils.REACTION_COLLECTION)}
+}
+
+// createIndexes creates the indexes of the "blog_reactions" collection
+func (r *MongoReactionRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// a user reacts at most once with each kind, the reactions are counted by blog
+		Keys:    bson.D{{Key: "blogId", Value: 1}, {Key: "userId", Value: 1}, {Key: "kind", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return err
+}
+
+// AddReaction stores the reaction, it reports false when the user already reacted with the kind
+func (r *MongoReactionRepository) AddReaction(ctx context.Context, reaction Reaction) (bool, error) {
+	// the unique index rejects a second reaction of the same kind
+	_, err := r.collection.InsertOne(ctx, reaction)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RemoveReaction removes the reaction of the user with the kind, it reports false when there was none
+func (r *MongoReactionRepository) RemoveReaction(ctx context.Context, blogID string, userID string, kind model.ReactionKind) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.D{
+		{Key: "blogId", Value: blogID},
+		{Key: "userId", Value: userID},
+		{Key: "kind", Value: kind},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// CountReactions returns the number of reactions of each kind to the blog
+func (r *MongoReactionRepository) CountReactions(ctx context.Context, blogID string) (map[model.ReactionKind]int, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "blogId", Value: blogID}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$kind"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []struct {
+		Kind  model.ReactionKind `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	var counts map[model.ReactionKind]int = make(map[model.ReactionKind]int, len(documents))
+	for _, document := range documents {
+		counts[document.Kind] = document.Count
+	}
+
+	return counts, nil
+}
+
+// ListUserReactions returns the kinds the user reacted with to the blog
+func (r *MongoReactionRepository) ListUserReactions(ctx context.Context, blogID string, userID string) ([]model.ReactionKind, error) {
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "blogId", Value: blogID}, {Key: "userId", Value: userID}})
+	if err != nil {
+		return nil, err
+	}
+
+	var reactions []Reaction
+
+	if err := cursor.All(ctx, &reactions); err != nil {
+		return nil, err
+	}
+
+	kinds := make([]model.ReactionKind, 0, len(reactions))
+	for _, reaction := range reactions {
+		kinds = append(kinds, reaction.Kind)
+	}
+
+	return kinds, nil
+}
+
+// DeleteBlogReactions removes the reactions to the blog
+func (r *MongoReactionRepository) DeleteBlogReactions(ctx context.Context, blogID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.D{{Key: "blogId", Value: blogID}})
+	return err
+}
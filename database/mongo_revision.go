@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRevisionRepository stores blog revisions in the "blog_revisions" collection
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRevisionRepository returns a revision repository for the given database
+func NewMongoRevisionRepository(db *mongo.Database) *MongoRevisionRepository {
+	return &MongoRevisionRepository{collection: db.Collection(utils.REVISION_COLLECTION)}
+}
+
+// createIndexes creates the indexes of the "blog_revisions" collection
+func (r *MongoRevisionRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// a version is assigned to a single revision of the blog
+		Keys:    bson.D{{Key: "blogId", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return err
+}
+
+// ListRevisions returns the revisions of the blog, newest first
+func (r *MongoRevisionRepository) ListRevisions(ctx context.Context, blogID string) ([]*model.BlogRevision, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.D{{Key: "blogId", Value: blogID}},
+		options.Find().SetSort(bson.D{{Key: "version", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*model.BlogRevision, 0)
+
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns the revision of the blog with the given version
+func (r *MongoRevisionRepository) GetRevision(ctx context.Context, blogID string, version int) (*model.BlogRevision, error) {
+	if _, err := primitive.ObjectIDFromHex(blogID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	revision := &model.BlogRevision{}
+
+	err := r.collection.FindOne(ctx, bson.D{{Key: "blogId", Value: blogID}, {Key: "version", Value: version}}).Decode(revision)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// mongoRevision returns the document of the revision, only the snapshot of the editor is stored
+func mongoRevision(revision model.BlogRevision) primitive.D {
+	return bson.D{
+		{Key: "blogId", Value: revision.BlogID},
+		{Key: "version", Value: revision.Version},
+		{Key: "title", Value: revision.Title},
+		{Key: "content", Value: revision.Content},
+		{Key: "editor", Value: mongoAuthorSnapshot(revision.Editor)},
+		{Key: "createdAt", Value: revision.CreatedAt},
+		{Key: "titleChanged", Value: revision.TitleChanged},
+		{Key: "linesAdded", Value: revision.LinesAdded},
+		{Key: "linesRemoved", Value: revision.LinesRemoved},
+	}
+}
+
+// DeleteBlogRevisions removes the revisions of the blog
+func (r *MongoRevisionRepository) DeleteBlogRevisions(ctx context.Context, blogID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.D{{Key: "blogId", Value: blogID}})
+	return err
+}
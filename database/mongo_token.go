@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoTokenRepository stores refresh tokens in the "refresh_tokens" collection
+// and revoked access tokens in the "revoked_tokens" collection
+type MongoTokenRepository struct {
+	collection *mongo.Collection
+	revoked    *mongo.Collection
+}
+
+// NewMongoTokenRepository returns a token repository for the given database
+func NewMongoTokenRepository(db *mongo.Database) *MongoTokenRepository {
+	return &MongoTokenRepository{
+		collection: db.Collection(utils.REFRESH_TOKEN_COLLECTION),
+		revoked:    db.Collection(utils.REVOKED_TOKEN_COLLECTION),
+	}
+}
+
+// createIndexes creates the indexes of the "refresh_tokens" collection
+func (r *MongoTokenRepository) createIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// tokens are found by their hash
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// families are revoked together
+		{Keys: bson.D{{Key: "familyId", Value: 1}}},
+		// MongoDB removes the tokens once they expire
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		// the sessions of a user are revoked together
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// MongoDB removes the revocations once the access tokens expire
+	_, err = r.revoked.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return err
+}
+
+// CreateRefreshToken stores a new refresh token and returns its ID
+func (r *MongoTokenRepository) CreateRefreshToken(ctx context.Context, token RefreshToken) (string, error) {
+	token.ID = newObjectID()
+	if token.FamilyID == "" {
+		token.FamilyID = token.ID
+	}
+
+	if _, err := r.collection.InsertOne(ctx, token); err != nil {
+		return "", err
+	}
+
+	return token.ID, nil
+}
+
+// GetRefreshTokenByHash returns the refresh token with the given hash
+func (r *MongoTokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	var token *RefreshToken = &RefreshToken{}
+
+	if err := r.collection.FindOne(ctx, bson.D{{Key: "tokenHash", Value: hash}}).Decode(token); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes a token replaced by another token of its family
+func (r *MongoTokenRepository) RotateRefreshToken(ctx context.Context, id string, replacedBy string, at time.Time) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		// only a token that is still valid can be rotated
+		bson.D{{Key: "_id", Value: id}, {Key: "revokedAt", Value: nil}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "revokedAt", Value: at},
+			{Key: "replacedBy", Value: replacedBy},
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.ModifiedCount < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RevokeTokenFamily revokes every token of the family
+func (r *MongoTokenRepository) RevokeTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.D{{Key: "familyId", Value: familyID}, {Key: "revokedAt", Value: nil}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revokedAt", Value: at}}}},
+	)
+
+	return err
+}
+
+// RevokeAccessToken records the ID of an access token that must no longer be accepted
+func (r *MongoTokenRepository) RevokeAccessToken(ctx context.Context, token RevokedToken) error {
+	_, err := r.revoked.ReplaceOne(ctx, bson.D{{Key: "_id", Value: token.ID}}, token, options.Replace().SetUpsert(true))
+	return err
+}
+
+// RevokeUserSessions revokes every refresh token of the user
+// and every access token issued to the user until the given time
+func (r *MongoTokenRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time, expiresAt time.Time) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.D{{Key: "userId", Value: userID}, {Key: "revokedAt", Value: nil}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revokedAt", Value: at}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return r.RevokeAccessToken(ctx, RevokedToken{ID: userRevocationID(userID), UserID: userID, RevokedAt: at, ExpiresAt: expiresAt})
+}
+
+// IsAccessTokenRevoked reports whether the access token has been revoked
+func (r *MongoTokenRepository) IsAccessTokenRevoked(ctx context.Context, id string, userID string, issuedAt time.Time) (bool, error) {
+	cursor, err := r.revoked.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{id, userRevocationID(userID)}}}}})
+	if err != nil {
+		return false, err
+	}
+
+	var revocations []RevokedToken
+	if err := cursor.All(ctx, &revocations); err != nil {
+		return false, err
+	}
+
+	for _, revoked := range revocations {
+		if revoked.revokes(id, userID, issuedAt) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// PruneTokens removes the refresh tokens and revocations that expired before the given time
+// MongoDB also removes them in the background with the TTL indexes
+func (r *MongoTokenRepository) PruneTokens(ctx context.Context, before time.Time) error {
+	var filter bson.D = bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$lt", Value: before}}}}
+
+	if _, err := r.collection.DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+
+	_, err := r.revoked.DeleteMany(ctx, filter)
+	return err
+}
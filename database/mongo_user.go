@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoUserRepository stores users in the "users" collection
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository returns a user repository for the given database
+func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
+	return &MongoUserRepository{collection: db.Collection(utils.USER_COLLECTION)}
+}
+
+// emailCollation compares the emails without their case
+var emailCollation *options.Collation = &options.Collation{Locale: "en", Strength: 2}
+
+// createIndexes creates the indexes of the users collection
+// the duplicated emails stored before must be merged first
+func (r *MongoUserRepository) createIndexes(ctx context.Context) error {
+	if err := r.checkDuplicateEmails(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// an email is registered once whatever its case, the users log in with it
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true).SetCollation(emailCollation),
+	})
+
+	return err
+}
+
+// checkDuplicateEmails refuses to index the users while several of them share an email
+// the emails are compared without their case and their surrounding spaces
+func (r *MongoUserRepository) checkDuplicateEmails(ctx context.Context) error {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$toLower", Value: bson.D{{Key: "$trim", Value: bson.D{{Key: "input", Value: "$email"}}}}}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var groups []struct {
+		Email string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	var duplicates []string
+	for i, group := range groups {
+		if i == maxReportedDuplicates {
+			break
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s (%d users)", group.Email, group.Count))
+	}
+
+	return fmt.Errorf("%d emails are registered by several users, merge their accounts before upgrading: %s", len(groups), strings.Join(duplicates, ", "))
+}
+
+// normalizeEmails stores the emails registered before they were normalized in lower case
+func (r *MongoUserRepository) normalizeEmails(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.D{},
+		mongo.Pipeline{{{Key: "$set", Value: bson.D{
+			{Key: "email", Value: bson.D{{Key: "$toLower", Value: bson.D{{Key: "$trim", Value: bson.D{{Key: "input", Value: "$email"}}}}}}},
+		}}}},
+	)
+
+	return err
+}
+
+// CreateUser stores a new user and returns its ID
+func (r *MongoUserRepository) CreateUser(ctx context.Context, user User) (string, error) {
+	// the ID is generated by MongoDB
+	user.ID = ""
+
+	// add a new user to the "users" collection
+	// the unique index rejects a second user with the same email
+	res, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", ErrDuplicate
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// convert ObjectID into the string
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetUserByID returns the user with the given ID
+func (r *MongoUserRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	// create an ObjectID from id
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	return r.findOne(ctx, bson.D{{Key: "_id", Value: userID}})
+}
+
+// GetUsersByIDs returns the users with the given IDs in any order
+// the IDs without a user are left out
+func (r *MongoUserRepository) GetUsersByIDs(ctx context.Context, ids []string) ([]*User, error) {
+	var userIDs []primitive.ObjectID = make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		// an invalid ID has no user
+		if userID, err := primitive.ObjectIDFromHex(id); err == nil {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	users := make([]*User, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: userIDs}}}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetUserByEmail returns the user with the given email
+func (r *MongoUserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	// the collation of the unique index compares the emails without their case
+	var user *User = &User{}
+
+	if err := r.collection.FindOne(ctx, bson.D{{Key: "email", Value: email}}, options.FindOne().SetCollation(emailCollation)).Decode(user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateUserRole changes the role of a user and returns the stored record
+func (r *MongoUserRepository) UpdateUserRole(ctx context.Context, id string, role model.Role, updatedAt time.Time) (*User, error) {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "_id", Value: userID}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "role", Value: role},
+			{Key: "updatedAt", Value: updatedAt},
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var user *User = &User{}
+	if err := result.Decode(user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateUserProfile replaces the profile of a user and returns the stored record
+func (r *MongoUserRepository) UpdateUserProfile(ctx context.Context, id string, profile UserProfile, updatedAt time.Time) (*User, error) {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.D{{Key: "_id", Value: userID}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "displayName", Value: profile.DisplayName},
+			{Key: "bio", Value: profile.Bio},
+			{Key: "avatarUrl", Value: profile.AvatarURL},
+			{Key: "updatedAt", Value: updatedAt},
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var user *User = &User{}
+	if err := result.Decode(user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findOne returns the first user matching the filter
+func (r *MongoUserRepository) findOne(ctx context.Context, filter primitive.D) (*User, error) {
+	var user *User = &User{}
+
+	if err := r.collection.FindOne(ctx, filter).Decode(user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,436 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/diff"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrInvalidID is returned when the given ID cannot be used by the backend
+	ErrInvalidID = errors.New("id is invalid")
+	// ErrDuplicate is returned when a unique field of the record is already stored
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// UserRepository represents the persistence of users
+type UserRepository interface {
+	// CreateUser stores a new user and returns its ID
+	// it fails with ErrDuplicate when the email is already registered
+	CreateUser(ctx context.Context, user User) (string, error)
+	// GetUserByID returns the user with the given ID
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	// GetUsersByIDs returns the users with the given IDs in any order
+	// the IDs without a user are left out
+	GetUsersByIDs(ctx context.Context, ids []string) ([]*User, error)
+	// GetUserByEmail returns the user with the given email
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	// UpdateUserRole changes the role of a user and returns the stored record
+	UpdateUserRole(ctx context.Context, id string, role model.Role, updatedAt time.Time) (*User, error)
+	// UpdateUserProfile replaces the profile of a user and returns the stored record
+	UpdateUserProfile(ctx context.Context, id string, profile UserProfile, updatedAt time.Time) (*User, error)
+}
+
+// User represents a stored user
+// it holds the password hash, so it is never returned to the clients
+type User struct {
+	ID           string     `bson:"_id,omitempty"`
+	Username     string     `bson:"username"`
+	Email        string     `bson:"email"`
+	PasswordHash string     `bson:"password"`
+	Role         model.Role `bson:"role"`
+	CreatedAt    time.Time  `bson:"createdAt"`
+	UpdatedAt    *time.Time `bson:"updatedAt"`
+	DisplayName  string     `bson:"displayName"`
+	Bio          string     `bson:"bio"`
+	AvatarURL    string     `bson:"avatarUrl"`
+}
+
+// UserProfile represents the public details a user chooses for themselves
+// an empty field is not set
+type UserProfile struct {
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+}
+
+// Model returns the user without its credentials
+func (u *User) Model() *model.User {
+	return &model.User{
+		ID:          u.ID,
+		Username:    u.Username,
+		Email:       &u.Email,
+		Role:        u.Role,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		DisplayName: optionalString(u.DisplayName),
+		Bio:         optionalString(u.Bio),
+		AvatarURL:   optionalString(u.AvatarURL),
+	}
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// AuthorSnapshot returns the part of the user stored with the blogs
+// the other fields are read from the users when they are needed
+func AuthorSnapshot(user *model.User) *model.User {
+	if user == nil {
+		return nil
+	}
+	return &model.User{ID: user.ID, Username: user.Username}
+}
+
+// BlogRepository represents the persistence of blogs
+// the blogs in the trash are only seen by the trash methods
+type BlogRepository interface {
+	// ListBlogs returns the blogs matching the query
+	ListBlogs(ctx context.Context, query BlogQuery) ([]*model.Blog, error)
+	// SearchBlogs returns the blogs whose title or content match the text, the most relevant first
+	SearchBlogs(ctx context.Context, text string, offset int, limit int) ([]BlogSearchHit, error)
+	// GetBlogByID returns the blog with the given ID
+	GetBlogByID(ctx context.Context, id string) (*model.Blog, error)
+	// CreateBlog stores a new blog together with its first revision and returns the stored record
+	// only the snapshot of the author is stored
+	CreateBlog(ctx context.Context, blog model.Blog) (*model.Blog, error)
+	// UpdateBlog changes the title and the content of a blog together with its next revision
+	// the revision is compared with the version it replaces
+	// when authorID is set, the blog must also be owned by that author
+	UpdateBlog(ctx context.Context, id string, authorID *string, update BlogUpdate) (*model.Blog, error)
+	// TrashBlog moves a blog to the trash
+	// when authorID is set, the blog must also be owned by that author
+	TrashBlog(ctx context.Context, id string, authorID *string, deletedAt time.Time) error
+	// RestoreBlog brings a blog back from the trash
+	// when authorID is set, the blog must also be owned by that author
+	RestoreBlog(ctx context.Context, id string, authorID *string) (*model.Blog, error)
+	// ListTrashedBlogs returns the blogs of the author in the trash, the last deleted first
+	ListTrashedBlogs(ctx context.Context, authorID string) ([]*model.Blog, error)
+	// ListExpiredBlogs returns the IDs of the blogs moved to the trash before the given time
+	ListExpiredBlogs(ctx context.Context, before time.Time) ([]string, error)
+	// PurgeTrashedBlog removes the blog when it was moved to the trash before the given time
+	// ErrNotFound is returned when the blog was restored in the meantime
+	PurgeTrashedBlog(ctx context.Context, id string, before time.Time) error
+	// ListTags returns the tags of the published blogs with their usage, the most used first
+	ListTags(ctx context.Context) ([]*model.TagCount, error)
+	// UpdateBlogStatus changes the publication state of a blog
+	// when authorID is set, the blog must also be owned by that author
+	UpdateBlogStatus(ctx context.Context, id string, authorID *string, update BlogStatusUpdate) (*model.Blog, error)
+	// PublishScheduledBlogs publishes the scheduled blogs whose publication time has come
+	// and returns the published blogs
+	PublishScheduledBlogs(ctx context.Context, now time.Time) ([]ScheduledPublication, error)
+}
+
+// CommentRepository represents the persistence of comments
+type CommentRepository interface {
+	// ListComments returns the comments matching the query, oldest first
+	ListComments(ctx context.Context, query CommentQuery) ([]*model.Comment, error)
+	// GetCommentByID returns the comment with the given ID
+	GetCommentByID(ctx context.Context, id string) (*model.Comment, error)
+	// CountComments returns the number of comments of the blog, including the replies
+	CountComments(ctx context.Context, blogID string) (int, error)
+	// CountReplies returns the number of direct replies of the comment
+	CountReplies(ctx context.Context, commentID string) (int, error)
+	// CreateComment stores a new comment and returns the stored record
+	// only the snapshot of the author is stored
+	CreateComment(ctx context.Context, comment model.Comment) (*model.Comment, error)
+	// UpdateComment changes the content of a comment
+	UpdateComment(ctx context.Context, id string, content string, updatedAt time.Time) (*model.Comment, error)
+	// DeleteComment removes a comment and all of its replies
+	DeleteComment(ctx context.Context, id string) error
+	// DeleteBlogComments removes all comments of the blog
+	DeleteBlogComments(ctx context.Context, blogID string) error
+}
+
+// revisionAttempts is how many times a blog is edited
+// when another edit of the blog takes its version first
+const revisionAttempts = 3
+
+// newRevision returns the revision recording the version of the blog with the given number
+// previous is the version replaced by the edit, nil for the first version of the blog
+func newRevision(previous *model.Blog, blog *model.Blog, editor *model.User, version int) model.BlogRevision {
+	var revision model.BlogRevision = model.BlogRevision{
+		BlogID:    blog.ID,
+		Version:   version,
+		Title:     blog.Title,
+		Content:   blog.Content,
+		Editor:    AuthorSnapshot(editor),
+		CreatedAt: blog.CreatedAt,
+	}
+	if blog.UpdatedAt != nil {
+		revision.CreatedAt = *blog.UpdatedAt
+	}
+
+	var oldContent string
+	if previous != nil {
+		oldContent = previous.Content
+		revision.TitleChanged = previous.Title != blog.Title
+	}
+
+	revision.LinesAdded, revision.LinesRemoved = diff.Count(diff.Lines(oldContent, blog.Content))
+
+	return revision
+}
+
+// editedBlog returns the blog with the changes of the update applied
+func editedBlog(blog model.Blog, update BlogUpdate) *model.Blog {
+	var updatedAt time.Time = update.UpdatedAt
+
+	blog.Title = update.Title
+	blog.Content = update.Content
+	blog.Tags = append([]string{}, update.Tags...)
+	blog.UpdatedAt = &updatedAt
+
+	return &blog
+}
+
+// RevisionRepository represents the persistence of the blog revisions
+// a revision is never changed once stored, the blog repository stores it together with the edit
+type RevisionRepository interface {
+	// ListRevisions returns the revisions of the blog, newest first
+	ListRevisions(ctx context.Context, blogID string) ([]*model.BlogRevision, error)
+	// GetRevision returns the revision of the blog with the given version
+	GetRevision(ctx context.Context, blogID string, version int) (*model.BlogRevision, error)
+	// DeleteBlogRevisions removes the revisions of the blog
+	DeleteBlogRevisions(ctx context.Context, blogID string) error
+}
+
+// ReactionRepository represents the persistence of the reactions to blogs
+// a user reacts at most once with each kind, and the counts are computed
+// from the stored reactions so they stay correct when users react at the same time
+type ReactionRepository interface {
+	// AddReaction stores the reaction, it reports false when the user already reacted with the kind
+	AddReaction(ctx context.Context, reaction Reaction) (bool, error)
+	// RemoveReaction removes the reaction of the user with the kind, it reports false when there was none
+	RemoveReaction(ctx context.Context, blogID string, userID string, kind model.ReactionKind) (bool, error)
+	// CountReactions returns the number of reactions of each kind to the blog
+	CountReactions(ctx context.Context, blogID string) (map[model.ReactionKind]int, error)
+	// ListUserReactions returns the kinds the user reacted with to the blog
+	ListUserReactions(ctx context.Context, blogID string, userID string) ([]model.ReactionKind, error)
+	// DeleteBlogReactions removes the reactions to the blog
+	DeleteBlogReactions(ctx context.Context, blogID string) error
+}
+
+// FollowRepository represents the persistence of the follows between users
+// a user follows another user at most once
+type FollowRepository interface {
+	// Follow stores the follow, it reports false when the follower already follows the user
+	Follow(ctx context.Context, follow Follow) (bool, error)
+	// Unfollow removes the follow, it reports false when there was none
+	Unfollow(ctx context.Context, followerID string, followeeID string) (bool, error)
+	// ListFollows returns the follows matching the query, the last started first
+	ListFollows(ctx context.Context, query FollowQuery) ([]Follow, error)
+}
+
+// LoginAttemptRepository represents the persistence of the failed logins
+// the failures are counted by key, for an email or an IP address,
+// so the emails that are not registered are counted like the others
+type LoginAttemptRepository interface {
+	// GetLoginFailures returns the failures of the key, it fails with ErrNotFound when there are none
+	GetLoginFailures(ctx context.Context, key string) (*LoginFailures, error)
+	// RecordLoginFailure counts a failed login of the key and returns the updated record
+	// the count starts again when the previous failure happened before since
+	RecordLoginFailure(ctx context.Context, key string, at time.Time, since time.Time) (*LoginFailures, error)
+	// LockLogin refuses the logins of the key until the given time
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	// ResetLoginFailures forgets the failures and the lock of the key
+	ResetLoginFailures(ctx context.Context, key string) error
+}
+
+// AuditRepository represents the persistence of the security events
+// a record is never changed once stored
+type AuditRepository interface {
+	// CreateAuditRecord stores a new record and returns its ID
+	CreateAuditRecord(ctx context.Context, record AuditRecord) (string, error)
+	// ListAuditRecords returns the latest records, newest first
+	// only the records of the user are returned when userID is set
+	ListAuditRecords(ctx context.Context, userID string, limit int) ([]AuditRecord, error)
+}
+
+// TokenRepository represents the persistence of refresh tokens and revoked access tokens
+// only the hashes of the refresh tokens are stored
+type TokenRepository interface {
+	// CreateRefreshToken stores a new refresh token and returns its ID
+	// a token without a family starts a new family
+	CreateRefreshToken(ctx context.Context, token RefreshToken) (string, error)
+	// GetRefreshTokenByHash returns the refresh token with the given hash
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// RotateRefreshToken revokes a token replaced by another token of its family
+	// it fails with ErrNotFound when the token was already revoked
+	RotateRefreshToken(ctx context.Context, id string, replacedBy string, at time.Time) error
+	// RevokeTokenFamily revokes every token of the family
+	RevokeTokenFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeAccessToken records the ID of an access token that must no longer be accepted
+	RevokeAccessToken(ctx context.Context, token RevokedToken) error
+	// RevokeUserSessions revokes every refresh token of the user
+	// and every access token issued to the user until the given time
+	// the revocation is kept until expiresAt, when those access tokens have expired
+	RevokeUserSessions(ctx context.Context, userID string, at time.Time, expiresAt time.Time) error
+	// IsAccessTokenRevoked reports whether the access token has been revoked
+	IsAccessTokenRevoked(ctx context.Context, id string, userID string, issuedAt time.Time) (bool, error)
+	// PruneTokens removes the refresh tokens and revocations that expired before the given time
+	PruneTokens(ctx context.Context, before time.Time) error
+}
+
+// RevokedToken represents a revoked access token
+// the revocation is only needed until the token expires
+type RevokedToken struct {
+	ID        string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	RevokedAt time.Time `bson:"revokedAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// userRevocationID returns the ID of the revocation covering all access tokens of a user
+// the IDs of the access tokens never contain a colon
+func userRevocationID(userID string) string {
+	return "user:" + userID
+}
+
+// revokes reports whether the revocation applies to an access token
+func (r RevokedToken) revokes(id string, userID string, issuedAt time.Time) bool {
+	if r.ID == userRevocationID(userID) {
+		return !issuedAt.After(r.RevokedAt)
+	}
+	return id != "" && r.ID == id
+}
+
+// RefreshToken represents a stored refresh token
+// the tokens created by rotating a token share its family
+type RefreshToken struct {
+	ID         string     `bson:"_id"`
+	FamilyID   string     `bson:"familyId"`
+	UserID     string     `bson:"userId"`
+	TokenHash  string     `bson:"tokenHash"`
+	CreatedAt  time.Time  `bson:"createdAt"`
+	ExpiresAt  time.Time  `bson:"expiresAt"`
+	RevokedAt  *time.Time `bson:"revokedAt"`
+	ReplacedBy string     `bson:"replacedBy"`
+}
+
+// LoginFailures represents the failed logins of an email or an IP address
+type LoginFailures struct {
+	Key          string     `bson:"_id"`
+	Count        int        `bson:"count"`
+	LastFailedAt time.Time  `bson:"lastFailedAt"`
+	LockedUntil  *time.Time `bson:"lockedUntil"`
+}
+
+// Locked reports whether the logins of the key are refused at the given time
+func (f *LoginFailures) Locked(at time.Time) bool {
+	return f.LockedUntil != nil && at.Before(*f.LockedUntil)
+}
+
+// AuditRecord represents a stored security event
+// the users are empty when unknown, the actor is empty for the events of the server
+type AuditRecord struct {
+	ID        string            `bson:"_id,omitempty"`
+	Action    model.AuditAction `bson:"action"`
+	UserID    string            `bson:"userId"`
+	ActorID   string            `bson:"actorId"`
+	Email     string            `bson:"email"`
+	IP        string            `bson:"ip"`
+	CreatedAt time.Time         `bson:"createdAt"`
+}
+
+// Model returns the record with snapshots of its users
+func (r AuditRecord) Model() *model.AuditRecord {
+	var record *model.AuditRecord = &model.AuditRecord{
+		ID:        r.ID,
+		Action:    r.Action,
+		Email:     optionalString(r.Email),
+		IP:        optionalString(r.IP),
+		CreatedAt: r.CreatedAt,
+	}
+	if r.UserID != "" {
+		record.User = &model.User{ID: r.UserID}
+	}
+	if r.ActorID != "" {
+		record.Actor = &model.User{ID: r.ActorID}
+	}
+
+	return record
+}
+
+// Reaction represents the reaction of a user to a blog
+type Reaction struct {
+	BlogID    string             `bson:"blogId"`
+	UserID    string             `bson:"userId"`
+	Kind      model.ReactionKind `bson:"kind"`
+	CreatedAt time.Time          `bson:"createdAt"`
+}
+
+// Follow represents a user following another user
+type Follow struct {
+	FollowerID string    `bson:"followerId"`
+	FolloweeID string    `bson:"followeeId"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// BlogSearchHit represents a blog matching a search
+// a higher score means a more relevant blog
+type BlogSearchHit struct {
+	Blog  *model.Blog
+	Score float64
+}
+
+// BlogUpdate represents the changes applied to a blog
+type BlogUpdate struct {
+	Title     string
+	Content   string
+	Tags      []string
+	UpdatedAt time.Time
+	// Editor is recorded as the editor of the revision
+	Editor *model.User
+}
+
+// ScheduledPublication represents a blog published at its scheduled time
+type ScheduledPublication struct {
+	Blog *model.Blog
+	// First reports whether the blog was never published before
+	First bool
+}
+
+// BlogStatusUpdate represents a change of the publication state of a blog
+type BlogStatusUpdate struct {
+	Status model.BlogStatus
+	// PublishAt is the publication time of a scheduled blog, it is cleared when nil
+	PublishAt *time.Time
+	// PublishedAt is the publication time of a published blog, it is kept when nil
+	PublishedAt *time.Time
+}
+
+// Store represents a storage backend and gives access to its repositories
+type Store interface {
+	// Users returns the user repository
+	Users() UserRepository
+	// Blogs returns the blog repository
+	Blogs() BlogRepository
+	// Tokens returns the refresh token repository
+	Tokens() TokenRepository
+	// Comments returns the comment repository
+	Comments() CommentRepository
+	// Revisions returns the blog revision repository
+	Revisions() RevisionRepository
+	// Reactions returns the reaction repository
+	Reactions() ReactionRepository
+	// Follows returns the follow repository
+	Follows() FollowRepository
+	// LoginAttempts returns the failed login repository
+	LoginAttempts() LoginAttemptRepository
+	// Audit returns the audit record repository
+	Audit() AuditRepository
+	// Drop removes all data from the store
+	Drop(ctx context.Context) error
+	// Close releases the resources held by the store
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/search"
+
+	// register the PostgreSQL driver
+	"github.com/lib/pq"
+	// register the SQLite driver
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// SQLite represents the SQLite dialect
+	SQLite = "sqlite"
+	// Postgres represents the PostgreSQL dialect
+	Postgres = "postgres"
+)
+
+// SQLStore represents a relational storage backend
+// both SQLite and PostgreSQL are supported
+type SQLStore struct {
+	db        *sqlDB
+	users     *SQLUserRepository
+	blogs     *SQLBlogRepository
+	tokens    *SQLTokenRepository
+	comments  *SQLCommentRepository
+	revisions *SQLRevisionRepository
+	reactions *SQLReactionRepository
+	follows   *SQLFollowRepository
+	attempts  *SQLLoginAttemptRepository
+	audit     *SQLAuditRepository
+}
+
+// sqlDB represents a database handle with the dialect of the backend
+type sqlDB struct {
+	*sql.DB
+	dialect string
+}
+
+// sqlTx represents a transaction of a database handle
+type sqlTx struct {
+	*sql.Tx
+	db *sqlDB
+}
+
+// sqlExecutor executes the statements on the database or inside a transaction
+type sqlExecutor interface {
+	exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// OpenSQLStore opens the database, applies the migrations and returns the store
+func OpenSQLStore(ctx context.Context, dialect string, dsn string) (*SQLStore, error) {
+	if dialect != SQLite && dialect != Postgres {
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+
+	// the foreign keys of SQLite are enabled by every new connection
+	if dialect == SQLite {
+		dsn = sqliteDSN(dsn)
+	}
+
+	// open the database
+	conn, err := sql.Open(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialect == SQLite {
+		// SQLite allows a single writer, and an in-memory database
+		// only lives as long as its connection
+		conn.SetMaxOpenConns(1)
+		conn.SetConnMaxLifetime(0)
+	}
+
+	var db *sqlDB = &sqlDB{DB: conn, dialect: dialect}
+
+	// create or upgrade the tables
+	if err := migrate(ctx, db); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var store *SQLStore = &SQLStore{
+		db:        db,
+		users:     &SQLUserRepository{db: db},
+		blogs:     &SQLBlogRepository{db: db, index: search.NewIndex()},
+		tokens:    &SQLTokenRepository{db: db},
+		comments:  &SQLCommentRepository{db: db},
+		revisions: &SQLRevisionRepository{db: db},
+		reactions: &SQLReactionRepository{db: db},
+		follows:   &SQLFollowRepository{db: db},
+		attempts:  &SQLLoginAttemptRepository{db: db},
+		audit:     &SQLAuditRepository{db: db},
+	}
+
+	// build the search index from the stored blogs
+	if err := store.blogs.loadIndex(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// sqliteDSN adds the pragma enabling the foreign keys to the SQLite data source name
+// the pragma is run on every connection the pool opens, so the cascades always apply
+func sqliteDSN(dsn string) string {
+	var separator string = "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	return dsn + separator + "_pragma=foreign_keys(1)"
+}
+
+// Users returns the user repository
+func (s *SQLStore) Users() UserRepository {
+	return s.users
+}
+
+// Blogs returns the blog repository
+func (s *SQLStore) Blogs() BlogRepository {
+	return s.blogs
+}
+
+// Tokens returns the refresh token repository
+func (s *SQLStore) Tokens() TokenRepository {
+	return s.tokens
+}
+
+// Comments returns the comment repository
+func (s *SQLStore) Comments() CommentRepository {
+	return s.comments
+}
+
+// Revisions returns the blog revision repository
+func (s *SQLStore) Revisions() RevisionRepository {
+	return s.revisions
+}
+
+// Reactions returns the reaction repository
+func (s *SQLStore) Reactions() ReactionRepository {
+	return s.reactions
+}
+
+// Follows returns the follow repository
+func (s *SQLStore) Follows() FollowRepository {
+	return s.follows
+}
+
+// LoginAttempts returns the failed login repository
+func (s *SQLStore) LoginAttempts() LoginAttemptRepository {
+	return s.attempts
+}
+
+// Audit returns the audit record repository
+func (s *SQLStore) Audit() AuditRepository {
+	return s.audit
+}
+
+// Drop removes all rows from the tables, the schema is kept
+func (s *SQLStore) Drop(ctx context.Context) error {
+	// the tables are emptied in the reverse order of the migrations
+	// so the foreign keys are never violated
+	for i := len(sqlTables) - 1; i >= 0; i-- {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM "+sqlTables[i]); err != nil {
+			return err
+		}
+	}
+
+	s.blogs.index.Clear()
+
+	return nil
+}
+
+// Close closes the database
+func (s *SQLStore) Close(ctx context.Context) error {
+	return s.db.Close()
+}
+
+// rebind replaces the "?" placeholders with the placeholders of the dialect
+func (db *sqlDB) rebind(query string) string {
+	if db.dialect != Postgres {
+		return query
+	}
+
+	var (
+		builder strings.Builder
+		index   int
+	)
+
+	for _, char := range query {
+		if char != '?' {
+			builder.WriteRune(char)
+			continue
+		}
+		index++
+		builder.WriteString("$" + strconv.Itoa(index))
+	}
+
+	return builder.String()
+}
+
+// exec executes a query without returning any rows
+func (db *sqlDB) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(ctx, db.rebind(query), args...)
+}
+
+// query executes a query that returns rows
+func (db *sqlDB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(ctx, db.rebind(query), args...)
+}
+
+// queryRow executes a query that returns at most one row
+func (db *sqlDB) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(ctx, db.rebind(query), args...)
+}
+
+// withTx runs fn inside a transaction, which is committed when fn succeeds and rolled back otherwise
+func (db *sqlDB) withTx(ctx context.Context, fn func(tx *sqlTx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlTx{Tx: tx, db: db}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// exec executes a statement of the transaction without returning any rows
+func (tx *sqlTx) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(ctx, tx.db.rebind(query), args...)
+}
+
+// query executes a query of the transaction that returns rows
+func (tx *sqlTx) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(ctx, tx.db.rebind(query), args...)
+}
+
+// queryRow executes a query of the transaction that returns at most one row
+func (tx *sqlTx) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.QueryRowContext(ctx, tx.db.rebind(query), args...)
+}
+
+// isUniqueViolation reports whether the statement failed on a unique index or a primary key
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	return false
+}
+
+// sqlTime converts the time into the precision shared by all dialects
+func sqlTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// sqlNullTime converts an optional time into a nullable column value
+func sqlNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: sqlTime(*t), Valid: true}
+}
+
+// timePointer converts a nullable column value into an optional time
+func timePointer(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	var value time.Time = t.Time
+	return &value
+}
@@ -0,0 +1,767 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/search"
+)
+
+// sqlBlogQuery selects the blogs together with their authors
+const sqlBlogQuery = `SELECT
+	b.id, b.title, b.content, b.created_at, b.updated_at,
+	b.status, b.publish_at, b.published_at, b.deleted_at,
+	u.id, u.username
+	FROM blogs b LEFT JOIN users u ON u.id = b.author_id`
+
+// SQLBlogRepository stores blogs in the "blogs" table
+// the blogs are searched with an in-process index kept next to the table
+type SQLBlogRepository struct {
+	db    *sqlDB
+	index *search.Index
+}
+
+// loadIndex adds the published blogs to the search index
+func (r *SQLBlogRepository) loadIndex(ctx context.Context) error {
+	rows, err := r.db.query(ctx, "SELECT id, title, content FROM blogs WHERE status = ? AND deleted_at IS NULL", model.BlogStatusPublished)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, title, content string
+		if err := rows.Scan(&id, &title, &content); err != nil {
+			return err
+		}
+		r.index.Add(id, title, content)
+	}
+
+	return rows.Err()
+}
+
+// ListBlogs returns the blogs matching the query
+func (r *SQLBlogRepository) ListBlogs(ctx context.Context, query BlogQuery) ([]*model.Blog, error) {
+	var (
+		field      BlogSortField = query.sortField()
+		column     string        = sqlSortColumns[field]
+		direction  string        = "DESC"
+		conditions []string
+		args       []interface{}
+	)
+
+	conditions, args = sqlBlogFilter(query.Filter)
+
+	if query.Ascending {
+		direction = "ASC"
+	}
+
+	// blogs placed after the cursor follow the sort direction
+	if query.After != nil {
+		condition, conditionArgs := sqlCursorCondition(*query.After, field, query.Ascending)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	// blogs placed before the cursor go against the sort direction
+	if query.Before != nil {
+		condition, conditionArgs := sqlCursorCondition(*query.Before, field, !query.Ascending)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	var statement string = sqlBlogQuery
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// a missing update time is lower than any time in every dialect
+	var nulls string
+	if field == SortByUpdatedAt {
+		nulls = " NULLS LAST"
+		if query.Ascending {
+			nulls = " NULLS FIRST"
+		}
+	}
+
+	statement += " ORDER BY " + column + " " + direction + nulls + ", b.id " + direction
+
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	return r.queryBlogs(ctx, statement, args...)
+}
+
+// SearchBlogs returns the published blogs whose title or content match the text, the most relevant first
+func (r *SQLBlogRepository) SearchBlogs(ctx context.Context, text string, offset int, limit int) ([]BlogSearchHit, error) {
+	var hits []search.Hit = pageHits(r.index.Search(text), offset, limit)
+	if len(hits) == 0 {
+		return []BlogSearchHit{}, nil
+	}
+
+	var (
+		placeholders []string      = make([]string, 0, len(hits))
+		args         []interface{} = make([]interface{}, 0, len(hits))
+	)
+
+	for _, hit := range hits {
+		placeholders = append(placeholders, "?")
+		args = append(args, hit.ID)
+	}
+
+	blogs, err := r.queryBlogs(ctx, sqlBlogQuery+" WHERE b.deleted_at IS NULL AND b.id IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var byID map[string]*model.Blog = make(map[string]*model.Blog, len(blogs))
+	for _, blog := range blogs {
+		byID[blog.ID] = blog
+	}
+
+	// keep the order of the index
+	results := make([]BlogSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		if blog, ok := byID[hit.ID]; ok {
+			results = append(results, BlogSearchHit{Blog: blog, Score: hit.Score})
+		}
+	}
+
+	return results, nil
+}
+
+// GetBlogByID returns the blog with the given ID
+func (r *SQLBlogRepository) GetBlogByID(ctx context.Context, id string) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	blog, err := scanBlog(r.db.queryRow(ctx, sqlBlogQuery+" WHERE b.id = ? AND b.deleted_at IS NULL", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := r.loadTags(ctx, []*model.Blog{blog}); err != nil {
+		return nil, err
+	}
+
+	return blog, nil
+}
+
+// CreateBlog stores a new blog and returns the stored record
+func (r *SQLBlogRepository) CreateBlog(ctx context.Context, blog model.Blog) (*model.Blog, error) {
+	var (
+		id       string = newObjectID()
+		authorID sql.NullString
+	)
+
+	if blog.Author != nil {
+		authorID = sql.NullString{String: blog.Author.ID, Valid: true}
+	}
+
+	// the blog is never stored without its tags and its first revision
+	err := r.db.withTx(ctx, func(tx *sqlTx) error {
+		_, err := tx.exec(
+			ctx,
+			"INSERT INTO blogs (id, title, content, author_id, created_at, updated_at, status, publish_at, published_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			id,
+			blog.Title,
+			blog.Content,
+			authorID,
+			sqlTime(blog.CreatedAt),
+			sqlNullTime(blog.UpdatedAt),
+			blog.Status,
+			sqlNullTime(blog.PublishAt),
+			sqlNullTime(blog.PublishedAt),
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := setTags(ctx, tx, id, blog.Tags); err != nil {
+			return err
+		}
+
+		blog.ID = id
+		return insertRevision(ctx, tx, newRevision(nil, &blog, blog.Author, 1))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.reindex(ctx, id)
+}
+
+// UpdateBlog changes the title and the content of a blog
+func (r *SQLBlogRepository) UpdateBlog(ctx context.Context, id string, authorID *string, update BlogUpdate) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	condition, args := sqlOwnedBlog(id, authorID)
+
+	// the tags and the revision are only stored together with the blog,
+	// and the revision is compared with the row the edit replaces
+	err := r.db.withTx(ctx, func(tx *sqlTx) error {
+		previous, err := selectBlogForUpdate(ctx, tx, condition, args)
+		if err != nil {
+			return err
+		}
+
+		// the blogs created before the revisions were recorded
+		// get their previous version as the first revision
+		var latest int
+		if err := tx.queryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM blog_revisions WHERE blog_id = ?", id).Scan(&latest); err != nil {
+			return err
+		}
+		if latest == 0 {
+			latest = 1
+			if err := insertRevision(ctx, tx, newRevision(nil, previous, previous.Author, latest)); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.exec(
+			ctx,
+			"UPDATE blogs SET title = ?, content = ?, updated_at = ? WHERE "+condition,
+			append([]interface{}{update.Title, update.Content, sqlTime(update.UpdatedAt)}, args...)...,
+		)
+		if err := checkAffected(result, err); err != nil {
+			return err
+		}
+
+		if err := setTags(ctx, tx, id, update.Tags); err != nil {
+			return err
+		}
+
+		return insertRevision(ctx, tx, newRevision(previous, editedBlog(*previous, update), update.Editor, latest+1))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.reindex(ctx, id)
+}
+
+// TrashBlog moves a blog to the trash
+func (r *SQLBlogRepository) TrashBlog(ctx context.Context, id string, authorID *string, deletedAt time.Time) error {
+	if !validObjectID(id) {
+		return ErrInvalidID
+	}
+
+	condition, args := sqlOwnedBlog(id, authorID)
+	result, err := r.db.exec(ctx, "UPDATE blogs SET deleted_at = ? WHERE "+condition, append([]interface{}{sqlTime(deletedAt)}, args...)...)
+	if err := checkAffected(result, err); err != nil {
+		return err
+	}
+
+	r.index.Remove(id)
+
+	return nil
+}
+
+// RestoreBlog brings a blog back from the trash
+func (r *SQLBlogRepository) RestoreBlog(ctx context.Context, id string, authorID *string) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	var (
+		statement string        = "UPDATE blogs SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL"
+		args      []interface{} = []interface{}{id}
+	)
+
+	if authorID != nil {
+		statement += " AND author_id = ?"
+		args = append(args, *authorID)
+	}
+
+	result, err := r.db.exec(ctx, statement, args...)
+	if err := checkAffected(result, err); err != nil {
+		return nil, err
+	}
+
+	return r.reindex(ctx, id)
+}
+
+// ListTrashedBlogs returns the blogs of the author in the trash, the last deleted first
+func (r *SQLBlogRepository) ListTrashedBlogs(ctx context.Context, authorID string) ([]*model.Blog, error) {
+	return r.queryBlogs(
+		ctx,
+		sqlBlogQuery+" WHERE b.deleted_at IS NOT NULL AND b.author_id = ? ORDER BY b.deleted_at DESC, b.id DESC",
+		authorID,
+	)
+}
+
+// ListExpiredBlogs returns the IDs of the blogs moved to the trash before the given time
+func (r *SQLBlogRepository) ListExpiredBlogs(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := r.db.query(ctx, "SELECT id FROM blogs WHERE deleted_at < ?", sqlTime(before))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string = []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// PurgeTrashedBlog removes the blog when it was moved to the trash before the given time
+func (r *SQLBlogRepository) PurgeTrashedBlog(ctx context.Context, id string, before time.Time) error {
+	if !validObjectID(id) {
+		return ErrInvalidID
+	}
+
+	result, err := r.db.exec(ctx, "DELETE FROM blogs WHERE id = ? AND deleted_at < ?", id, sqlTime(before))
+	return checkAffected(result, err)
+}
+
+// ListTags returns the tags of the published blogs with their usage, the most used first
+func (r *SQLBlogRepository) ListTags(ctx context.Context) ([]*model.TagCount, error) {
+	rows, err := r.db.query(
+		ctx,
+		`SELECT t.tag, COUNT(*) FROM blog_tags t JOIN blogs b ON b.id = t.blog_id
+		WHERE b.status = ? AND b.deleted_at IS NULL GROUP BY t.tag ORDER BY COUNT(*) DESC, t.tag ASC`,
+		model.BlogStatusPublished,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]*model.TagCount, 0)
+
+	for rows.Next() {
+		var count *model.TagCount = &model.TagCount{}
+		if err := rows.Scan(&count.Tag, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// UpdateBlogStatus changes the publication state of a blog
+func (r *SQLBlogRepository) UpdateBlogStatus(ctx context.Context, id string, authorID *string, update BlogStatusUpdate) (*model.Blog, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	var (
+		statement string        = "UPDATE blogs SET status = ?, publish_at = ?"
+		args      []interface{} = []interface{}{update.Status, sqlNullTime(update.PublishAt)}
+	)
+
+	if update.PublishedAt != nil {
+		statement += ", published_at = ?"
+		args = append(args, sqlTime(*update.PublishedAt))
+	}
+
+	condition, conditionArgs := sqlOwnedBlog(id, authorID)
+	result, err := r.db.exec(ctx, statement+" WHERE "+condition, append(args, conditionArgs...)...)
+	if err := checkAffected(result, err); err != nil {
+		return nil, err
+	}
+
+	return r.reindex(ctx, id)
+}
+
+// PublishScheduledBlogs publishes the scheduled blogs whose publication time has come
+func (r *SQLBlogRepository) PublishScheduledBlogs(ctx context.Context, now time.Time) ([]ScheduledPublication, error) {
+	rows, err := r.db.query(ctx, "SELECT id, published_at FROM blogs WHERE status = ? AND publish_at <= ? AND deleted_at IS NULL", model.BlogStatusScheduled, sqlTime(now))
+	if err != nil {
+		return nil, err
+	}
+
+	// the blogs without a publication time were never published
+	var scheduled []ScheduledPublication
+	for rows.Next() {
+		var (
+			id          string
+			publishedAt sql.NullTime
+		)
+		if err := rows.Scan(&id, &publishedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		scheduled = append(scheduled, ScheduledPublication{Blog: &model.Blog{ID: id}, First: !publishedAt.Valid})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the blogs are published at their publication time
+	var published []ScheduledPublication
+	for _, publication := range scheduled {
+		result, err := r.db.exec(
+			ctx,
+			"UPDATE blogs SET status = ?, published_at = publish_at, publish_at = NULL WHERE id = ? AND status = ? AND deleted_at IS NULL",
+			model.BlogStatusPublished,
+			publication.Blog.ID,
+			model.BlogStatusScheduled,
+		)
+		if err := checkAffected(result, err); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return published, err
+		}
+
+		if publication.Blog, err = r.reindex(ctx, publication.Blog.ID); err != nil {
+			return published, err
+		}
+		published = append(published, publication)
+	}
+
+	return published, nil
+}
+
+// reindex keeps the search index in line with the stored blog and returns the blog
+// only the published blogs are searched
+func (r *SQLBlogRepository) reindex(ctx context.Context, id string) (*model.Blog, error) {
+	blog, err := r.GetBlogByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if blog.Status == model.BlogStatusPublished {
+		r.index.Add(blog.ID, blog.Title, blog.Content)
+	} else {
+		r.index.Remove(blog.ID)
+	}
+
+	return blog, nil
+}
+
+// setTags replaces the tags of the blog
+func setTags(ctx context.Context, db sqlExecutor, id string, tags []string) error {
+	if _, err := db.exec(ctx, "DELETE FROM blog_tags WHERE blog_id = ?", id); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := db.exec(ctx, "INSERT INTO blog_tags (blog_id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectBlogForUpdate reads the blog an edit is about to replace
+// PostgreSQL locks the row until the end of the transaction, SQLite runs a single transaction at a time
+func selectBlogForUpdate(ctx context.Context, tx *sqlTx, condition string, args []interface{}) (*model.Blog, error) {
+	var (
+		statement string      = "SELECT id, title, content, author_id, created_at, updated_at FROM blogs WHERE " + condition
+		blog      *model.Blog = &model.Blog{}
+		authorID  sql.NullString
+		updatedAt sql.NullTime
+	)
+
+	if tx.db.dialect == Postgres {
+		statement += " FOR UPDATE"
+	}
+
+	err := tx.queryRow(ctx, statement, args...).Scan(&blog.ID, &blog.Title, &blog.Content, &authorID, &blog.CreatedAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	blog.UpdatedAt = timePointer(updatedAt)
+	if authorID.Valid {
+		blog.Author = &model.User{ID: authorID.String}
+	}
+
+	return blog, nil
+}
+
+// loadTags reads the tags of the blogs, sorted by name
+func (r *SQLBlogRepository) loadTags(ctx context.Context, blogs []*model.Blog) error {
+	if len(blogs) == 0 {
+		return nil
+	}
+
+	var (
+		byID         map[string]*model.Blog = make(map[string]*model.Blog, len(blogs))
+		placeholders []string               = make([]string, 0, len(blogs))
+		args         []interface{}          = make([]interface{}, 0, len(blogs))
+	)
+
+	for _, blog := range blogs {
+		blog.Tags = []string{}
+		byID[blog.ID] = blog
+		placeholders = append(placeholders, "?")
+		args = append(args, blog.ID)
+	}
+
+	rows, err := r.db.query(ctx, "SELECT blog_id, tag FROM blog_tags WHERE blog_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY tag", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blogID, tag string
+		if err := rows.Scan(&blogID, &tag); err != nil {
+			return err
+		}
+		if blog, ok := byID[blogID]; ok {
+			blog.Tags = append(blog.Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+// sqlOwnedBlog returns the condition selecting a blog outside the trash
+// when authorID is set, the blog must also be owned by that author
+func sqlOwnedBlog(id string, authorID *string) (string, []interface{}) {
+	if authorID == nil {
+		return "id = ? AND deleted_at IS NULL", []interface{}{id}
+	}
+
+	return "id = ? AND deleted_at IS NULL AND author_id = ?", []interface{}{id, *authorID}
+}
+
+// queryBlogs returns the blogs selected by the query
+func (r *SQLBlogRepository) queryBlogs(ctx context.Context, query string, args ...interface{}) ([]*model.Blog, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blogs := make([]*model.Blog, 0)
+
+	for rows.Next() {
+		blog, err := scanBlog(rows)
+		if err != nil {
+			return nil, err
+		}
+		blogs = append(blogs, blog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// SQLite allows a single connection, so the rows are closed before the tags are read
+	if err := r.loadTags(ctx, blogs); err != nil {
+		return nil, err
+	}
+
+	return blogs, nil
+}
+
+// sqlSortColumns maps the sort fields to the columns
+var sqlSortColumns = map[BlogSortField]string{
+	SortByCreatedAt: "b.created_at",
+	SortByUpdatedAt: "b.updated_at",
+	SortByTitle:     "b.title",
+}
+
+// sqlLikeEscaper escapes the wildcards of a LIKE pattern
+var sqlLikeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// sqlBlogFilter translates the filter into SQL conditions and their arguments
+// the blogs in the trash are left out
+func sqlBlogFilter(filter BlogFilter) ([]string, []interface{}) {
+	var (
+		conditions []string      = []string{"b.deleted_at IS NULL"}
+		args       []interface{} = []interface{}{}
+	)
+
+	if filter.AuthorID != "" {
+		conditions = append(conditions, "b.author_id = ?")
+		args = append(args, filter.AuthorID)
+	}
+
+	if len(filter.AuthorIDs) > 0 {
+		var placeholders []string = make([]string, 0, len(filter.AuthorIDs))
+		for _, id := range filter.AuthorIDs {
+			placeholders = append(placeholders, "?")
+			args = append(args, id)
+		}
+		conditions = append(conditions, "b.author_id IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if filter.Tag != "" {
+		conditions = append(conditions, "b.id IN (SELECT blog_id FROM blog_tags WHERE tag = ?)")
+		args = append(args, filter.Tag)
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "b.status = ?")
+		args = append(args, filter.Status)
+	}
+
+	if filter.Visibility != nil {
+		if filter.Visibility.AuthorID != "" {
+			conditions = append(conditions, "(b.status = ? OR b.author_id = ?)")
+			args = append(args, model.BlogStatusPublished, filter.Visibility.AuthorID)
+		} else {
+			conditions = append(conditions, "b.status = ?")
+			args = append(args, model.BlogStatusPublished)
+		}
+	}
+
+	if filter.TitleContains != "" {
+		conditions = append(conditions, `LOWER(b.title) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+sqlLikeEscaper.Replace(strings.ToLower(filter.TitleContains))+"%")
+	}
+
+	var ranges = []struct {
+		condition string
+		value     *time.Time
+	}{
+		{"b.created_at > ?", filter.CreatedAfter},
+		{"b.created_at < ?", filter.CreatedBefore},
+		{"b.updated_at > ?", filter.UpdatedAfter},
+		{"b.updated_at < ?", filter.UpdatedBefore},
+	}
+
+	for _, r := range ranges {
+		if r.value != nil {
+			conditions = append(conditions, r.condition)
+			args = append(args, sqlTime(*r.value))
+		}
+	}
+
+	if filter.Edited != nil {
+		// a blog that was never edited has no update time
+		if *filter.Edited {
+			conditions = append(conditions, "b.updated_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "b.updated_at IS NULL")
+		}
+	}
+
+	return conditions, args
+}
+
+// sqlCursorCondition returns a condition for the blogs placed after a cursor,
+// greater values when greater is set and lower values otherwise
+// a missing update time is lower than any time
+func sqlCursorCondition(cursor BlogCursor, field BlogSortField, greater bool) (string, []interface{}) {
+	var (
+		column   string = sqlSortColumns[field]
+		operator string = "<"
+		value    interface{}
+	)
+
+	if greater {
+		operator = ">"
+	}
+
+	switch field {
+	case SortByTitle:
+		value = cursor.Title
+	case SortByUpdatedAt:
+		if cursor.UpdatedAt != nil {
+			value = sqlTime(*cursor.UpdatedAt)
+		}
+	default:
+		value = sqlTime(cursor.CreatedAt)
+	}
+
+	switch {
+	case value == nil && greater:
+		return "(" + column + " IS NOT NULL OR b.id > ?)", []interface{}{cursor.ID}
+	case value == nil:
+		return "(" + column + " IS NULL AND b.id < ?)", []interface{}{cursor.ID}
+	case !greater && field == SortByUpdatedAt:
+		return "(" + column + " < ? OR " + column + " IS NULL OR (" + column + " = ? AND b.id < ?))", []interface{}{value, value, cursor.ID}
+	}
+
+	return "(" + column + " " + operator + " ? OR (" + column + " = ? AND b.id " + operator + " ?))", []interface{}{value, value, cursor.ID}
+}
+
+// rowScanner represents a single row or a set of rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBlog reads a blog and its author from the row
+func scanBlog(row rowScanner) (*model.Blog, error) {
+	var (
+		blog           *model.Blog = &model.Blog{}
+		updatedAt      sql.NullTime
+		publishAt      sql.NullTime
+		publishedAt    sql.NullTime
+		deletedAt      sql.NullTime
+		authorID       sql.NullString
+		authorUsername sql.NullString
+	)
+
+	err := row.Scan(
+		&blog.ID,
+		&blog.Title,
+		&blog.Content,
+		&blog.CreatedAt,
+		&updatedAt,
+		&blog.Status,
+		&publishAt,
+		&publishedAt,
+		&deletedAt,
+		&authorID,
+		&authorUsername,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	blog.UpdatedAt = timePointer(updatedAt)
+	blog.PublishAt = timePointer(publishAt)
+	blog.PublishedAt = timePointer(publishedAt)
+	blog.DeletedAt = timePointer(deletedAt)
+
+	// the author is optional, only its snapshot is read
+	if authorID.Valid {
+		blog.Author = &model.User{
+			ID:       authorID.String,
+			Username: authorUsername.String,
+		}
+	}
+
+	return blog, nil
+}
+
+// checkAffected returns ErrNotFound when the statement changed no rows
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
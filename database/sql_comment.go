@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// sqlCommentQuery selects the comments together with their authors
+const sqlCommentQuery = `SELECT
+	c.id, c.blog_id, c.parent_id, c.depth, c.content, c.created_at, c.updated_at,
+	u.id, u.username
+	FROM comments c LEFT JOIN users u ON u.id = c.author_id`
+
+// SQLCommentRepository stores comments in the "comments" table
+type SQLCommentRepository struct {
+	db *sqlDB
+}
+
+// ListComments returns the comments matching the query, oldest first
+func (r *SQLCommentRepository) ListComments(ctx context.Context, query CommentQuery) ([]*model.Comment, error) {
+	var (
+		statement string        = sqlCommentQuery + " WHERE c.blog_id = ?"
+		args      []interface{} = []interface{}{query.BlogID}
+	)
+
+	if query.ParentID == nil {
+		statement += " AND c.parent_id IS NULL"
+	} else {
+		statement += " AND c.parent_id = ?"
+		args = append(args, *query.ParentID)
+	}
+
+	if query.After != nil {
+		var createdAt time.Time = sqlTime(query.After.CreatedAt)
+		statement += " AND (c.created_at > ? OR (c.created_at = ? AND c.id > ?))"
+		args = append(args, createdAt, createdAt, query.After.ID)
+	}
+
+	statement += " ORDER BY c.created_at ASC, c.id ASC"
+
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := r.db.query(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*model.Comment, 0)
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// GetCommentByID returns the comment with the given ID
+func (r *SQLCommentRepository) GetCommentByID(ctx context.Context, id string) (*model.Comment, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	comment, err := scanComment(r.db.queryRow(ctx, sqlCommentQuery+" WHERE c.id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// CountComments returns the number of comments of the blog, including the replies
+func (r *SQLCommentRepository) CountComments(ctx context.Context, blogID string) (int, error) {
+	var count int
+	err := r.db.queryRow(ctx, "SELECT COUNT(*) FROM comments WHERE blog_id = ?", blogID).Scan(&count)
+	return count, err
+}
+
+// CountReplies returns the number of direct replies of the comment
+func (r *SQLCommentRepository) CountReplies(ctx context.Context, commentID string) (int, error) {
+	var count int
+	err := r.db.queryRow(ctx, "SELECT COUNT(*) FROM comments WHERE parent_id = ?", commentID).Scan(&count)
+	return count, err
+}
+
+// CreateComment stores a new comment and returns the stored record
+func (r *SQLCommentRepository) CreateComment(ctx context.Context, comment model.Comment) (*model.Comment, error) {
+	var (
+		id       string = newObjectID()
+		parentID sql.NullString
+		authorID sql.NullString
+	)
+
+	if comment.ParentID != nil {
+		parentID = sql.NullString{String: *comment.ParentID, Valid: true}
+	}
+
+	if comment.Author != nil {
+		authorID = sql.NullString{String: comment.Author.ID, Valid: true}
+	}
+
+	_, err := r.db.exec(
+		ctx,
+		"INSERT INTO comments (id, blog_id, parent_id, depth, content, author_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		id,
+		comment.BlogID,
+		parentID,
+		comment.Depth,
+		comment.Content,
+		authorID,
+		sqlTime(comment.CreatedAt),
+		sqlNullTime(comment.UpdatedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetCommentByID(ctx, id)
+}
+
+// UpdateComment changes the content of a comment
+func (r *SQLCommentRepository) UpdateComment(ctx context.Context, id string, content string, updatedAt time.Time) (*model.Comment, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	result, err := r.db.exec(ctx, "UPDATE comments SET content = ?, updated_at = ? WHERE id = ?", content, sqlTime(updatedAt), id)
+	if err := checkAffected(result, err); err != nil {
+		return nil, err
+	}
+
+	return r.GetCommentByID(ctx, id)
+}
+
+// DeleteComment removes a comment, the replies are removed by the foreign key
+func (r *SQLCommentRepository) DeleteComment(ctx context.Context, id string) error {
+	if !validObjectID(id) {
+		return ErrInvalidID
+	}
+
+	result, err := r.db.exec(ctx, "DELETE FROM comments WHERE id = ?", id)
+	return checkAffected(result, err)
+}
+
+// DeleteBlogComments removes all comments of the blog
+func (r *SQLCommentRepository) DeleteBlogComments(ctx context.Context, blogID string) error {
+	_, err := r.db.exec(ctx, "DELETE FROM comments WHERE blog_id = ?", blogID)
+	return err
+}
+
+// scanComment reads a comment and its author from the row
+func scanComment(row rowScanner) (*model.Comment, error) {
+	var (
+		comment        *model.Comment = &model.Comment{}
+		parentID       sql.NullString
+		updatedAt      sql.NullTime
+		authorID       sql.NullString
+		authorUsername sql.NullString
+	)
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.BlogID,
+		&parentID,
+		&comment.Depth,
+		&comment.Content,
+		&comment.CreatedAt,
+		&updatedAt,
+		&authorID,
+		&authorUsername,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		comment.ParentID = &parentID.String
+	}
+
+	comment.UpdatedAt = timePointer(updatedAt)
+
+	// the author is optional, only its snapshot is read
+	if authorID.Valid {
+		comment.Author = &model.User{
+			ID:       authorID.String,
+			Username: authorUsername.String,
+		}
+	}
+
+	return comment, nil
+}
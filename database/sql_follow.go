@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// SQLFollowRepository stores the follows between users in the "follows" table
+type SQLFollowRepository struct {
+	db *sqlDB
+}
+
+// Follow stores the follow, it reports false when the follower already follows the user
+func (r *SQLFollowRepository) Follow(ctx context.Context, follow Follow) (bool, error) {
+	// the primary key rejects a second follow of the same user
+	result, err := r.db.exec(
+		ctx,
+		`INSERT INTO follows (follower_id, followee_id, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (follower_id, followee_id) DO NOTHING`,
+		follow.FollowerID,
+		follow.FolloweeID,
+		sqlTime(follow.CreatedAt),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// Unfollow removes the follow, it reports false when there was none
+func (r *SQLFollowRepository) Unfollow(ctx context.Context, followerID string, followeeID string) (bool, error) {
+	result, err := r.db.exec(ctx, "DELETE FROM follows WHERE follower_id = ? AND followee_id = ?", followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ListFollows returns the follows matching the query, the last started first
+func (r *SQLFollowRepository) ListFollows(ctx context.Context, query FollowQuery) ([]Follow, error) {
+	var (
+		statement string        = "SELECT follower_id, followee_id, created_at FROM follows WHERE 1 = 1"
+		args      []interface{} = []interface{}{}
+		// the listed user breaks the ties between the follows started at the same time
+		listed string = "follower_id"
+	)
+
+	if query.FollowerID != "" {
+		listed = "followee_id"
+		statement += " AND follower_id = ?"
+		args = append(args, query.FollowerID)
+	}
+
+	if query.FolloweeID != "" {
+		statement += " AND followee_id = ?"
+		args = append(args, query.FolloweeID)
+	}
+
+	if query.After != nil {
+		var createdAt time.Time = sqlTime(query.After.CreatedAt)
+		statement += " AND (created_at < ? OR (created_at = ? AND " + listed + " < ?))"
+		args = append(args, createdAt, createdAt, query.After.UserID)
+	}
+
+	statement += " ORDER BY created_at DESC, " + listed + " DESC"
+
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := r.db.query(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := make([]Follow, 0)
+
+	for rows.Next() {
+		var follow Follow
+		if err := rows.Scan(&follow.FollowerID, &follow.FolloweeID, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+
+	return follows, rows.Err()
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// sqlAuditColumns lists the columns read into an audit record
+const sqlAuditColumns = "id, action, user_id, actor_id, email, ip, created_at"
+
+// SQLLoginAttemptRepository stores the failed logins in the "login_failures" table
+type SQLLoginAttemptRepository struct {
+	db *sqlDB
+}
+
+// GetLoginFailures returns the failures of the key, it fails with ErrNotFound when there are none
+func (r *SQLLoginAttemptRepository) GetLoginFailures(ctx context.Context, key string) (*LoginFailures, error) {
+	var (
+		failures    *LoginFailures = &LoginFailures{}
+		lockedUntil sql.NullTime
+	)
+
+	err := r.db.queryRow(
+		ctx,
+		"SELECT login_key, failure_count, last_failed_at, locked_until FROM login_failures WHERE login_key = ?",
+		key,
+	).Scan(&failures.Key, &failures.Count, &failures.LastFailedAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	failures.LockedUntil = timePointer(lockedUntil)
+
+	return failures, nil
+}
+
+// RecordLoginFailure counts a failed login of the key and returns the updated record
+func (r *SQLLoginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, at time.Time, since time.Time) (*LoginFailures, error) {
+	// the count is changed by the database, so the concurrent failures are all counted
+	_, err := r.db.exec(
+		ctx,
+		`INSERT INTO login_failures (login_key, failure_count, last_failed_at) VALUES (?, 1, ?)
+		ON CONFLICT (login_key) DO UPDATE SET
+			failure_count = CASE WHEN login_failures.last_failed_at < ? THEN 1 ELSE login_failures.failure_count + 1 END,
+			last_failed_at = excluded.last_failed_at`,
+		key,
+		sqlTime(at),
+		sqlTime(since),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetLoginFailures(ctx, key)
+}
+
+// LockLogin refuses the logins of the key until the given time
+func (r *SQLLoginAttemptRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.exec(
+		ctx,
+		`INSERT INTO login_failures (login_key, failure_count, last_failed_at, locked_until) VALUES (?, 0, ?, ?)
+		ON CONFLICT (login_key) DO UPDATE SET locked_until = excluded.locked_until`,
+		key,
+		sqlTime(time.Time{}),
+		sqlTime(until),
+	)
+
+	return err
+}
+
+// ResetLoginFailures forgets the failures and the lock of the key
+func (r *SQLLoginAttemptRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	_, err := r.db.exec(ctx, "DELETE FROM login_failures WHERE login_key = ?", key)
+	return err
+}
+
+// SQLAuditRepository stores the security events in the "audit_records" table
+type SQLAuditRepository struct {
+	db *sqlDB
+}
+
+// CreateAuditRecord stores a new record and returns its ID
+func (r *SQLAuditRepository) CreateAuditRecord(ctx context.Context, record AuditRecord) (string, error) {
+	record.ID = newObjectID()
+
+	_, err := r.db.exec(
+		ctx,
+		"INSERT INTO audit_records ("+sqlAuditColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		record.ID,
+		record.Action,
+		record.UserID,
+		record.ActorID,
+		record.Email,
+		record.IP,
+		sqlTime(record.CreatedAt),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+// ListAuditRecords returns the latest records, newest first
+func (r *SQLAuditRepository) ListAuditRecords(ctx context.Context, userID string, limit int) ([]AuditRecord, error) {
+	var (
+		query string        = "SELECT " + sqlAuditColumns + " FROM audit_records"
+		args  []interface{} = []interface{}{}
+	)
+
+	if userID != "" {
+		query += " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+
+	// the IDs grow with time, so they order the records created at the same time
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]AuditRecord, 0)
+
+	for rows.Next() {
+		var record AuditRecord
+		if err := rows.Scan(&record.ID, &record.Action, &record.UserID, &record.ActorID, &record.Email, &record.IP, &record.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
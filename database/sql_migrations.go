@@ -0,0 +1,312 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqlMigration represents a versioned change of the SQL schema
+type sqlMigration struct {
+	version int
+	// check refuses the migration when the stored data cannot be migrated
+	check      func(ctx context.Context, tx *sqlTx) error
+	statements []string
+}
+
+// sqlTables lists the tables in the order they are created
+var sqlTables = []string{"users", "blogs", "refresh_tokens", "revoked_tokens", "comments", "blog_tags", "blog_revisions", "blog_reactions", "follows", "login_failures", "audit_records"}
+
+// sqlMigrations lists the schema changes, they are applied in order
+// a migration must never be changed once it has been released
+var sqlMigrations = []sqlMigration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE users (
+				id TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				email TEXT NOT NULL,
+				password TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NULL
+			)`,
+			`CREATE INDEX users_email_idx ON users (email)`,
+			`CREATE TABLE blogs (
+				id TEXT PRIMARY KEY,
+				title TEXT NOT NULL,
+				content TEXT NOT NULL,
+				author_id TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NULL
+			)`,
+			`CREATE INDEX blogs_created_at_idx ON blogs (created_at, id)`,
+			`CREATE INDEX blogs_author_id_idx ON blogs (author_id)`,
+		},
+	},
+	{
+		version: 2,
+		statements: []string{
+			`CREATE INDEX blogs_updated_at_idx ON blogs (updated_at, id)`,
+			`CREATE INDEX blogs_title_idx ON blogs (title, id)`,
+		},
+	},
+	{
+		version: 3,
+		statements: []string{
+			`CREATE TABLE refresh_tokens (
+				id TEXT PRIMARY KEY,
+				family_id TEXT NOT NULL,
+				user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				token_hash TEXT NOT NULL UNIQUE,
+				created_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				revoked_at TIMESTAMP NULL,
+				replaced_by TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id)`,
+		},
+	},
+	{
+		version: 4,
+		statements: []string{
+			`CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id)`,
+			`CREATE INDEX refresh_tokens_expires_at_idx ON refresh_tokens (expires_at)`,
+			`CREATE TABLE revoked_tokens (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				revoked_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at)`,
+		},
+	},
+	{
+		version: 5,
+		statements: []string{
+			// the users created before the roles were added are authors
+			`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'AUTHOR'`,
+		},
+	},
+	{
+		version: 6,
+		statements: []string{
+			// the replies are removed with their parent and the comments with their blog
+			`CREATE TABLE comments (
+				id TEXT PRIMARY KEY,
+				blog_id TEXT NOT NULL REFERENCES blogs (id) ON DELETE CASCADE,
+				parent_id TEXT NULL REFERENCES comments (id) ON DELETE CASCADE,
+				depth INTEGER NOT NULL,
+				content TEXT NOT NULL,
+				author_id TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NULL
+			)`,
+			`CREATE INDEX comments_blog_id_idx ON comments (blog_id, parent_id, created_at, id)`,
+			`CREATE INDEX comments_parent_id_idx ON comments (parent_id)`,
+		},
+	},
+	{
+		version: 7,
+		statements: []string{
+			// the tags are looked up by slug without reading the blogs
+			`CREATE TABLE blog_tags (
+				blog_id TEXT NOT NULL REFERENCES blogs (id) ON DELETE CASCADE,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (blog_id, tag)
+			)`,
+			`CREATE INDEX blog_tags_tag_idx ON blog_tags (tag, blog_id)`,
+		},
+	},
+	{
+		version: 8,
+		statements: []string{
+			// the blogs created before the statuses were added were visible to every user
+			`ALTER TABLE blogs ADD COLUMN status TEXT NOT NULL DEFAULT 'PUBLISHED'`,
+			`ALTER TABLE blogs ADD COLUMN publish_at TIMESTAMP NULL`,
+			`ALTER TABLE blogs ADD COLUMN published_at TIMESTAMP NULL`,
+			`UPDATE blogs SET published_at = created_at`,
+			`CREATE INDEX blogs_status_idx ON blogs (status, publish_at)`,
+		},
+	},
+	{
+		version: 9,
+		statements: []string{
+			// a version is assigned to a single revision of the blog
+			`CREATE TABLE blog_revisions (
+				blog_id TEXT NOT NULL REFERENCES blogs (id) ON DELETE CASCADE,
+				version INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				content TEXT NOT NULL,
+				editor_id TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+				created_at TIMESTAMP NOT NULL,
+				title_changed BOOLEAN NOT NULL,
+				lines_added INTEGER NOT NULL,
+				lines_removed INTEGER NOT NULL,
+				PRIMARY KEY (blog_id, version)
+			)`,
+		},
+	},
+	{
+		version: 10,
+		statements: []string{
+			// the deleted blogs stay in the trash until they are purged
+			`ALTER TABLE blogs ADD COLUMN deleted_at TIMESTAMP NULL`,
+			`CREATE INDEX blogs_deleted_at_idx ON blogs (deleted_at)`,
+		},
+	},
+	{
+		version: 11,
+		statements: []string{
+			// a user reacts at most once with each kind, the reactions are counted by blog
+			`CREATE TABLE blog_reactions (
+				blog_id TEXT NOT NULL REFERENCES blogs (id) ON DELETE CASCADE,
+				user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				kind TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (blog_id, user_id, kind)
+			)`,
+		},
+	},
+	{
+		version: 12,
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT ''`,
+			// a user follows another user at most once
+			`CREATE TABLE follows (
+				follower_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				followee_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (follower_id, followee_id)
+			)`,
+			`CREATE INDEX follows_follower_idx ON follows (follower_id, created_at)`,
+			`CREATE INDEX follows_followee_idx ON follows (followee_id, created_at)`,
+		},
+	},
+	{
+		version: 13,
+		statements: []string{
+			// the failures are counted by email or IP address, the emails may not be registered
+			`CREATE TABLE login_failures (
+				login_key TEXT PRIMARY KEY,
+				failure_count INTEGER NOT NULL,
+				last_failed_at TIMESTAMP NOT NULL,
+				locked_until TIMESTAMP NULL
+			)`,
+			// the records outlive the users they mention
+			`CREATE TABLE audit_records (
+				id TEXT PRIMARY KEY,
+				action TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				actor_id TEXT NOT NULL,
+				email TEXT NOT NULL,
+				ip TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX audit_records_created_idx ON audit_records (created_at)`,
+			`CREATE INDEX audit_records_user_idx ON audit_records (user_id, created_at)`,
+		},
+	},
+	{
+		version: 14,
+		// an email is registered once whatever its case, the duplicates stored before must be merged first
+		check: checkDuplicateEmails,
+		statements: []string{
+			`UPDATE users SET email = lower(trim(email))`,
+			`DROP INDEX users_email_idx`,
+			`CREATE UNIQUE INDEX users_email_idx ON users (lower(email))`,
+		},
+	},
+}
+
+// migrate applies the migrations that have not been applied yet
+func migrate(ctx context.Context, db *sqlDB) error {
+	// create the table that records the applied migrations
+	_, err := db.exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	// get the version of the last applied migration
+	var current int
+	if err := db.queryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return err
+	}
+
+	for _, migration := range sqlMigrations {
+		if migration.version <= current {
+			continue
+		}
+
+		if err := applyMigration(ctx, db, migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration applies a single migration inside a transaction
+func applyMigration(ctx context.Context, db *sqlDB, migration sqlMigration) error {
+	return db.withTx(ctx, func(tx *sqlTx) error {
+		if migration.check != nil {
+			if err := migration.check(ctx, tx); err != nil {
+				return fmt.Errorf("migration %d: %w", migration.version, err)
+			}
+		}
+
+		for _, statement := range migration.statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.exec(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", migration.version, sqlTime(time.Now()))
+		return err
+	})
+}
+
+// maxReportedDuplicates is the number of duplicated emails named by the migration error
+const maxReportedDuplicates = 10
+
+// checkDuplicateEmails refuses to migrate the users while several of them share an email
+// the emails are compared without their case and their surrounding spaces
+func checkDuplicateEmails(ctx context.Context, tx *sqlTx) error {
+	rows, err := tx.query(ctx, "SELECT lower(trim(email)), COUNT(*) FROM users GROUP BY lower(trim(email)) HAVING COUNT(*) > 1 ORDER BY 1")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var duplicates []string
+	for rows.Next() {
+		var (
+			email string
+			count int
+		)
+		if err := rows.Scan(&email, &count); err != nil {
+			return err
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s (%d users)", email, count))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	var reported []string = duplicates
+	if len(reported) > maxReportedDuplicates {
+		reported = reported[:maxReportedDuplicates]
+	}
+
+	return fmt.Errorf("%d emails are registered by several users, merge their accounts before upgrading: %s", len(duplicates), strings.Join(reported, ", "))
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// SQLReactionRepository stores the reactions to blogs in the "blog_reactions" table
+type SQLReactionRepository struct {
+	db *sqlDB
+}
+
+// AddReaction stores the reaction, it reports false when the user already reacted with the kind
+func (r *SQLReactionRepository) AddReaction(ctx context.Context, reaction Reaction) (bool, error) {
+	// the primary key rejects a second reaction of the same kind
+	result, err := r.db.exec(
+		ctx,
+		`INSERT INTO blog_reactions (blog_id, user_id, kind, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (blog_id, user_id, kind) DO NOTHING`,
+		reaction.BlogID,
+		reaction.UserID,
+		reaction.Kind,
+		sqlTime(reaction.CreatedAt),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// RemoveReaction removes the reaction of the user with the kind, it reports false when there was none
+func (r *SQLReactionRepository) RemoveReaction(ctx context.Context, blogID string, userID string, kind model.ReactionKind) (bool, error) {
+	result, err := r.db.exec(ctx, "DELETE FROM blog_reactions WHERE blog_id = ? AND user_id = ? AND kind = ?", blogID, userID, kind)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// CountReactions returns the number of reactions of each kind to the blog
+func (r *SQLReactionRepository) CountReactions(ctx context.Context, blogID string) (map[model.ReactionKind]int, error) {
+	rows, err := r.db.query(ctx, "SELECT kind, COUNT(*) FROM blog_reactions WHERE blog_id = ? GROUP BY kind", blogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts map[model.ReactionKind]int = make(map[model.ReactionKind]int)
+
+	for rows.Next() {
+		var (
+			kind  model.ReactionKind
+			count int
+		)
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, err
+		}
+		counts[kind] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// ListUserReactions returns the kinds the user reacted with to the blog
+func (r *SQLReactionRepository) ListUserReactions(ctx context.Context, blogID string, userID string) ([]model.ReactionKind, error) {
+	rows, err := r.db.query(ctx, "SELECT kind FROM blog_reactions WHERE blog_id = ? AND user_id = ?", blogID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	kinds := make([]model.ReactionKind, 0)
+
+	for rows.Next() {
+		var kind model.ReactionKind
+		if err := rows.Scan(&kind); err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+
+	return kinds, rows.Err()
+}
+
+// DeleteBlogReactions removes the reactions to the blog
+func (r *SQLReactionRepository) DeleteBlogReactions(ctx context.Context, blogID string) error {
+	_, err := r.db.exec(ctx, "DELETE FROM blog_reactions WHERE blog_id = ?", blogID)
+	return err
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// sqlRevisionQuery selects the revisions together with their editors
+const sqlRevisionQuery = `SELECT
+	r.blog_id, r.version, r.title, r.content, r.created_at,
+	r.title_changed, r.lines_added, r.lines_removed,
+	u.id, u.username
+	FROM blog_revisions r LEFT JOIN users u ON u.id = r.editor_id`
+
+// SQLRevisionRepository stores blog revisions in the "blog_revisions" table
+type SQLRevisionRepository struct {
+	db *sqlDB
+}
+
+// ListRevisions returns the revisions of the blog, newest first
+func (r *SQLRevisionRepository) ListRevisions(ctx context.Context, blogID string) ([]*model.BlogRevision, error) {
+	rows, err := r.db.query(ctx, sqlRevisionQuery+" WHERE r.blog_id = ? ORDER BY r.version DESC", blogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]*model.BlogRevision, 0)
+
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision returns the revision of the blog with the given version
+func (r *SQLRevisionRepository) GetRevision(ctx context.Context, blogID string, version int) (*model.BlogRevision, error) {
+	if !validObjectID(blogID) {
+		return nil, ErrInvalidID
+	}
+
+	revision, err := scanRevision(r.db.queryRow(ctx, sqlRevisionQuery+" WHERE r.blog_id = ? AND r.version = ?", blogID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// insertRevision stores the revision of a blog, only the ID of the editor is stored
+func insertRevision(ctx context.Context, db sqlExecutor, revision model.BlogRevision) error {
+	var editorID sql.NullString
+	if revision.Editor != nil {
+		editorID = sql.NullString{String: revision.Editor.ID, Valid: true}
+	}
+
+	_, err := db.exec(
+		ctx,
+		`INSERT INTO blog_revisions
+		(blog_id, version, title, content, editor_id, created_at, title_changed, lines_added, lines_removed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		revision.BlogID,
+		revision.Version,
+		revision.Title,
+		revision.Content,
+		editorID,
+		sqlTime(revision.CreatedAt),
+		revision.TitleChanged,
+		revision.LinesAdded,
+		revision.LinesRemoved,
+	)
+
+	return err
+}
+
+// DeleteBlogRevisions removes the revisions of the blog
+func (r *SQLRevisionRepository) DeleteBlogRevisions(ctx context.Context, blogID string) error {
+	_, err := r.db.exec(ctx, "DELETE FROM blog_revisions WHERE blog_id = ?", blogID)
+	return err
+}
+
+// scanRevision reads a revision and its editor from the row
+func scanRevision(row rowScanner) (*model.BlogRevision, error) {
+	var (
+		revision       *model.BlogRevision = &model.BlogRevision{}
+		editorID       sql.NullString
+		editorUsername sql.NullString
+	)
+
+	err := row.Scan(
+		&revision.BlogID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Content,
+		&revision.CreatedAt,
+		&revision.TitleChanged,
+		&revision.LinesAdded,
+		&revision.LinesRemoved,
+		&editorID,
+		&editorUsername,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the editor is optional, only its snapshot is read
+	if editorID.Valid {
+		revision.Editor = &model.User{
+			ID:       editorID.String,
+			Username: editorUsername.String,
+		}
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+func TestOpenSQLStore_MigrationsAreAppliedOnce(t *testing.T) {
+	var (
+		ctx context.Context = context.Background()
+		dsn string          = filepath.Join(t.TempDir(), "blog.db")
+	)
+
+	store, err := OpenSQLStore(ctx, SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := store.Users().CreateUser(ctx, User{Username: "test", Email: "test@test.com", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close(ctx)
+
+	// opening the same database again must keep the schema and the data
+	store, err = OpenSQLStore(ctx, SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	user, err := store.Users().GetUserByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Email != "test@test.com" {
+		t.Fatalf("unexpected user: %v", user)
+	}
+}
+
+func TestOpenSQLStore_LoadsSearchIndex(t *testing.T) {
+	var (
+		ctx context.Context = context.Background()
+		dsn string          = filepath.Join(t.TempDir(), "blog.db")
+	)
+
+	store, err := OpenSQLStore(ctx, SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "Searchable", Status: model.BlogStatusPublished, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close(ctx)
+
+	// the index is rebuilt from the table
+	store, err = OpenSQLStore(ctx, SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	hits, err := store.Blogs().SearchBlogs(ctx, "searchable", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("expected one hit, got %v", hits)
+	}
+}
+
+func TestSQLBlogRepository_WritesWithTags(t *testing.T) {
+	var ctx context.Context = context.Background()
+
+	store, err := OpenSQLStore(ctx, SQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	// a repeated tag breaks the key of the tags, so the blog must not be stored either
+	if _, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "broken", Tags: []string{"go", "go"}, CreatedAt: time.Now()}); err == nil {
+		t.Fatal("expected the repeated tag to be refused")
+	}
+
+	var count int
+	if err := store.db.queryRow(ctx, "SELECT COUNT(*) FROM blogs").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected the blog to be rolled back, got %d blogs", count)
+	}
+
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "kept", Tags: []string{"go"}, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Blogs().UpdateBlog(ctx, blog.ID, nil, BlogUpdate{Title: "changed", Tags: []string{"sql", "sql"}, UpdatedAt: time.Now()}); err == nil {
+		t.Fatal("expected the repeated tag to be refused")
+	}
+
+	stored, err := store.Blogs().GetBlogByID(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "kept" || len(stored.Tags) != 1 || stored.Tags[0] != "go" {
+		t.Fatalf("expected the update to be rolled back, got %+v", stored)
+	}
+}
+
+func TestSQLBlogRepository_WritesWithRevision(t *testing.T) {
+	var ctx context.Context = context.Background()
+
+	store, err := OpenSQLStore(ctx, SQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "kept", Content: "kept", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an unknown editor breaks the key of the revision, so the edit must not be stored either
+	update := BlogUpdate{Title: "changed", Content: "changed", UpdatedAt: time.Now(), Editor: &model.User{ID: newObjectID()}}
+	if _, err := store.Blogs().UpdateBlog(ctx, blog.ID, nil, update); err == nil {
+		t.Fatal("expected the revision to be refused")
+	}
+
+	stored, err := store.Blogs().GetBlogByID(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "kept" || stored.Content != "kept" || stored.UpdatedAt != nil {
+		t.Fatalf("expected the update to be rolled back, got %+v", stored)
+	}
+
+	if revisions, err := store.Revisions().ListRevisions(ctx, blog.ID); err != nil || len(revisions) != 1 {
+		t.Fatalf("expected only the first revision, got %+v (%v)", revisions, err)
+	}
+}
+
+func TestOpenSQLStore_RefusesDuplicateEmails(t *testing.T) {
+	var (
+		ctx context.Context = context.Background()
+		dsn string          = filepath.Join(t.TempDir(), "blog.db")
+	)
+
+	conn, err := sql.Open(SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var db *sqlDB = &sqlDB{DB: conn, dialect: SQLite}
+
+	// the users were registered before the emails were unique
+	if _, err := db.exec(ctx, "CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at TIMESTAMP NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range sqlMigrations[:13] {
+		if err := applyMigration(ctx, db, migration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, email := range []string{"alice@test.com", "Alice@test.com ", "bob@test.com"} {
+		if _, err := db.exec(ctx, "INSERT INTO users (id, username, email, password, role, created_at) VALUES (?, ?, ?, '', 'AUTHOR', ?)", newObjectID(), fmt.Sprint("user", i), email, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+
+	// the upgrade stops and names the emails to merge
+	_, err = OpenSQLStore(ctx, SQLite, dsn)
+	if err == nil || !strings.Contains(err.Error(), "merge their accounts") || !strings.Contains(err.Error(), "alice@test.com (2 users)") {
+		t.Fatalf("expected the duplicated emails to be reported, got %v", err)
+	}
+
+	// once the accounts are merged the emails are stored in lower case
+	if conn, err = sql.Open(SQLite, dsn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "DELETE FROM users WHERE email = 'alice@test.com'"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	store, err := OpenSQLStore(ctx, SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	user, err := store.Users().GetUserByEmail(ctx, "alice@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "alice@test.com" {
+		t.Fatalf("expected the email to be normalized, got %q", user.Email)
+	}
+}
+
+func TestOpenSQLStore_CascadesOnEveryConnection(t *testing.T) {
+	var (
+		ctx context.Context = context.Background()
+		dsn string          = filepath.Join(t.TempDir(), "blog.db")
+		now time.Time       = time.Now()
+	)
+
+	store, err := OpenSQLStore(ctx, SQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close(ctx)
+
+	// every statement runs on a new connection of the pool
+	store.db.SetMaxIdleConns(0)
+
+	userID, err := store.Users().CreateUser(ctx, User{Username: "test", Email: "test@test.com", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower, err := store.Users().CreateUser(ctx, User{Username: "follower", Email: "follower@test.com", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the blog is stored with its first revision
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "title", Tags: []string{"go"}, Author: &model.User{ID: userID}, CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment, err := store.Comments().CreateComment(ctx, model.Comment{BlogID: blog.ID, Content: "comment", Author: &model.User{ID: follower}, CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Comments().CreateComment(ctx, model.Comment{BlogID: blog.ID, ParentID: &comment.ID, Depth: 1, Content: "reply", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reactions().AddReaction(ctx, Reaction{BlogID: blog.ID, UserID: userID, Kind: model.ReactionKindLike, CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Follows().Follow(ctx, Follow{FollowerID: follower, FolloweeID: userID, CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Tokens().CreateRefreshToken(ctx, RefreshToken{ID: "token", FamilyID: "family", UserID: userID, TokenHash: "hash", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rows of the blog and of the user are removed with them
+	if _, err := store.db.exec(ctx, "DELETE FROM blogs WHERE id = ?", blog.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.exec(ctx, "DELETE FROM users WHERE id = ?", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"comments", "blog_tags", "blog_revisions", "blog_reactions", "follows", "refresh_tokens"} {
+		var count int
+		if err := store.db.queryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("expected the rows of %s to be removed, got %d", table, count)
+		}
+	}
+}
+
+func TestSQLDB_Rebind(t *testing.T) {
+	var db *sqlDB = &sqlDB{dialect: Postgres}
+
+	if query := db.rebind("SELECT * FROM blogs WHERE id = ? AND author_id = ?"); query != "SELECT * FROM blogs WHERE id = $1 AND author_id = $2" {
+		t.Fatalf("unexpected query: %s", query)
+	}
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// sqlTokenColumns lists the columns read into a refresh token
+const sqlTokenColumns = "id, family_id, user_id, token_hash, created_at, expires_at, revoked_at, replaced_by"
+
+// SQLTokenRepository stores refresh tokens in the "refresh_tokens" table
+type SQLTokenRepository struct {
+	db *sqlDB
+}
+
+// CreateRefreshToken stores a new refresh token and returns its ID
+func (r *SQLTokenRepository) CreateRefreshToken(ctx context.Context, token RefreshToken) (string, error) {
+	token.ID = newObjectID()
+	if token.FamilyID == "" {
+		token.FamilyID = token.ID
+	}
+
+	_, err := r.db.exec(
+		ctx,
+		"INSERT INTO refresh_tokens ("+sqlTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		token.ID,
+		token.FamilyID,
+		token.UserID,
+		token.TokenHash,
+		sqlTime(token.CreatedAt),
+		sqlTime(token.ExpiresAt),
+		sqlNullTime(token.RevokedAt),
+		token.ReplacedBy,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return token.ID, nil
+}
+
+// GetRefreshTokenByHash returns the refresh token with the given hash
+func (r *SQLTokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	var (
+		token     *RefreshToken = &RefreshToken{}
+		revokedAt sql.NullTime
+	)
+
+	err := r.db.queryRow(ctx, "SELECT "+sqlTokenColumns+" FROM refresh_tokens WHERE token_hash = ?", hash).Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&revokedAt,
+		&token.ReplacedBy,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	token.RevokedAt = timePointer(revokedAt)
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes a token replaced by another token of its family
+func (r *SQLTokenRepository) RotateRefreshToken(ctx context.Context, id string, replacedBy string, at time.Time) error {
+	// only a token that is still valid can be rotated
+	result, err := r.db.exec(
+		ctx,
+		"UPDATE refresh_tokens SET revoked_at = ?, replaced_by = ? WHERE id = ? AND revoked_at IS NULL",
+		sqlTime(at),
+		replacedBy,
+		id,
+	)
+
+	return checkAffected(result, err)
+}
+
+// RevokeTokenFamily revokes every token of the family
+func (r *SQLTokenRepository) RevokeTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	_, err := r.db.exec(
+		ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		sqlTime(at),
+		familyID,
+	)
+
+	return err
+}
+
+// RevokeAccessToken records the ID of an access token that must no longer be accepted
+func (r *SQLTokenRepository) RevokeAccessToken(ctx context.Context, token RevokedToken) error {
+	_, err := r.db.exec(
+		ctx,
+		`INSERT INTO revoked_tokens (id, user_id, revoked_at, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET revoked_at = excluded.revoked_at, expires_at = excluded.expires_at`,
+		token.ID,
+		token.UserID,
+		sqlTime(token.RevokedAt),
+		sqlTime(token.ExpiresAt),
+	)
+
+	return err
+}
+
+// RevokeUserSessions revokes every refresh token of the user
+// and every access token issued to the user until the given time
+func (r *SQLTokenRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time, expiresAt time.Time) error {
+	_, err := r.db.exec(
+		ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		sqlTime(at),
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return r.RevokeAccessToken(ctx, RevokedToken{ID: userRevocationID(userID), UserID: userID, RevokedAt: at, ExpiresAt: expiresAt})
+}
+
+// IsAccessTokenRevoked reports whether the access token has been revoked
+func (r *SQLTokenRepository) IsAccessTokenRevoked(ctx context.Context, id string, userID string, issuedAt time.Time) (bool, error) {
+	rows, err := r.db.query(
+		ctx,
+		"SELECT id, user_id, revoked_at, expires_at FROM revoked_tokens WHERE id IN (?, ?)",
+		id,
+		userRevocationID(userID),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revoked RevokedToken
+		if err := rows.Scan(&revoked.ID, &revoked.UserID, &revoked.RevokedAt, &revoked.ExpiresAt); err != nil {
+			return false, err
+		}
+		if revoked.revokes(id, userID, issuedAt) {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// PruneTokens removes the refresh tokens and revocations that expired before the given time
+func (r *SQLTokenRepository) PruneTokens(ctx context.Context, before time.Time) error {
+	if _, err := r.db.exec(ctx, "DELETE FROM refresh_tokens WHERE expires_at < ?", sqlTime(before)); err != nil {
+		return err
+	}
+
+	_, err := r.db.exec(ctx, "DELETE FROM revoked_tokens WHERE expires_at < ?", sqlTime(before))
+	return err
+}
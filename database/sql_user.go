@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// sqlUserColumns lists the columns read into a user
+const sqlUserColumns = "id, username, email, password, role, created_at, updated_at, display_name, bio, avatar_url"
+
+// SQLUserRepository stores users in the "users" table
+type SQLUserRepository struct {
+	db *sqlDB
+}
+
+// CreateUser stores a new user and returns its ID
+func (r *SQLUserRepository) CreateUser(ctx context.Context, user User) (string, error) {
+	var id string = newObjectID()
+
+	_, err := r.db.exec(
+		ctx,
+		"INSERT INTO users ("+sqlUserColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id,
+		user.Username,
+		user.Email,
+		user.PasswordHash,
+		user.Role,
+		sqlTime(user.CreatedAt),
+		sqlNullTime(user.UpdatedAt),
+		user.DisplayName,
+		user.Bio,
+		user.AvatarURL,
+	)
+	if isUniqueViolation(err) {
+		return "", ErrDuplicate
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// GetUserByID returns the user with the given ID
+func (r *SQLUserRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	return r.findOne(ctx, "SELECT "+sqlUserColumns+" FROM users WHERE id = ?", id)
+}
+
+// GetUsersByIDs returns the users with the given IDs in any order
+// the IDs without a user are left out
+func (r *SQLUserRepository) GetUsersByIDs(ctx context.Context, ids []string) ([]*User, error) {
+	users := make([]*User, 0, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	var (
+		placeholders []string      = make([]string, 0, len(ids))
+		args         []interface{} = make([]interface{}, 0, len(ids))
+	)
+
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	rows, err := r.db.query(ctx, "SELECT "+sqlUserColumns+" FROM users WHERE id IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// GetUserByEmail returns the user with the given email
+func (r *SQLUserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	// the emails are compared without their case, like the unique index
+	return r.findOne(ctx, "SELECT "+sqlUserColumns+" FROM users WHERE lower(email) = lower(?) ORDER BY created_at, id LIMIT 1", email)
+}
+
+// UpdateUserRole changes the role of a user and returns the stored record
+func (r *SQLUserRepository) UpdateUserRole(ctx context.Context, id string, role model.Role, updatedAt time.Time) (*User, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	result, err := r.db.exec(ctx, "UPDATE users SET role = ?, updated_at = ? WHERE id = ?", role, sqlTime(updatedAt), id)
+	if err := checkAffected(result, err); err != nil {
+		return nil, err
+	}
+
+	return r.GetUserByID(ctx, id)
+}
+
+// UpdateUserProfile replaces the profile of a user and returns the stored record
+func (r *SQLUserRepository) UpdateUserProfile(ctx context.Context, id string, profile UserProfile, updatedAt time.Time) (*User, error) {
+	if !validObjectID(id) {
+		return nil, ErrInvalidID
+	}
+
+	result, err := r.db.exec(
+		ctx,
+		"UPDATE users SET display_name = ?, bio = ?, avatar_url = ?, updated_at = ? WHERE id = ?",
+		profile.DisplayName,
+		profile.Bio,
+		profile.AvatarURL,
+		sqlTime(updatedAt),
+		id,
+	)
+	if err := checkAffected(result, err); err != nil {
+		return nil, err
+	}
+
+	return r.GetUserByID(ctx, id)
+}
+
+// findOne returns the user returned by the query
+func (r *SQLUserRepository) findOne(ctx context.Context, query string, args ...interface{}) (*User, error) {
+	user, err := scanUser(r.db.queryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// scanUser reads a user from a row of the user columns
+func scanUser(row rowScanner) (*User, error) {
+	var (
+		user      *User = &User{}
+		updatedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Role,
+		&user.CreatedAt,
+		&updatedAt,
+		&user.DisplayName,
+		&user.Bio,
+		&user.AvatarURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.UpdatedAt = timePointer(updatedAt)
+
+	return user, nil
+}
@@ -0,0 +1,1400 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/diff"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// forEachStore runs the test against every backend that needs no outside service
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		store, err := OpenSQLStore(context.Background(), SQLite, ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close(context.Background())
+
+		test(t, store)
+	})
+}
+
+func TestBlogRepository_SortedByCreatedAt(t *testing.T) {
+	forEachStore(t, testBlogRepositorySortedByCreatedAt)
+}
+
+func testBlogRepositorySortedByCreatedAt(t *testing.T, store Store) {
+	var (
+		ctx   context.Context = context.Background()
+		repo  BlogRepository  = store.Blogs()
+		now   time.Time       = time.Now()
+		older                 = model.Blog{Title: "older", CreatedAt: now.Add(-time.Hour)}
+		newer                 = model.Blog{Title: "newer", CreatedAt: now}
+	)
+
+	for _, blog := range []model.Blog{older, newer} {
+		if _, err := repo.CreateBlog(ctx, blog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blogs, err := repo.ListBlogs(ctx, BlogQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blogs) != 2 || blogs[0].Title != "newer" || blogs[1].Title != "older" {
+		t.Fatalf("unexpected order: %v", blogs)
+	}
+}
+
+func TestBlogRepository_OwnershipCheck(t *testing.T) {
+	forEachStore(t, testBlogRepositoryOwnershipCheck)
+}
+
+func testBlogRepositoryOwnershipCheck(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo BlogRepository  = store.Blogs()
+	)
+
+	authorID, err := store.Users().CreateUser(ctx, User{Username: "author", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := model.User{ID: authorID}
+
+	blog, err := repo.CreateBlog(ctx, model.Blog{Title: "title", Author: &author, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var otherID string = newObjectID()
+
+	_, err = repo.UpdateBlog(ctx, blog.ID, &otherID, BlogUpdate{Title: "changed"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := repo.TrashBlog(ctx, blog.ID, &otherID, time.Now()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := repo.TrashBlog(ctx, "not-an-id", &author.ID, time.Now()); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("expected ErrInvalidID, got %v", err)
+	}
+
+	// without an author, any blog can be changed
+	updated, err := repo.UpdateBlog(ctx, blog.ID, nil, BlogUpdate{Title: "changed", UpdatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "changed" || authorIDOf(updated) == nil || *authorIDOf(updated) != author.ID {
+		t.Fatalf("unexpected blog: %+v", updated)
+	}
+
+	if err := repo.TrashBlog(ctx, blog.ID, &author.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_ConcurrentWrites(t *testing.T) {
+	forEachStore(t, testStoreConcurrentWrites)
+}
+
+func testStoreConcurrentWrites(t *testing.T, store Store) {
+	var (
+		ctx context.Context = context.Background()
+		wg  sync.WaitGroup
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(email string) {
+			defer wg.Done()
+			id, err := store.Users().CreateUser(ctx, User{Email: email, CreatedAt: time.Now()})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := store.Users().GetUserByID(ctx, id); err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprintf("test%d@test.com", i))
+	}
+	wg.Wait()
+
+	if err := store.Drop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Users().GetUserByEmail(ctx, "test0@test.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after drop, got %v", err)
+	}
+}
+
+func TestBlogRepository_ListBlogs(t *testing.T) {
+	forEachStore(t, testBlogRepositoryListBlogs)
+}
+
+func testBlogRepositoryListBlogs(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo BlogRepository  = store.Blogs()
+		now  time.Time       = time.Now().Truncate(time.Millisecond)
+	)
+
+	// two blogs share the creation time so the ID breaks the tie
+	for i, offset := range []time.Duration{0, time.Minute, time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		blog := model.Blog{Title: string(rune('a' + i)), CreatedAt: now.Add(offset)}
+		if _, err := repo.CreateBlog(ctx, blog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := repo.ListBlogs(ctx, BlogQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("expected 5 blogs, got %d", len(all))
+	}
+
+	// walk through the blogs two at a time
+	var (
+		seen  []string
+		after *BlogCursor
+	)
+	for {
+		page, err := repo.ListBlogs(ctx, BlogQuery{After: after, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, blog := range page {
+			seen = append(seen, blog.ID)
+		}
+		cursor := CursorOf(page[len(page)-1])
+		after = &cursor
+	}
+
+	if len(seen) != len(all) {
+		t.Fatalf("expected %d blogs, got %d", len(all), len(seen))
+	}
+	for i := range all {
+		if all[i].ID != seen[i] {
+			t.Fatalf("unexpected order at %d", i)
+		}
+	}
+
+	// read backwards from the last blog
+	last := CursorOf(all[4])
+	page, err := repo.ListBlogs(ctx, BlogQuery{Ascending: true, After: &last, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != all[3].ID || page[1].ID != all[2].ID {
+		t.Fatalf("unexpected backward page")
+	}
+
+	// both bounds are exclusive
+	first := CursorOf(all[0])
+	page, err = repo.ListBlogs(ctx, BlogQuery{After: &first, Before: &last})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 || page[0].ID != all[1].ID || page[2].ID != all[3].ID {
+		t.Fatalf("unexpected bounded page")
+	}
+}
+
+func TestBlogRepository_FilterAndSort(t *testing.T) {
+	forEachStore(t, testBlogRepositoryFilterAndSort)
+}
+
+func testBlogRepositoryFilterAndSort(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo BlogRepository  = store.Blogs()
+		now  time.Time       = time.Now().Truncate(time.Millisecond)
+	)
+
+	authorID, err := store.Users().CreateUser(ctx, User{Username: "author", Email: "author@test.com", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherID, err := store.Users().CreateUser(ctx, User{Username: "other", Email: "other@test.com", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create blogs, the odd ones are edited later
+	var titles = []string{"Go 100%", "go.mod", "Rust", "Haskell", "gopher"}
+	for i, title := range titles {
+		blog := model.Blog{Title: title, Author: &model.User{ID: otherID}, CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		if i < 2 {
+			blog.Author = &model.User{ID: authorID}
+		}
+		created, err := repo.CreateBlog(ctx, blog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 1 {
+			update := BlogUpdate{Title: title, UpdatedAt: now.Add(time.Hour - time.Duration(i)*time.Minute)}
+			if _, err := repo.UpdateBlog(ctx, created.ID, authorIDOf(created), update); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var edited = true
+	var cases = []struct {
+		name   string
+		query  BlogQuery
+		titles []string
+	}{
+		{"author", BlogQuery{Filter: BlogFilter{AuthorID: authorID}}, []string{"go.mod", "Go 100%"}},
+		{"title contains", BlogQuery{Filter: BlogFilter{TitleContains: "GO"}}, []string{"gopher", "go.mod", "Go 100%"}},
+		{"wildcards are literal", BlogQuery{Filter: BlogFilter{TitleContains: "0%"}}, []string{"Go 100%"}},
+		{"regexp is literal", BlogQuery{Filter: BlogFilter{TitleContains: "o.m"}}, []string{"go.mod"}},
+		{"created range", BlogQuery{Filter: BlogFilter{CreatedAfter: timeOf(now), CreatedBefore: timeOf(now.Add(3 * time.Minute))}}, []string{"Rust", "go.mod"}},
+		{"edited", BlogQuery{Filter: BlogFilter{Edited: &edited}}, []string{"Haskell", "go.mod"}},
+		{"updated range", BlogQuery{Filter: BlogFilter{UpdatedAfter: timeOf(now.Add(58 * time.Minute))}}, []string{"go.mod"}},
+		{"title ascending", BlogQuery{SortBy: SortByTitle, Ascending: true}, []string{"Go 100%", "Haskell", "Rust", "go.mod", "gopher"}},
+		{"updated descending", BlogQuery{SortBy: SortByUpdatedAt}, []string{"go.mod", "Haskell", "gopher", "Rust", "Go 100%"}},
+		{"updated ascending", BlogQuery{SortBy: SortByUpdatedAt, Ascending: true}, []string{"Go 100%", "Rust", "gopher", "Haskell", "go.mod"}},
+	}
+
+	for _, c := range cases {
+		blogs, err := repo.ListBlogs(ctx, c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// blogs with the same update time are sorted by ID, which follows the creation order
+		if got := titlesOf(blogs); strings.Join(got, ",") != strings.Join(c.titles, ",") {
+			t.Errorf("%s: expected %v, got %v", c.name, c.titles, got)
+		}
+	}
+
+	// walk through the blogs sorted by the update time in both directions
+	for _, ascending := range []bool{false, true} {
+		all, err := repo.ListBlogs(ctx, BlogQuery{SortBy: SortByUpdatedAt, Ascending: ascending})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			seen  []string
+			after *BlogCursor
+		)
+		for {
+			page, err := repo.ListBlogs(ctx, BlogQuery{SortBy: SortByUpdatedAt, Ascending: ascending, After: after, Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			seen = append(seen, titlesOf(page)...)
+			cursor := CursorOf(page[len(page)-1])
+			after = &cursor
+		}
+
+		if strings.Join(seen, ",") != strings.Join(titlesOf(all), ",") {
+			t.Errorf("ascending %v: expected %v, got %v", ascending, titlesOf(all), seen)
+		}
+	}
+}
+
+// authorIDOf returns the ID of the author of the blog
+func authorIDOf(blog *model.Blog) *string {
+	if blog.Author == nil {
+		return nil
+	}
+	return &blog.Author.ID
+}
+
+// titlesOf returns the titles of the blogs
+func titlesOf(blogs []*model.Blog) []string {
+	var titles []string
+	for _, blog := range blogs {
+		titles = append(titles, blog.Title)
+	}
+	return titles
+}
+
+// timeOf returns a pointer to the time
+func timeOf(t time.Time) *time.Time {
+	return &t
+}
+
+func TestBlogRepository_SearchBlogs(t *testing.T) {
+	forEachStore(t, testBlogRepositorySearchBlogs)
+}
+
+func testBlogRepositorySearchBlogs(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo BlogRepository  = store.Blogs()
+	)
+
+	authorID, err := store.Users().CreateUser(ctx, User{Username: "author", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var author *model.User = &model.User{ID: authorID}
+
+	golang, err := repo.CreateBlog(ctx, model.Blog{Title: "Golang tips", Content: "channels and goroutines", Author: author, Status: model.BlogStatusPublished, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateBlog(ctx, model.Blog{Title: "Cooking", Content: "golang is not a recipe", Author: author, Status: model.BlogStatusPublished, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := repo.SearchBlogs(ctx, "golang", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Blog.ID != golang.ID || hits[0].Score <= hits[1].Score {
+		t.Fatalf("unexpected hits: %v", hits)
+	}
+
+	// pages of hits
+	hits, err = repo.SearchBlogs(ctx, "golang", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Blog.Title != "Cooking" {
+		t.Fatalf("unexpected second page: %v", hits)
+	}
+
+	// edits and deletions are visible to the search
+	if _, err := repo.UpdateBlog(ctx, golang.ID, &authorID, BlogUpdate{Title: "Rust tips", Content: "ownership", UpdatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := repo.SearchBlogs(ctx, "ownership", 0, 10); len(hits) != 1 {
+		t.Fatalf("expected the edited blog, got %v", hits)
+	}
+	if err := repo.TrashBlog(ctx, golang.ID, &authorID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := repo.SearchBlogs(ctx, "ownership", 0, 10); len(hits) != 0 {
+		t.Fatalf("expected no hits, got %v", hits)
+	}
+}
+
+func TestTokenRepository_Rotation(t *testing.T) {
+	forEachStore(t, testTokenRepositoryRotation)
+}
+
+func testTokenRepositoryRotation(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo TokenRepository = store.Tokens()
+		now  time.Time       = time.Now()
+	)
+
+	userID, err := store.Users().CreateUser(ctx, User{Username: "user", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token without a family starts a new family
+	firstID, err := repo.CreateRefreshToken(ctx, RefreshToken{UserID: userID, TokenHash: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := repo.GetRefreshTokenByHash(ctx, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != firstID || first.FamilyID != firstID || first.RevokedAt != nil {
+		t.Fatalf("unexpected token: %+v", first)
+	}
+
+	secondID, err := repo.CreateRefreshToken(ctx, RefreshToken{FamilyID: first.FamilyID, UserID: userID, TokenHash: "second", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token can only be rotated once
+	if err := repo.RotateRefreshToken(ctx, firstID, secondID, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RotateRefreshToken(ctx, firstID, secondID, now); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	first, err = repo.GetRefreshTokenByHash(ctx, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.RevokedAt == nil || first.ReplacedBy != secondID {
+		t.Fatalf("expected the token to be replaced: %+v", first)
+	}
+
+	// revoking the family revokes the tokens issued after the rotation
+	if err := repo.RevokeTokenFamily(ctx, first.FamilyID, now); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := repo.GetRefreshTokenByHash(ctx, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RevokedAt == nil {
+		t.Fatal("expected the family to be revoked")
+	}
+
+	if _, err := repo.GetRefreshTokenByHash(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTokenRepository_Revocation(t *testing.T) {
+	forEachStore(t, testTokenRepositoryRevocation)
+}
+
+func testTokenRepositoryRevocation(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo TokenRepository = store.Tokens()
+		now  time.Time       = time.Now()
+	)
+
+	userID, err := store.Users().CreateUser(ctx, User{Username: "user", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a single access token is revoked by its ID
+	if err := repo.RevokeAccessToken(ctx, RevokedToken{ID: "jti", UserID: userID, RevokedAt: now, ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, check := range []struct {
+		id       string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{id: "jti", issuedAt: now.Add(-time.Minute), revoked: true},
+		{id: "other", issuedAt: now.Add(-time.Minute), revoked: false},
+	} {
+		revoked, err := repo.IsAccessTokenRevoked(ctx, check.id, userID, check.issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != check.revoked {
+			t.Fatalf("expected %s revoked=%v", check.id, check.revoked)
+		}
+	}
+
+	// revoking the sessions covers the tokens issued until then
+	if _, err := repo.CreateRefreshToken(ctx, RefreshToken{UserID: userID, TokenHash: "hash", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RevokeUserSessions(ctx, userID, now, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, check := range []struct {
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{issuedAt: now.Add(-time.Second), revoked: true},
+		{issuedAt: now.Add(time.Second), revoked: false},
+	} {
+		revoked, err := repo.IsAccessTokenRevoked(ctx, "other", userID, check.issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != check.revoked {
+			t.Fatalf("expected token issued at %v revoked=%v", check.issuedAt, check.revoked)
+		}
+	}
+
+	token, err := repo.GetRefreshTokenByHash(ctx, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.RevokedAt == nil {
+		t.Fatal("expected the refresh token to be revoked")
+	}
+
+	// the revocations are pruned once the access tokens have expired
+	if err := repo.PruneTokens(ctx, now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := repo.IsAccessTokenRevoked(ctx, "jti", userID, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked {
+		t.Fatal("expected the revocation to be pruned")
+	}
+
+	if _, err := repo.GetRefreshTokenByHash(ctx, "hash"); err != nil {
+		t.Fatalf("expected the refresh token to be kept until it expires, got %v", err)
+	}
+}
+
+func TestBlogRepository_AuthorSnapshot(t *testing.T) {
+	forEachStore(t, testBlogRepositoryAuthorSnapshot)
+}
+
+func testBlogRepositoryAuthorSnapshot(t *testing.T, store Store) {
+	var (
+		ctx   context.Context = context.Background()
+		email string          = "author@test.com"
+	)
+
+	authorID, err := store.Users().CreateUser(ctx, User{Username: "author", Email: email, PasswordHash: "hash", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	author, err := store.Users().GetUserByID(ctx, authorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if author.PasswordHash != "hash" {
+		t.Fatalf("expected the password hash to be stored, got %q", author.PasswordHash)
+	}
+
+	// the blogs only keep the ID and the username of their author
+	created, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "title", Author: author.Model(), CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blog, err := store.Blogs().GetBlogByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blog.Author == nil || blog.Author.ID != authorID || blog.Author.Username != "author" || blog.Author.Email != nil {
+		t.Fatalf("unexpected author: %+v", blog.Author)
+	}
+}
+
+func TestCommentRepository_Threads(t *testing.T) {
+	forEachStore(t, testCommentRepositoryThreads)
+}
+
+func testCommentRepositoryThreads(t *testing.T, store Store) {
+	var (
+		ctx  context.Context   = context.Background()
+		repo CommentRepository = store.Comments()
+		now  time.Time         = time.Now()
+	)
+
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "title", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var top []*model.Comment
+	for i := 0; i < 3; i++ {
+		comment, err := repo.CreateComment(ctx, model.Comment{BlogID: blog.ID, Content: "top", CreatedAt: now.Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		top = append(top, comment)
+	}
+
+	reply, err := repo.CreateComment(ctx, model.Comment{BlogID: blog.ID, ParentID: &top[0].ID, Depth: 1, Content: "reply", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateComment(ctx, model.Comment{BlogID: blog.ID, ParentID: &reply.ID, Depth: 2, Content: "nested", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the top-level comments are listed oldest first, page by page
+	var after CommentCursor = CommentCursorOf(top[0])
+	page, err := repo.ListComments(ctx, CommentQuery{BlogID: blog.ID, After: &after, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != top[1].ID {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+
+	replies, err := repo.ListComments(ctx, CommentQuery{BlogID: blog.ID, ParentID: &top[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0].ID != reply.ID || *replies[0].ParentID != top[0].ID {
+		t.Fatalf("unexpected replies: %+v", replies)
+	}
+
+	if count, err := repo.CountComments(ctx, blog.ID); err != nil || count != 5 {
+		t.Fatalf("expected 5 comments, got %d (%v)", count, err)
+	}
+
+	if count, err := repo.CountReplies(ctx, top[0].ID); err != nil || count != 1 {
+		t.Fatalf("expected 1 reply, got %d (%v)", count, err)
+	}
+
+	// the replies are removed with their parent
+	if err := repo.DeleteComment(ctx, top[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := repo.CountComments(ctx, blog.ID); err != nil || count != 2 {
+		t.Fatalf("expected 2 comments, got %d (%v)", count, err)
+	}
+	if _, err := repo.GetCommentByID(ctx, reply.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the reply to be removed, got %v", err)
+	}
+
+	if err := repo.DeleteBlogComments(ctx, blog.ID); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := repo.CountComments(ctx, blog.ID); err != nil || count != 0 {
+		t.Fatalf("expected no comments, got %d (%v)", count, err)
+	}
+}
+
+func TestBlogRepository_Tags(t *testing.T) {
+	forEachStore(t, testBlogRepositoryTags)
+}
+
+func testBlogRepositoryTags(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo BlogRepository  = store.Blogs()
+		now  time.Time       = time.Now()
+	)
+
+	first, err := repo.CreateBlog(ctx, model.Blog{Title: "first", Tags: []string{"go", "graphql"}, Status: model.BlogStatusPublished, CreatedAt: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateBlog(ctx, model.Blog{Title: "second", Tags: []string{"go"}, Status: model.BlogStatusPublished, CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	blogs, err := repo.ListBlogs(ctx, BlogQuery{Filter: BlogFilter{Tag: "go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(titlesOf(blogs), ","); got != "second,first" {
+		t.Fatalf("unexpected blogs: %s", got)
+	}
+
+	// the tags are replaced by an update
+	updated, err := repo.UpdateBlog(ctx, first.ID, nil, BlogUpdate{Title: "first", Tags: []string{"sql"}, UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(updated.Tags, ",") != "sql" {
+		t.Fatalf("unexpected tags: %v", updated.Tags)
+	}
+
+	counts, err := repo.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0].Tag != "go" || counts[0].Count != 1 || counts[1].Tag != "sql" {
+		t.Fatalf("unexpected tag counts: %+v", counts)
+	}
+}
+
+func TestBlogRepository_Status(t *testing.T) {
+	forEachStore(t, testBlogRepositoryStatus)
+}
+
+func testBlogRepositoryStatus(t *testing.T, store Store) {
+	var (
+		ctx       context.Context = context.Background()
+		repo      BlogRepository  = store.Blogs()
+		now       time.Time       = time.Now()
+		publishAt time.Time       = now.Add(time.Hour)
+	)
+
+	authorID, err := store.Users().CreateUser(ctx, User{Username: "author", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var author *model.User = &model.User{ID: authorID}
+
+	if _, err := repo.CreateBlog(ctx, model.Blog{Title: "published", Status: model.BlogStatusPublished, CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	draft, err := repo.CreateBlog(ctx, model.Blog{Title: "draft", Content: "scheduled", Author: author, Status: model.BlogStatusDraft, CreatedAt: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the drafts are only visible to their author
+	for viewer, expected := range map[string]string{"": "published", authorID: "published,draft"} {
+		blogs, err := repo.ListBlogs(ctx, BlogQuery{Filter: BlogFilter{Visibility: &BlogVisibility{AuthorID: viewer}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(titlesOf(blogs), ","); got != expected {
+			t.Fatalf("expected %s for viewer %q, got %s", expected, viewer, got)
+		}
+	}
+
+	if _, err := repo.UpdateBlogStatus(ctx, draft.ID, nil, BlogStatusUpdate{Status: model.BlogStatusScheduled, PublishAt: &publishAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blog is not published before its time
+	if published, err := repo.PublishScheduledBlogs(ctx, now); err != nil || len(published) != 0 {
+		t.Fatalf("expected no blog to be published, got %d (%v)", len(published), err)
+	}
+
+	published, err := repo.PublishScheduledBlogs(ctx, publishAt)
+	if err != nil || len(published) != 1 || published[0].Blog.ID != draft.ID || published[0].Blog.Status != model.BlogStatusPublished || !published[0].First {
+		t.Fatalf("expected the blog to be published for the first time, got %+v (%v)", published, err)
+	}
+
+	blog, err := repo.GetBlogByID(ctx, draft.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blog.Status != model.BlogStatusPublished || blog.PublishAt != nil || blog.PublishedAt == nil || blog.PublishedAt.Sub(publishAt).Abs() > time.Millisecond {
+		t.Fatalf("unexpected blog: %+v", blog)
+	}
+
+	// the published blog can be searched
+	hits, err := repo.SearchBlogs(ctx, "scheduled", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Blog.ID != draft.ID {
+		t.Fatalf("unexpected hits: %v", hits)
+	}
+
+	// a blog scheduled again after it was withdrawn was already published
+	if _, err := repo.UpdateBlogStatus(ctx, draft.ID, nil, BlogStatusUpdate{Status: model.BlogStatusScheduled, PublishAt: &publishAt}); err != nil {
+		t.Fatal(err)
+	}
+	if published, err := repo.PublishScheduledBlogs(ctx, publishAt); err != nil || len(published) != 1 || published[0].First {
+		t.Fatalf("expected the blog to be published again, got %+v (%v)", published, err)
+	}
+}
+
+func TestRevisionRepository_Versions(t *testing.T) {
+	forEachStore(t, testRevisionRepositoryVersions)
+}
+
+func testRevisionRepositoryVersions(t *testing.T, store Store) {
+	var (
+		ctx   context.Context    = context.Background()
+		repo  RevisionRepository = store.Revisions()
+		now   time.Time          = time.Now()
+		email string             = "editor@test.com"
+	)
+
+	editorID, err := store.Users().CreateUser(ctx, User{Username: "editor", Email: "editor@test.com", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var editor *model.User = &model.User{ID: editorID, Username: "editor", Email: &email}
+
+	// the blog is stored with its first version
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "title", Content: "line\n", Author: editor, CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the versions follow each other
+	for i := 2; i <= 3; i++ {
+		update := BlogUpdate{Title: "title", Content: strings.Repeat("line\n", i), UpdatedAt: now, Editor: editor}
+		if _, err := store.Blogs().UpdateBlog(ctx, blog.ID, nil, update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := repo.ListRevisions(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || revisions[0].Version != 3 || revisions[2].Version != 1 || revisions[2].LinesAdded != 1 {
+		t.Fatalf("unexpected revisions: %+v", revisions)
+	}
+
+	// only the snapshot of the editor is stored
+	revision, err := repo.GetRevision(ctx, blog.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.Content != "line\nline\n" || revision.LinesAdded != 1 || revision.LinesRemoved != 0 || revision.Editor == nil || revision.Editor.ID != editorID || revision.Editor.Email != nil {
+		t.Fatalf("unexpected revision: %+v", revision)
+	}
+
+	if _, err := repo.GetRevision(ctx, blog.ID, 4); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := repo.DeleteBlogRevisions(ctx, blog.ID); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, err := repo.ListRevisions(ctx, blog.ID); err != nil || len(revisions) != 0 {
+		t.Fatalf("expected no revisions, got %v (%v)", revisions, err)
+	}
+
+	// a blog without revisions gets the version replaced by the edit as its first revision
+	if _, err := store.Blogs().UpdateBlog(ctx, blog.ID, nil, BlogUpdate{Title: "changed", Content: "other\n", UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err = repo.ListRevisions(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[1].Content != strings.Repeat("line\n", 3) || revisions[1].Editor == nil || revisions[1].Editor.ID != editorID {
+		t.Fatalf("expected the previous version as the first revision, got %+v", revisions)
+	}
+	if revisions[0].Version != 2 || !revisions[0].TitleChanged || revisions[0].LinesAdded != 1 || revisions[0].LinesRemoved != 3 || revisions[0].Editor != nil {
+		t.Fatalf("expected the edit to be compared with the previous version, got %+v", revisions[0])
+	}
+}
+
+func TestRevisionRepository_ConcurrentVersions(t *testing.T) {
+	forEachStore(t, testRevisionRepositoryConcurrentVersions)
+}
+
+func testRevisionRepositoryConcurrentVersions(t *testing.T, store Store) {
+	var (
+		ctx  context.Context    = context.Background()
+		repo RevisionRepository = store.Revisions()
+		wg   sync.WaitGroup
+	)
+
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "title", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every writer loses the race at most once to each other writer, so all of them are stored
+	errs := make(chan error, revisionAttempts)
+	for i := 0; i < revisionAttempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			update := BlogUpdate{Title: "title", Content: fmt.Sprintf("line %d\n", i), UpdatedAt: time.Now()}
+			_, err := store.Blogs().UpdateBlog(ctx, blog.ID, nil, update)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := repo.ListRevisions(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != revisionAttempts+1 {
+		t.Fatalf("expected %d revisions, got %d", revisionAttempts+1, len(revisions))
+	}
+
+	// every revision is compared with the version it replaced
+	for i, revision := range revisions {
+		if revision.Version != revisionAttempts+1-i {
+			t.Fatalf("expected the versions to follow each other, got %+v", revisions)
+		}
+		if i+1 < len(revisions) {
+			added, removed := diff.Count(diff.Lines(revisions[i+1].Content, revision.Content))
+			if revision.LinesAdded != added || revision.LinesRemoved != removed {
+				t.Fatalf("expected revision %d to be compared with revision %d, got %+v", revision.Version, revisions[i+1].Version, revisions)
+			}
+		}
+	}
+}
+
+func TestBlogRepository_Trash(t *testing.T) {
+	forEachStore(t, testBlogRepositoryTrash)
+}
+
+func testBlogRepositoryTrash(t *testing.T, store Store) {
+	var (
+		ctx       context.Context = context.Background()
+		repo      BlogRepository  = store.Blogs()
+		now       time.Time       = time.Now()
+		deletedAt time.Time       = now.Add(time.Minute)
+		otherID   string          = newObjectID()
+	)
+
+	authorID, err := store.Users().CreateUser(ctx, User{Username: "author", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blog, err := repo.CreateBlog(ctx, model.Blog{
+		Title:     "trashed",
+		Status:    model.BlogStatusPublished,
+		Author:    &model.User{ID: authorID},
+		CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.TrashBlog(ctx, blog.ID, &authorID, deletedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blog in the trash is left out of the other methods
+	if _, err := repo.GetBlogByID(ctx, blog.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if blogs, err := repo.ListBlogs(ctx, BlogQuery{}); err != nil || len(blogs) != 0 {
+		t.Fatalf("expected no blogs, got %v (%v)", blogs, err)
+	}
+	if hits, err := repo.SearchBlogs(ctx, "trashed", 0, 10); err != nil || len(hits) != 0 {
+		t.Fatalf("expected no hits, got %v (%v)", hits, err)
+	}
+	if err := repo.TrashBlog(ctx, blog.ID, nil, deletedAt); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	trashed, err := repo.ListTrashedBlogs(ctx, authorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 1 || trashed[0].ID != blog.ID || trashed[0].DeletedAt == nil || trashed[0].DeletedAt.Sub(deletedAt).Abs() > time.Millisecond {
+		t.Fatalf("unexpected trash: %+v", trashed)
+	}
+
+	// only the author can restore the blog
+	if _, err := repo.RestoreBlog(ctx, blog.ID, &otherID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	restored, err := repo.RestoreBlog(ctx, blog.ID, &authorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt != nil {
+		t.Fatalf("unexpected blog: %+v", restored)
+	}
+	if hits, err := repo.SearchBlogs(ctx, "trashed", 0, 10); err != nil || len(hits) != 1 {
+		t.Fatalf("expected the restored blog, got %v (%v)", hits, err)
+	}
+
+	// the blogs are purged once their retention has passed
+	if err := repo.TrashBlog(ctx, blog.ID, nil, deletedAt); err != nil {
+		t.Fatal(err)
+	}
+	if expired, err := repo.ListExpiredBlogs(ctx, deletedAt); err != nil || len(expired) != 0 {
+		t.Fatalf("expected no blog to expire, got %v (%v)", expired, err)
+	}
+	if err := repo.PurgeTrashedBlog(ctx, blog.ID, deletedAt); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if expired, err := repo.ListExpiredBlogs(ctx, deletedAt.Add(time.Second)); err != nil || len(expired) != 1 || expired[0] != blog.ID {
+		t.Fatalf("expected the blog to expire, got %v (%v)", expired, err)
+	}
+	if err := repo.PurgeTrashedBlog(ctx, blog.ID, deletedAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RestoreBlog(ctx, blog.ID, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestReactionRepository_ConcurrentReactions(t *testing.T) {
+	forEachStore(t, testReactionRepositoryConcurrentReactions)
+}
+
+func testReactionRepositoryConcurrentReactions(t *testing.T, store Store) {
+	var (
+		ctx     context.Context    = context.Background()
+		repo    ReactionRepository = store.Reactions()
+		now     time.Time          = time.Now()
+		userIDs []string
+		added   int
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+
+	blog, err := store.Blogs().CreateBlog(ctx, model.Blog{Title: "title", CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		id, err := store.Users().CreateUser(ctx, User{Username: "reader", Email: fmt.Sprintf("reader%d@test.com", i), CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	// every user likes the blog several times at once, only one like of each user is kept
+	for _, userID := range userIDs {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(userID string) {
+				defer wg.Done()
+				ok, err := repo.AddReaction(ctx, Reaction{BlogID: blog.ID, UserID: userID, Kind: model.ReactionKindLike, CreatedAt: now})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if ok {
+					mu.Lock()
+					added++
+					mu.Unlock()
+				}
+			}(userID)
+		}
+	}
+	wg.Wait()
+
+	if added != len(userIDs) {
+		t.Fatalf("expected %d added reactions, got %d", len(userIDs), added)
+	}
+
+	if _, err := repo.AddReaction(ctx, Reaction{BlogID: blog.ID, UserID: userIDs[0], Kind: model.ReactionKindLove, CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := repo.CountReactions(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[model.ReactionKindLike] != 10 || counts[model.ReactionKindLove] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+
+	kinds, err := repo.ListUserReactions(ctx, blog.ID, userIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kinds) != 2 {
+		t.Fatalf("unexpected reactions: %v", kinds)
+	}
+
+	// a reaction is only removed once
+	if removed, err := repo.RemoveReaction(ctx, blog.ID, userIDs[0], model.ReactionKindLike); err != nil || !removed {
+		t.Fatalf("expected the reaction to be removed, got %v (%v)", removed, err)
+	}
+	if removed, err := repo.RemoveReaction(ctx, blog.ID, userIDs[0], model.ReactionKindLike); err != nil || removed {
+		t.Fatalf("expected no reaction to be removed, got %v (%v)", removed, err)
+	}
+
+	if err := repo.DeleteBlogReactions(ctx, blog.ID); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err := repo.CountReactions(ctx, blog.ID); err != nil || len(counts) != 0 {
+		t.Fatalf("expected no reactions, got %v (%v)", counts, err)
+	}
+}
+
+func TestFollowRepository_Follows(t *testing.T) {
+	forEachStore(t, testFollowRepositoryFollows)
+}
+
+func testFollowRepositoryFollows(t *testing.T, store Store) {
+	var (
+		ctx     context.Context  = context.Background()
+		repo    FollowRepository = store.Follows()
+		now     time.Time        = time.Now().Truncate(time.Millisecond)
+		userIDs []string
+	)
+
+	for i := 0; i < 4; i++ {
+		id, err := store.Users().CreateUser(ctx, User{Username: "user", Email: fmt.Sprintf("user%d@example.com", i), CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	// the first user follows the others, one second apart
+	for i, followeeID := range userIDs[1:] {
+		ok, err := repo.Follow(ctx, Follow{FollowerID: userIDs[0], FolloweeID: followeeID, CreatedAt: now.Add(time.Duration(i) * time.Second)})
+		if err != nil || !ok {
+			t.Fatalf("expected the follow to be stored, got %v (%v)", ok, err)
+		}
+	}
+
+	// following a user again changes nothing
+	if ok, err := repo.Follow(ctx, Follow{FollowerID: userIDs[0], FolloweeID: userIDs[1], CreatedAt: now}); err != nil || ok {
+		t.Fatalf("expected the follow to be rejected, got %v (%v)", ok, err)
+	}
+
+	var query FollowQuery = FollowQuery{FollowerID: userIDs[0], Limit: 2}
+
+	follows, err := repo.ListFollows(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 2 || follows[0].FolloweeID != userIDs[3] || follows[1].FolloweeID != userIDs[2] {
+		t.Fatalf("unexpected first page: %+v", follows)
+	}
+
+	var cursor FollowCursor = query.CursorOf(follows[1])
+	query.After = &cursor
+
+	follows, err = repo.ListFollows(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].FolloweeID != userIDs[1] {
+		t.Fatalf("unexpected second page: %+v", follows)
+	}
+
+	follows, err = repo.ListFollows(ctx, FollowQuery{FolloweeID: userIDs[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].FollowerID != userIDs[0] {
+		t.Fatalf("unexpected followers: %+v", follows)
+	}
+
+	// a follow is only removed once
+	if removed, err := repo.Unfollow(ctx, userIDs[0], userIDs[2]); err != nil || !removed {
+		t.Fatalf("expected the follow to be removed, got %v (%v)", removed, err)
+	}
+	if removed, err := repo.Unfollow(ctx, userIDs[0], userIDs[2]); err != nil || removed {
+		t.Fatalf("expected no follow to be removed, got %v (%v)", removed, err)
+	}
+
+	if follows, err := repo.ListFollows(ctx, FollowQuery{FolloweeID: userIDs[2]}); err != nil || len(follows) != 0 {
+		t.Fatalf("expected no followers, got %+v (%v)", follows, err)
+	}
+}
+
+func TestUserRepository_GetUsersByIDs(t *testing.T) {
+	forEachStore(t, testUserRepositoryGetUsersByIDs)
+}
+
+func testUserRepositoryGetUsersByIDs(t *testing.T, store Store) {
+	var (
+		ctx context.Context = context.Background()
+		ids []string
+	)
+
+	for i := 0; i < 3; i++ {
+		id, err := store.Users().CreateUser(ctx, User{Username: "user", Email: fmt.Sprintf("user%d@example.com", i), CreatedAt: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	// the unknown and the invalid IDs are left out
+	users, err := store.Users().GetUsersByIDs(ctx, []string{ids[0], ids[2], newObjectID(), "invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found map[string]bool = make(map[string]bool)
+	for _, user := range users {
+		found[user.ID] = true
+	}
+	if len(users) != 2 || !found[ids[0]] || !found[ids[2]] {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	if users, err := store.Users().GetUsersByIDs(ctx, nil); err != nil || len(users) != 0 {
+		t.Fatalf("expected no users, got %+v (%v)", users, err)
+	}
+}
+
+func TestLoginAttemptRepository_Failures(t *testing.T) {
+	forEachStore(t, testLoginAttemptRepositoryFailures)
+}
+
+func testLoginAttemptRepositoryFailures(t *testing.T, store Store) {
+	var (
+		ctx   context.Context        = context.Background()
+		repo  LoginAttemptRepository = store.LoginAttempts()
+		start time.Time              = time.Now().UTC().Truncate(time.Second)
+	)
+
+	if _, err := repo.GetLoginFailures(ctx, "email:user@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected no failures, got %v", err)
+	}
+
+	// the failures are counted within the window
+	for i := 1; i <= 3; i++ {
+		failures, err := repo.RecordLoginFailure(ctx, "email:user@example.com", start.Add(time.Duration(i)*time.Minute), start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failures.Count != i {
+			t.Fatalf("expected %d failures, got %d", i, failures.Count)
+		}
+	}
+
+	// the count starts again after a failure older than the window
+	failures, err := repo.RecordLoginFailure(ctx, "email:user@example.com", start.Add(time.Hour), start.Add(30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures.Count != 1 || failures.Locked(start.Add(time.Hour)) {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+
+	// the other keys are counted apart
+	if failures, err := repo.RecordLoginFailure(ctx, "ip:192.0.2.1", start, start); err != nil || failures.Count != 1 {
+		t.Fatalf("expected a single failure, got %+v (%v)", failures, err)
+	}
+
+	if err := repo.LockLogin(ctx, "email:user@example.com", start.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	failures, err = repo.GetLoginFailures(ctx, "email:user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures.Count != 1 || !failures.Locked(start.Add(time.Hour)) || failures.Locked(start.Add(2*time.Hour)) {
+		t.Fatalf("unexpected lock: %+v", failures)
+	}
+
+	// the reset forgets the failures and the lock
+	if err := repo.ResetLoginFailures(ctx, "email:user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetLoginFailures(ctx, "email:user@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected no failures, got %v", err)
+	}
+	if _, err := repo.GetLoginFailures(ctx, "ip:192.0.2.1"); err != nil {
+		t.Fatalf("expected the failures of the other key to be kept, got %v", err)
+	}
+}
+
+func TestAuditRepository_Records(t *testing.T) {
+	forEachStore(t, testAuditRepositoryRecords)
+}
+
+func testAuditRepositoryRecords(t *testing.T, store Store) {
+	var (
+		ctx   context.Context = context.Background()
+		repo  AuditRepository = store.Audit()
+		start time.Time       = time.Now().UTC().Truncate(time.Second)
+		ids   []string
+	)
+
+	for i, record := range []AuditRecord{
+		{Action: model.AuditActionLoginLocked, UserID: "user", Email: "user@example.com", IP: "192.0.2.1"},
+		{Action: model.AuditActionIPLocked, IP: "192.0.2.1"},
+		{Action: model.AuditActionLoginUnlocked, UserID: "user", ActorID: "admin", Email: "user@example.com"},
+	} {
+		// the last two records are created at the same time
+		record.CreatedAt = start
+		if i > 0 {
+			record.CreatedAt = start.Add(time.Minute)
+		}
+
+		id, err := repo.CreateAuditRecord(ctx, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	records, err := repo.ListAuditRecords(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].ID != ids[2] || records[1].ID != ids[1] || records[2].ID != ids[0] {
+		t.Fatalf("expected the newest records first, got %+v", records)
+	}
+	if records[0].ActorID != "admin" || records[0].Action != model.AuditActionLoginUnlocked {
+		t.Fatalf("unexpected record: %+v", records[0])
+	}
+
+	records, err = repo.ListAuditRecords(ctx, "user", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != ids[2] {
+		t.Fatalf("expected the latest record of the user, got %+v", records)
+	}
+}
+
+func TestUserRepository_EmailCase(t *testing.T) {
+	forEachStore(t, testUserRepositoryEmailCase)
+}
+
+func testUserRepositoryEmailCase(t *testing.T, store Store) {
+	var (
+		ctx  context.Context = context.Background()
+		repo UserRepository  = store.Users()
+	)
+
+	id, err := repo.CreateUser(ctx, User{Username: "alice", Email: "alice@test.com", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the emails are compared without their case
+	if _, err := repo.CreateUser(ctx, User{Username: "other", Email: "Alice@Test.com", CreatedAt: time.Now()}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+
+	user, err := repo.GetUserByEmail(ctx, "ALICE@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != id {
+		t.Fatalf("expected the user %s, got %+v", id, user)
+	}
+}
+
+func TestUserRepository_DuplicateEmail(t *testing.T) {
+	forEachStore(t, testUserRepositoryDuplicateEmail)
+}
+
+func testUserRepositoryDuplicateEmail(t *testing.T, store Store) {
+	var (
+		ctx     context.Context = context.Background()
+		wg      sync.WaitGroup
+		created int32
+	)
+
+	// the registrations race on the same email, a single one is stored
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Users().CreateUser(ctx, User{Email: "same@test.com", CreatedAt: time.Now()})
+			switch {
+			case err == nil:
+				atomic.AddInt32(&created, 1)
+			case !errors.Is(err, ErrDuplicate):
+				t.Errorf("expected ErrDuplicate, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Fatalf("expected a single user to be stored, got %d", created)
+	}
+}
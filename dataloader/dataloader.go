@@ -0,0 +1,133 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWait is how long the loader collects the keys of a batch
+	DefaultWait = time.Millisecond
+	// DefaultMaxBatch is the largest number of keys fetched together
+	DefaultMaxBatch = 100
+)
+
+// ErrNotFound is returned when the fetch returns no value for the key
+var ErrNotFound = errors.New("value not found")
+
+// FetchFunc returns the values of the keys
+// the keys without a value are left out of the map
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader collects the keys loaded at the same time and fetches them together
+// the values are cached, so a loader is meant to live as long as a single request
+type Loader[K comparable, V any] struct {
+	fetch    FetchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*result[V]
+	batch *batch[K, V]
+}
+
+// result represents the value of a key, it is ready once done is closed
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// batch represents the keys fetched together
+type batch[K comparable, V any] struct {
+	ctx     context.Context
+	keys    []K
+	results []*result[V]
+}
+
+// NewLoader returns a loader fetching the keys with the function
+// the keys are collected for the wait duration or until there are maxBatch keys
+func NewLoader[K comparable, V any](fetch FetchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value of the key
+// the key is fetched with the other keys loaded at the same time unless it is cached
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		l.add(ctx, key, r)
+	}
+
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// add places the key in the current batch and starts the batch when it is full
+// the caller must hold the lock of the loader
+func (l *Loader[K, V]) add(ctx context.Context, key K, r *result[V]) {
+	if l.batch == nil {
+		var b *batch[K, V] = &batch[K, V]{ctx: ctx}
+		l.batch = b
+
+		time.AfterFunc(l.wait, func() {
+			l.mu.Lock()
+			// the batch may have been started because it was full
+			var current bool = l.batch == b
+			if current {
+				l.batch = nil
+			}
+			l.mu.Unlock()
+
+			if current {
+				l.run(b)
+			}
+		})
+	}
+
+	l.batch.keys = append(l.batch.keys, key)
+	l.batch.results = append(l.batch.results, r)
+
+	if len(l.batch.keys) >= l.maxBatch {
+		go l.run(l.batch)
+		l.batch = nil
+	}
+}
+
+// run fetches the keys of the batch and delivers their values
+func (l *Loader[K, V]) run(b *batch[K, V]) {
+	values, err := l.fetch(b.ctx, b.keys)
+
+	for i, key := range b.keys {
+		var r *result[V] = b.results[i]
+
+		switch value, ok := values[key]; {
+		case err != nil:
+			r.err = err
+		case !ok:
+			r.err = ErrNotFound
+		default:
+			r.value = value
+		}
+
+		close(r.done)
+	}
+}
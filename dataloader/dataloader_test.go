@@ -0,0 +1,110 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingFetch returns the double of the positive keys and counts the fetches
+type countingFetch struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (f *countingFetch) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, keys)
+	f.mu.Unlock()
+
+	var values map[int]int = make(map[int]int, len(keys))
+	for _, key := range keys {
+		if key > 0 {
+			values[key] = key * 2
+		}
+	}
+	return values, nil
+}
+
+func TestLoader_Batch(t *testing.T) {
+	var (
+		fetch  *countingFetch    = &countingFetch{}
+		loader *Loader[int, int] = NewLoader[int, int](fetch.fetch, 10*time.Millisecond, 100)
+		wg     sync.WaitGroup
+		values map[int]int   = make(map[int]int)
+		errs   map[int]error = make(map[int]error)
+		mu     sync.Mutex
+	)
+
+	// the same keys are loaded several times at once
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			value, err := loader.Load(context.Background(), key)
+
+			mu.Lock()
+			values[key], errs[key] = value, err
+			mu.Unlock()
+		}(i % 5)
+	}
+	wg.Wait()
+
+	if len(fetch.batches) != 1 || len(fetch.batches[0]) != 5 {
+		t.Fatalf("expected a single batch of 5 keys, got %v", fetch.batches)
+	}
+
+	for key := 1; key < 5; key++ {
+		if values[key] != key*2 || errs[key] != nil {
+			t.Fatalf("unexpected value of %d: %d (%v)", key, values[key], errs[key])
+		}
+	}
+
+	if !errors.Is(errs[0], ErrNotFound) {
+		t.Fatalf("expected the missing key to be not found, got %v", errs[0])
+	}
+
+	// the values are cached
+	if value, err := loader.Load(context.Background(), 3); err != nil || value != 6 {
+		t.Fatalf("unexpected cached value: %d (%v)", value, err)
+	}
+	if len(fetch.batches) != 1 {
+		t.Fatalf("expected the cached value to be used, got %v", fetch.batches)
+	}
+}
+
+func TestLoader_MaxBatch(t *testing.T) {
+	var (
+		fetch  *countingFetch    = &countingFetch{}
+		loader *Loader[int, int] = NewLoader[int, int](fetch.fetch, time.Hour, 2)
+		wg     sync.WaitGroup
+	)
+
+	// a full batch is fetched without waiting
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			if _, err := loader.Load(context.Background(), key); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(fetch.batches) != 2 {
+		t.Fatalf("expected 2 batches, got %v", fetch.batches)
+	}
+}
+
+func TestLoader_FetchError(t *testing.T) {
+	var loader *Loader[int, int] = NewLoader[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		return nil, errors.New("fetch failed")
+	}, time.Millisecond, 10)
+
+	if _, err := loader.Load(context.Background(), 1); err == nil || err.Error() != "fetch failed" {
+		t.Fatalf("expected the fetch error, got %v", err)
+	}
+}
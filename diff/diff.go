@@ -0,0 +1,162 @@
+package diff
+
+import (
+	"strings"
+)
+
+// maxCells bounds the size of the comparison table, larger changes
+// are reported as the removal of the old lines and the insertion of the new lines
+const maxCells = 4 << 20
+
+// Operation represents what happened to a line
+type Operation int
+
+const (
+	// Equal is a line found in both texts
+	Equal Operation = iota
+	// Insert is a line only found in the new text
+	Insert
+	// Delete is a line only found in the old text
+	Delete
+)
+
+// Line represents a line of the comparison of two texts
+type Line struct {
+	Operation Operation
+	Text      string
+}
+
+// Lines compares the texts line by line and returns the lines of both texts
+// in order, the longest common sequence of lines is kept equal
+func Lines(old string, new string) []Line {
+	var (
+		a []string = split(old)
+		b []string = split(new)
+	)
+
+	// the lines shared at the start and the end are equal
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	var suffix int
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Operation: Equal, Text: text})
+	}
+
+	lines = append(lines, compare(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Operation: Equal, Text: text})
+	}
+
+	return lines
+}
+
+// compare returns the lines of both texts with their longest common sequence kept equal
+func compare(a []string, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+
+	if len(a)*len(b) > maxCells {
+		for _, text := range a {
+			lines = append(lines, Line{Operation: Delete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, Line{Operation: Insert, Text: text})
+		}
+		return lines
+	}
+
+	// common[i][j] is the length of the longest common sequence of a[i:] and b[j:]
+	var common [][]int = make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var i, j int
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Operation: Equal, Text: a[i]})
+			i, j = i+1, j+1
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, Line{Operation: Delete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Operation: Insert, Text: b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Operation: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Operation: Insert, Text: b[j]})
+	}
+
+	return lines
+}
+
+// Count returns the number of inserted and deleted lines
+func Count(lines []Line) (inserted int, deleted int) {
+	for _, line := range lines {
+		switch line.Operation {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+
+	return inserted, deleted
+}
+
+// Format returns the lines as text, each line starts with "+" when it is inserted,
+// "-" when it is deleted and a space when it is equal
+func Format(lines []Line) string {
+	var builder strings.Builder
+
+	for _, line := range lines {
+		switch line.Operation {
+		case Insert:
+			builder.WriteByte('+')
+		case Delete:
+			builder.WriteByte('-')
+		default:
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(line.Text)
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+// split returns the lines of the text, an empty text has no lines
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
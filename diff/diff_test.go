@@ -0,0 +1,28 @@
+package diff
+
+import "testing"
+
+func TestLines(t *testing.T) {
+	var lines []Line = Lines("one\ntwo\nthree\nfour", "one\n2\nthree\nfour\nfive")
+
+	if formatted := Format(lines); formatted != " one\n-two\n+2\n three\n four\n+five\n" {
+		t.Fatalf("unexpected diff:\n%s", formatted)
+	}
+
+	inserted, deleted := Count(lines)
+	if inserted != 2 || deleted != 1 {
+		t.Fatalf("expected 2 inserted and 1 deleted lines, got %d and %d", inserted, deleted)
+	}
+}
+
+func TestLines_EmptyText(t *testing.T) {
+	if lines := Lines("", ""); len(lines) != 0 {
+		t.Fatalf("expected no lines, got %v", lines)
+	}
+
+	// every line of a new text is inserted
+	inserted, deleted := Count(Lines("", "a\nb\n"))
+	if inserted != 2 || deleted != 0 {
+		t.Fatalf("expected 2 inserted lines, got %d and %d", inserted, deleted)
+	}
+}
@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
+)
+
+const (
+	// lookupWeight is the cost of a field resolved with a storage lookup
+	lookupWeight = 2
+	// searchWeight is the cost of a full text search
+	searchWeight = 10
+	// listEstimate is the estimated length of the lists that are not paginated
+	listEstimate = service.DefaultPageSize
+)
+
+// NewComplexity returns the cost of the fields used to compute the cost of an operation
+// a field costs one unless it is weighted here, the lists multiply the cost of their items
+func NewComplexity() ComplexityRoot {
+	var root ComplexityRoot
+
+	root.Query.Blogs = func(childComplexity int, _ *model.BlogFilter, _ *model.BlogOrder) int {
+		return listCost(childComplexity)
+	}
+	root.Query.BlogsConnection = func(childComplexity int, first *int, _ *string, last *int, _ *string, _ *model.BlogFilter, _ *model.BlogOrder) int {
+		if first == nil {
+			first = last
+		}
+		return pageCost(childComplexity, first)
+	}
+	root.Query.BlogsByTag = func(childComplexity int, _ string, first *int, _ *string) int {
+		return pageCost(childComplexity, first)
+	}
+	root.Query.SearchBlogs = func(childComplexity int, _ string, first *int, _ *string) int {
+		return searchWeight + pageCost(childComplexity, first)
+	}
+	root.Query.Feed = func(childComplexity int, first *int, _ *string) int {
+		return pageCost(childComplexity, first)
+	}
+	root.Query.AuditRecords = func(childComplexity int, _ *string, first *int) int {
+		return pageCost(childComplexity, first)
+	}
+	root.Query.Tags = listCost
+	root.Query.TrashedBlogs = listCost
+
+	root.AuditRecord.User = lookupCost
+	root.AuditRecord.Actor = lookupCost
+	root.Blog.Author = lookupCost
+	root.Blog.CommentCount = lookupCost
+	root.Blog.Reactions = lookupCost
+	root.Blog.Revisions = listCost
+	root.Blog.Comments = pageCostOf
+	root.BlogRevision.Editor = lookupCost
+	root.Comment.Author = lookupCost
+	root.Comment.ReplyCount = lookupCost
+	root.Comment.Replies = pageCostOf
+	root.User.Blogs = pageCostOf
+	root.User.Followers = pageCostOf
+	root.User.Following = pageCostOf
+
+	return root
+}
+
+// lookupCost returns the cost of a field resolved with a storage lookup
+func lookupCost(childComplexity int) int {
+	return lookupWeight + childComplexity
+}
+
+// listCost returns the cost of a list that is not paginated
+func listCost(childComplexity int) int {
+	return lookupWeight + listEstimate*childComplexity
+}
+
+// pageCostOf returns the cost of a page of a connection with the first and after arguments
+func pageCostOf(childComplexity int, first *int, _ *string) int {
+	return pageCost(childComplexity, first)
+}
+
+// pageCost returns the cost of a page, every item of the page costs the complexity of its fields
+// the default page size is used without a size, a size above the limit is rejected by the resolver
+func pageCost(childComplexity int, size *int) int {
+	var count int = service.DefaultPageSize
+	switch {
+	case size == nil:
+	case *size < 0:
+		count = 0
+	case *size > service.MaxPageSize:
+		count = service.MaxPageSize
+	default:
+		count = *size
+	}
+
+	return lookupWeight + count*childComplexity
+}
@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/middleware"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// NewDirectives returns the implementations of the schema directives
+func NewDirectives() DirectiveRoot {
+	return DirectiveRoot{
+		Auth:    auth,
+		HasRole: hasRole,
+	}
+}
+
+// auth resolves the field only for an authenticated user
+func auth(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if middleware.ForContext(ctx) == nil {
+		return nil, apperror.Unauthenticated("access denied")
+	}
+
+	return next(ctx)
+}
+
+// hasRole resolves the field only for a user with the role or a higher role
+func hasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	user := middleware.ForContext(ctx)
+	if user == nil {
+		return nil, apperror.Unauthenticated("access denied")
+	}
+
+	if !user.EffectiveRole().Includes(role) {
+		return nil, apperror.Forbidden("access denied, the " + string(role) + " role is required")
+	}
+
+	return next(ctx)
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// constraintDirective is the name of the directive constraining the input fields
+const constraintDirective = "constraint"
+
+// the formats of the string fields
+const (
+	// emailFormat is the format of the email addresses
+	emailFormat = "email"
+	// urlFormat is the format of the absolute http or https URLs
+	urlFormat = "url"
+)
+
+// Constraints checks the input fields against their @constraint directives before the resolvers run
+// every violation of the arguments of a field is reported, with the path of the input field
+type Constraints struct {
+	schema   *ast.Schema
+	patterns map[string]*regexp.Regexp
+}
+
+var _ interface {
+	graphql.FieldInterceptor
+	graphql.HandlerExtension
+} = &Constraints{}
+
+// ExtensionName returns the name of the extension
+func (c *Constraints) ExtensionName() string {
+	return "Constraints"
+}
+
+// Validate compiles the patterns and checks the formats of the directives when the extension is added to the server
+func (c *Constraints) Validate(schema graphql.ExecutableSchema) error {
+	c.schema = schema.Schema()
+	c.patterns = make(map[string]*regexp.Regexp)
+
+	for _, def := range c.schema.Types {
+		if def.Kind != ast.InputObject {
+			continue
+		}
+
+		for _, field := range def.Fields {
+			directive := field.Directives.ForName(constraintDirective)
+			if directive == nil {
+				continue
+			}
+
+			var args map[string]interface{} = directive.ArgumentMap(nil)
+
+			if pattern, ok := args["pattern"].(string); ok {
+				compiled, err := regexp.Compile(pattern)
+				if err != nil {
+					return fmt.Errorf("pattern of %s.%s is invalid: %w", def.Name, field.Name, err)
+				}
+				c.patterns[pattern] = compiled
+			}
+
+			if format, ok := args["format"].(string); ok && format != emailFormat && format != urlFormat {
+				return fmt.Errorf("format %q of %s.%s is unknown", format, def.Name, field.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// InterceptField checks the arguments of the field, the field is not resolved when they break a constraint
+func (c *Constraints) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Field.Field == nil || fc.Field.Definition == nil || len(fc.Field.Arguments) == 0 {
+		return next(ctx)
+	}
+
+	var violations []*gqlerror.Error
+	for _, arg := range fc.Field.Arguments {
+		def := fc.Field.Definition.Arguments.ForName(arg.Name)
+		if def == nil {
+			continue
+		}
+
+		// the invalid values are already reported when the arguments are parsed
+		value, err := arg.Value.Value(graphql.GetOperationContext(ctx).Variables)
+		if err != nil {
+			continue
+		}
+
+		violations = c.check(violations, def.Type, value, arg.Name)
+	}
+
+	if len(violations) == 0 {
+		return next(ctx)
+	}
+
+	// the field has errors, so it is not reported as null on its own
+	for _, violation := range violations {
+		graphql.AddError(ctx, violation)
+	}
+
+	return nil, nil
+}
+
+// check appends the violations of the input fields of the value, found at the path
+func (c *Constraints) check(violations []*gqlerror.Error, typ *ast.Type, value interface{}, path string) []*gqlerror.Error {
+	if value == nil {
+		return violations
+	}
+
+	if typ.Elem != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			// a single value is given for a list of one item
+			return c.check(violations, typ.Elem, value, path)
+		}
+
+		for i, item := range items {
+			violations = c.check(violations, typ.Elem, item, path+"["+strconv.Itoa(i)+"]")
+		}
+		return violations
+	}
+
+	def := c.schema.Types[typ.NamedType]
+	if def == nil || def.Kind != ast.InputObject {
+		return violations
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return violations
+	}
+
+	// the fields are checked in the order of the schema, so the violations are always reported alike
+	for _, field := range def.Fields {
+		fieldValue, ok := fields[field.Name]
+		if !ok {
+			continue
+		}
+
+		var fieldPath string = path + "." + field.Name
+
+		if directive := field.Directives.ForName(constraintDirective); directive != nil {
+			violations = c.checkConstraint(violations, directive, fieldValue, fieldPath)
+		}
+		violations = c.check(violations, field.Type, fieldValue, fieldPath)
+	}
+
+	return violations
+}
+
+// checkConstraint appends the violations of the directive by a string, or by every string of a list
+func (c *Constraints) checkConstraint(violations []*gqlerror.Error, directive *ast.Directive, value interface{}, path string) []*gqlerror.Error {
+	switch value := value.(type) {
+	case []interface{}:
+		for i, item := range value {
+			violations = c.checkConstraint(violations, directive, item, path+"["+strconv.Itoa(i)+"]")
+		}
+	case string:
+		for _, message := range c.violations(directive, value) {
+			violations = append(violations, constraintError(path, message))
+		}
+	}
+
+	return violations
+}
+
+// violations returns the messages of the rules of the directive broken by the value
+// the lengths are counted in characters
+func (c *Constraints) violations(directive *ast.Directive, value string) []string {
+	var (
+		args     map[string]interface{} = directive.ArgumentMap(nil)
+		length   int                    = utf8.RuneCountInString(value)
+		messages []string
+	)
+
+	if minLength, ok := args["minLength"].(int64); ok && int64(length) < minLength {
+		messages = append(messages, "must have at least "+characters(minLength))
+	}
+
+	if maxLength, ok := args["maxLength"].(int64); ok && int64(length) > maxLength {
+		messages = append(messages, "must have at most "+characters(maxLength))
+	}
+
+	if pattern, ok := args["pattern"].(string); ok && !c.patterns[pattern].MatchString(value) {
+		messages = append(messages, "must match the pattern "+pattern)
+	}
+
+	if format, ok := args["format"].(string); ok && format == emailFormat && !validEmail(value) {
+		messages = append(messages, "must be a valid email")
+	}
+
+	if format, ok := args["format"].(string); ok && format == urlFormat && !validURL(value) {
+		messages = append(messages, "must be an absolute http or https URL")
+	}
+
+	return messages
+}
+
+// constraintError returns the error of an input field breaking a constraint
+// the path of the input field is added to the extensions
+func constraintError(path string, message string) *gqlerror.Error {
+	err := gqlerror.Errorf("%s %s", path, message)
+	errcode.Set(err, string(apperror.CodeBadUserInput))
+	err.Extensions["field"] = path
+
+	return err
+}
+
+// characters returns the number of characters in words
+func characters(count int64) string {
+	if count == 1 {
+		return "1 character"
+	}
+
+	return strconv.FormatInt(count, 10) + " characters"
+}
+
+// validEmail reports whether the value is a bare email address, without a name or brackets
+func validEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+// validURL reports whether the value is an absolute http or https URL
+func validURL(value string) bool {
+	address, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	return (address.Scheme == "http" || address.Scheme == "https") && address.Host != ""
+}
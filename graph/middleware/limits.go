@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errDepthLimit is the code of the error returned for a too deep operation
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// depthExtension is the name of the depth limit extension and of its statistics
+const depthExtension = "DepthLimit"
+
+// DepthLimit rejects the operations selecting fields deeper than the limit
+// the fields of the introspection queries are not counted
+type DepthLimit struct {
+	MaxDepth int
+}
+
+// DepthStats represents the depth of an operation
+type DepthStats struct {
+	// Depth is the number of nested fields of the deepest selection
+	Depth int
+	// DepthLimit is the largest depth allowed
+	DepthLimit int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = DepthLimit{}
+
+// ExtensionName returns the name of the extension
+func (d DepthLimit) ExtensionName() string {
+	return depthExtension
+}
+
+// Validate checks the limit when the extension is added to the server
+func (d DepthLimit) Validate(schema graphql.ExecutableSchema) error {
+	if d.MaxDepth <= 0 {
+		return fmt.Errorf("max depth must be positive, got %d", d.MaxDepth)
+	}
+
+	return nil
+}
+
+// MutateOperationContext computes the depth of the operation and rejects it above the limit
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	var depth int = selectionDepth(rc.Operation.SelectionSet)
+
+	rc.Stats.SetExtension(depthExtension, &DepthStats{
+		Depth:      depth,
+		DepthLimit: d.MaxDepth,
+	})
+
+	if depth > d.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.MaxDepth)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+
+	return nil
+}
+
+// GetDepthStats returns the depth of the operation of the context
+func GetDepthStats(ctx context.Context) *DepthStats {
+	rc := graphql.GetOperationContext(ctx)
+	if rc == nil {
+		return nil
+	}
+
+	stats, _ := rc.Stats.GetExtension(depthExtension).(*DepthStats)
+	return stats
+}
+
+// selectionDepth returns the number of nested fields of the deepest selection
+// the fragments are validated before, so they cannot spread themselves
+func selectionDepth(selections ast.SelectionSet) int {
+	var depth int
+
+	for _, selection := range selections {
+		var current int
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name, "__") {
+				continue
+			}
+			current = 1 + selectionDepth(selection.SelectionSet)
+		case *ast.InlineFragment:
+			current = selectionDepth(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if selection.Definition != nil {
+				current = selectionDepth(selection.Definition.SelectionSet)
+			}
+		}
+
+		if current > depth {
+			depth = current
+		}
+	}
+
+	return depth
+}
+
+// NewCostMiddleware returns a middleware reporting the cost of the operation in the extensions of the response
+func NewCostMiddleware() graphql.ResponseMiddleware {
+	return func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		var cost map[string]int = make(map[string]int)
+
+		if stats := extension.GetComplexityStats(ctx); stats != nil {
+			cost["complexity"] = stats.Complexity
+			cost["complexityLimit"] = stats.ComplexityLimit
+		}
+		if stats := GetDepthStats(ctx); stats != nil {
+			cost["depth"] = stats.Depth
+			cost["depthLimit"] = stats.DepthLimit
+		}
+
+		// the extensions are read when the response is built, so they are registered first
+		if len(cost) > 0 {
+			graphql.RegisterExtension(ctx, "cost", cost)
+		}
+
+		return next(ctx)
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/dataloader"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// create a context key for the loaders
+var loadersCtxKey = &contextKey{"loaders"}
+
+// Loaders represents the batch loaders of a response
+type Loaders struct {
+	// Users loads the users by ID
+	Users *dataloader.Loader[string, *model.User]
+}
+
+// NewLoaders returns loaders with empty caches
+func NewLoaders(userService *service.UserService) *Loaders {
+	return &Loaders{
+		Users: dataloader.NewLoader(userService.GetUsers, dataloader.DefaultWait, dataloader.DefaultMaxBatch),
+	}
+}
+
+// NewLoadersMiddleware returns a middleware giving new loaders to every response
+// each event of a subscription is a response, so the cached users are never stale
+func NewLoadersMiddleware(userService *service.UserService) graphql.ResponseMiddleware {
+	return func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		// create a context with the loaders of the response
+		return next(context.WithValue(ctx, loadersCtxKey, NewLoaders(userService)))
+	}
+}
+
+// LoadersForContext returns the loaders of the response from the context
+func LoadersForContext(ctx context.Context) *Loaders {
+	// get context value for the loaders
+	raw, _ := ctx.Value(loadersCtxKey).(*Loaders)
+	// return context value
+	return raw
+}
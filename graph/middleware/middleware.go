@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
 	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 )
 
 // create a context key
@@ -17,8 +20,11 @@ type contextKey struct {
 // create a context key for user data
 var userCtxKey = &contextKey{"user"}
 
+// create a context key for the token data
+var tokenCtxKey = &contextKey{"token"}
+
 // NewMiddleware returns a middleware for authentication
-func NewMiddleware() func(http.Handler) http.Handler {
+func NewMiddleware(userService *service.UserService) func(http.Handler) http.Handler {
 	// return handler that acts as a middleware
 	return func(next http.Handler) http.Handler {
 		// return handler function
@@ -33,37 +39,14 @@ func NewMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// get the JWT token from the header
-			tokenData, err := utils.CheckToken(r)
-
-			// if the JWT token is invalid, return an error
-			// the next request cannot be proceed
-			if err != nil {
-				http.Error(w, "invalid token", http.StatusForbidden)
-				return
-			}
-
-			// create a userService component
-			var userService service.UserService = service.UserService{}
-
-			// get the user data by ID from the JWT token
-			userData, err := userService.GetUser(tokenData.UserId)
-
-			// if a user is not found, return an error
-			// the next request cannot be proceed
+			// authenticate the user of the JWT token
+			// the next request cannot be proceed when it fails
+			ctx, err := authenticate(r.Context(), userService, header)
 			if err != nil {
-				http.Error(w, "user not found", http.StatusForbidden)
+				http.Error(w, err.Error(), http.StatusForbidden)
 				return
 			}
 
-			// store the user data from the database
-			// into "user" variable
-			var user model.User = *userData
-
-			// create a context with value
-			// the context value is user data
-			ctx := context.WithValue(r.Context(), userCtxKey, &user)
-
 			// add context to the request object
 			r = r.WithContext(ctx)
 			// continue to serve HTTP
@@ -72,6 +55,66 @@ func NewMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
+// NewWebsocketInit returns the authentication of the websocket connections
+// the browsers cannot send the Authorization header when they open a websocket,
+// so the clients send it in the payload of the connection initialization instead
+func NewWebsocketInit(userService *service.UserService) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		var header string = initPayload.Authorization()
+
+		// the connection stays anonymous without a token
+		// or keeps the user of the Authorization header of the upgrade request
+		if header == "" {
+			return ctx, nil, nil
+		}
+
+		// a connection with an invalid token is refused
+		ctx, err := authenticate(ctx, userService, header)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return ctx, nil, nil
+	}
+}
+
+// authenticate returns the context of the user of the JWT token in the Authorization header value
+func authenticate(ctx context.Context, userService *service.UserService, header string) (context.Context, error) {
+	// get the JWT token from the header
+	tokenData, err := utils.CheckAuthorization(header)
+
+	// if the JWT token is invalid, return an error
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	// if the JWT token has been revoked by a logout, return an error
+	revoked, err := userService.IsTokenRevoked(ctx, *tokenData)
+	if err != nil || revoked {
+		return nil, errors.New("invalid token")
+	}
+
+	// get the user data by ID from the JWT token
+	userData, err := userService.GetUser(ctx, tokenData.UserId)
+
+	// if a user is not found, return an error
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// store the user data from the database
+	// into "user" variable
+	var user model.User = *userData
+
+	// create a context with value
+	// the context value is user data
+	ctx = context.WithValue(ctx, userCtxKey, &user)
+	// keep the token data so the token can be revoked
+	ctx = context.WithValue(ctx, tokenCtxKey, tokenData)
+
+	return ctx, nil
+}
+
 // ForContext returns value from the context
 func ForContext(ctx context.Context) *model.User {
 	// get context value for user data
@@ -79,3 +122,11 @@ func ForContext(ctx context.Context) *model.User {
 	// return context value
 	return raw
 }
+
+// TokenForContext returns the data of the JWT token from the context
+func TokenForContext(ctx context.Context) *utils.TokenMetadata {
+	// get context value for the token data
+	raw, _ := ctx.Value(tokenCtxKey).(*utils.TokenMetadata)
+	// return context value
+	return raw
+}
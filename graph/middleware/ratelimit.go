@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/ratelimit"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errRateLimited is the code of the error returned when a budget is exhausted
+const errRateLimited = "RATE_LIMITED"
+
+// create a context key for the IP address of the client
+var clientIPCtxKey = &contextKey{"clientIP"}
+
+// NewRateLimitMiddleware returns a middleware limiting the requests of every client
+// the clients are the authenticated users, or the IP addresses for the anonymous requests,
+// so it must run after the authentication
+func NewRateLimitMiddleware(limiter *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// keep the IP address for the limits of the fields
+			var ctx context.Context = context.WithValue(r.Context(), clientIPCtxKey, clientIP(r))
+
+			var result ratelimit.Result = limiter.Allow(ClientKey(ctx))
+			setRateLimitHeaders(w.Header(), limiter.Rate(), result)
+
+			if !result.Allowed {
+				// the clients read the GraphQL error like any other error of the API
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(graphql.Response{Errors: gqlerror.List{RateLimitError("too many requests", result)}})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NewFieldRateLimiter returns a middleware limiting the root fields of the schema
+// the rates are keyed by the type and the name of the field, e.g. "Mutation.login"
+func NewFieldRateLimiter(rates map[string]ratelimit.Rate) graphql.FieldMiddleware {
+	var limiters map[string]*ratelimit.Limiter = make(map[string]*ratelimit.Limiter, len(rates))
+	for field, rate := range rates {
+		limiters[field] = ratelimit.NewLimiter(rate)
+	}
+
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || fc.Object != "Query" && fc.Object != "Mutation" {
+			return next(ctx)
+		}
+
+		limiter, ok := limiters[fc.Object+"."+fc.Field.Name]
+		if !ok {
+			return next(ctx)
+		}
+
+		if result := limiter.Allow(ClientKey(ctx)); !result.Allowed {
+			return nil, RateLimitError("too many "+fc.Field.Name+" attempts", result)
+		}
+
+		return next(ctx)
+	}
+}
+
+// ClientKey returns the key of the client the requests are counted for
+func ClientKey(ctx context.Context) string {
+	if user := ForContext(ctx); user != nil {
+		return "user:" + user.ID
+	}
+
+	return "ip:" + ClientIPForContext(ctx)
+}
+
+// ClientIPForContext returns the IP address of the client from the context
+func ClientIPForContext(ctx context.Context) string {
+	// get context value for the IP address
+	raw, _ := ctx.Value(clientIPCtxKey).(string)
+	// return context value
+	return raw
+}
+
+// RateLimitError returns the error of an exhausted budget
+// the extensions tell the client when to retry
+func RateLimitError(message string, result ratelimit.Result) *gqlerror.Error {
+	var retryAfter int = seconds(result.RetryAfter)
+
+	err := gqlerror.Errorf("%s, retry in %d seconds", message, retryAfter)
+	errcode.Set(err, errRateLimited)
+	err.Extensions["limit"] = result.Limit
+	err.Extensions["retryAfter"] = retryAfter
+
+	return err
+}
+
+// setRateLimitHeaders describes the budget of the client with the RateLimit headers
+func setRateLimitHeaders(header http.Header, rate ratelimit.Rate, result ratelimit.Result) {
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rate.Limit, seconds(rate.Period)))
+}
+
+// clientIP returns the IP address of the connection
+// the forwarding headers are ignored, since every client could set them
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
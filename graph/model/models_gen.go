@@ -3,26 +3,157 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
+type ArchiveBlog struct {
+	BlogID string `json:"blogId" bson:"blogId"`
+}
+
+type AuditRecord struct {
+	ID        string      `json:"id" bson:"_id,omitempty"`
+	Action    AuditAction `json:"action" bson:"action"`
+	User      *User       `json:"user,omitempty" bson:"user"`
+	Actor     *User       `json:"actor,omitempty" bson:"actor"`
+	Email     *string     `json:"email,omitempty" bson:"email"`
+	IP        *string     `json:"ip,omitempty" bson:"ip"`
+	CreatedAt time.Time   `json:"createdAt" bson:"createdAt"`
+}
+
+type AuthToken struct {
+	AccessToken          string    `json:"accessToken" bson:"accessToken"`
+	RefreshToken         string    `json:"refreshToken" bson:"refreshToken"`
+	AccessTokenExpiresAt time.Time `json:"accessTokenExpiresAt" bson:"accessTokenExpiresAt"`
+}
+
 type Blog struct {
-	ID        string     `json:"id" bson:"_id,omitempty"`
-	Title     string     `json:"title" bson:"title"`
-	Content   string     `json:"content" bson:"content"`
-	Author    *User      `json:"author,omitempty" bson:"author"`
-	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
-	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt"`
+	ID           string             `json:"id" bson:"_id,omitempty"`
+	Title        string             `json:"title" bson:"title"`
+	Content      string             `json:"content" bson:"content"`
+	Author       *User              `json:"author,omitempty" bson:"author"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt"`
+	Tags         []string           `json:"tags" bson:"tags"`
+	Status       BlogStatus         `json:"status" bson:"status"`
+	PublishAt    *time.Time         `json:"publishAt,omitempty" bson:"publishAt"`
+	PublishedAt  *time.Time         `json:"publishedAt,omitempty" bson:"publishedAt"`
+	DeletedAt    *time.Time         `json:"deletedAt,omitempty" bson:"deletedAt"`
+	Comments     *CommentConnection `json:"comments" bson:"comments"`
+	CommentCount int                `json:"commentCount" bson:"commentCount"`
+	Revisions    []*BlogRevision    `json:"revisions" bson:"revisions"`
+	Reactions    *ReactionSummary   `json:"reactions" bson:"reactions"`
+}
+
+type BlogConnection struct {
+	Edges    []*BlogEdge `json:"edges" bson:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo" bson:"pageInfo"`
+}
+
+type BlogEdge struct {
+	Cursor string `json:"cursor" bson:"cursor"`
+	Node   *Blog  `json:"node" bson:"node"`
+}
+
+type BlogFilter struct {
+	AuthorID      *string     `json:"authorId,omitempty" bson:"authorId"`
+	Status        *BlogStatus `json:"status,omitempty" bson:"status"`
+	TitleContains *string     `json:"titleContains,omitempty" bson:"titleContains"`
+	CreatedAfter  *time.Time  `json:"createdAfter,omitempty" bson:"createdAfter"`
+	CreatedBefore *time.Time  `json:"createdBefore,omitempty" bson:"createdBefore"`
+	UpdatedAfter  *time.Time  `json:"updatedAfter,omitempty" bson:"updatedAfter"`
+	UpdatedBefore *time.Time  `json:"updatedBefore,omitempty" bson:"updatedBefore"`
+	HasBeenEdited *bool       `json:"hasBeenEdited,omitempty" bson:"hasBeenEdited"`
+}
+
+type BlogOrder struct {
+	Field     BlogOrderField `json:"field" bson:"field"`
+	Direction OrderDirection `json:"direction" bson:"direction"`
+}
+
+type BlogRevision struct {
+	BlogID       string    `json:"blogId" bson:"blogId"`
+	Version      int       `json:"version" bson:"version"`
+	Title        string    `json:"title" bson:"title"`
+	Content      string    `json:"content" bson:"content"`
+	Editor       *User     `json:"editor,omitempty" bson:"editor"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+	TitleChanged bool      `json:"titleChanged" bson:"titleChanged"`
+	LinesAdded   int       `json:"linesAdded" bson:"linesAdded"`
+	LinesRemoved int       `json:"linesRemoved" bson:"linesRemoved"`
+}
+
+type BlogRevisionDiff struct {
+	BlogID       string `json:"blogId" bson:"blogId"`
+	FromVersion  int    `json:"fromVersion" bson:"fromVersion"`
+	ToVersion    int    `json:"toVersion" bson:"toVersion"`
+	FromTitle    string `json:"fromTitle" bson:"fromTitle"`
+	ToTitle      string `json:"toTitle" bson:"toTitle"`
+	Diff         string `json:"diff" bson:"diff"`
+	LinesAdded   int    `json:"linesAdded" bson:"linesAdded"`
+	LinesRemoved int    `json:"linesRemoved" bson:"linesRemoved"`
+}
+
+type BlogSearchConnection struct {
+	Edges    []*BlogSearchEdge `json:"edges" bson:"edges"`
+	PageInfo *PageInfo         `json:"pageInfo" bson:"pageInfo"`
+}
+
+type BlogSearchEdge struct {
+	Cursor         string  `json:"cursor" bson:"cursor"`
+	Node           *Blog   `json:"node" bson:"node"`
+	Score          float64 `json:"score" bson:"score"`
+	TitleHighlight string  `json:"titleHighlight" bson:"titleHighlight"`
+	Snippet        string  `json:"snippet" bson:"snippet"`
+}
+
+type Comment struct {
+	ID         string             `json:"id" bson:"_id,omitempty"`
+	BlogID     string             `json:"blogId" bson:"blogId"`
+	ParentID   *string            `json:"parentId,omitempty" bson:"parentId"`
+	Depth      int                `json:"depth" bson:"depth"`
+	Content    string             `json:"content" bson:"content"`
+	Author     *User              `json:"author,omitempty" bson:"author"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  *time.Time         `json:"updatedAt,omitempty" bson:"updatedAt"`
+	Replies    *CommentConnection `json:"replies" bson:"replies"`
+	ReplyCount int                `json:"replyCount" bson:"replyCount"`
+}
+
+type CommentConnection struct {
+	Edges    []*CommentEdge `json:"edges" bson:"edges"`
+	PageInfo *PageInfo      `json:"pageInfo" bson:"pageInfo"`
+}
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor" bson:"cursor"`
+	Node   *Comment `json:"node" bson:"node"`
 }
 
 type DeleteBlog struct {
 	BlogID string `json:"blogId" bson:"blogId"`
 }
 
+type DeleteComment struct {
+	CommentID string `json:"commentId" bson:"commentId"`
+}
+
 type EditBlog struct {
-	BlogID  string `json:"blogId" bson:"blogId"`
-	Title   string `json:"title" bson:"title"`
-	Content string `json:"content" bson:"content"`
+	BlogID  string   `json:"blogId" bson:"blogId"`
+	Title   string   `json:"title" bson:"title"`
+	Content string   `json:"content" bson:"content"`
+	Tags    []string `json:"tags" bson:"tags"`
+}
+
+type EditComment struct {
+	CommentID string `json:"commentId" bson:"commentId"`
+	Content   string `json:"content" bson:"content"`
+}
+
+type FollowUser struct {
+	UserID string `json:"userId" bson:"userId"`
 }
 
 type LoginInput struct {
@@ -30,9 +161,20 @@ type LoginInput struct {
 	Password string `json:"password" bson:"password"`
 }
 
+type LogoutInput struct {
+	RefreshToken *string `json:"refreshToken,omitempty" bson:"refreshToken"`
+}
+
 type NewBlog struct {
-	Title   string `json:"title" bson:"title"`
-	Content string `json:"content" bson:"content"`
+	Title   string   `json:"title" bson:"title"`
+	Content string   `json:"content" bson:"content"`
+	Tags    []string `json:"tags" bson:"tags"`
+}
+
+type NewComment struct {
+	BlogID   string  `json:"blogId" bson:"blogId"`
+	ParentID *string `json:"parentId,omitempty" bson:"parentId"`
+	Content  string  `json:"content" bson:"content"`
 }
 
 type NewUser struct {
@@ -41,11 +183,370 @@ type NewUser struct {
 	Password string `json:"password" bson:"password"`
 }
 
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage" bson:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage" bson:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty" bson:"startCursor"`
+	EndCursor       *string `json:"endCursor,omitempty" bson:"endCursor"`
+}
+
+type PublishBlog struct {
+	BlogID string `json:"blogId" bson:"blogId"`
+}
+
+type ReactToBlog struct {
+	BlogID string       `json:"blogId" bson:"blogId"`
+	Kind   ReactionKind `json:"kind" bson:"kind"`
+}
+
+type ReactionCount struct {
+	Kind    ReactionKind `json:"kind" bson:"kind"`
+	Count   int          `json:"count" bson:"count"`
+	Reacted bool         `json:"reacted" bson:"reacted"`
+}
+
+type ReactionSummary struct {
+	Total  int              `json:"total" bson:"total"`
+	Counts []*ReactionCount `json:"counts" bson:"counts"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refreshToken" bson:"refreshToken"`
+}
+
+type RemoveReaction struct {
+	BlogID string       `json:"blogId" bson:"blogId"`
+	Kind   ReactionKind `json:"kind" bson:"kind"`
+}
+
+type RestoreBlog struct {
+	BlogID string `json:"blogId" bson:"blogId"`
+}
+
+type RestoreBlogRevision struct {
+	BlogID  string `json:"blogId" bson:"blogId"`
+	Version int    `json:"version" bson:"version"`
+}
+
+type ScheduleBlog struct {
+	BlogID    string    `json:"blogId" bson:"blogId"`
+	PublishAt time.Time `json:"publishAt" bson:"publishAt"`
+}
+
+type SetUserRole struct {
+	UserID string `json:"userId" bson:"userId"`
+	Role   Role   `json:"role" bson:"role"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag" bson:"tag"`
+	Count int    `json:"count" bson:"count"`
+}
+
+type UnfollowUser struct {
+	UserID string `json:"userId" bson:"userId"`
+}
+
+type UnlockUser struct {
+	UserID string `json:"userId" bson:"userId"`
+}
+
+type UnpublishBlog struct {
+	BlogID string `json:"blogId" bson:"blogId"`
+}
+
+type UpdateProfile struct {
+	DisplayName *string `json:"displayName,omitempty" bson:"displayName"`
+	Bio         *string `json:"bio,omitempty" bson:"bio"`
+	AvatarURL   *string `json:"avatarUrl,omitempty" bson:"avatarUrl"`
+}
+
 type User struct {
-	ID        string     `json:"id" bson:"_id,omitempty"`
-	Username  string     `json:"username" bson:"username"`
-	Email     string     `json:"email" bson:"email"`
-	Password  string     `json:"password" bson:"password"`
-	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
-	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt"`
+	ID          string          `json:"id" bson:"_id,omitempty"`
+	Username    string          `json:"username" bson:"username"`
+	Email       *string         `json:"email,omitempty" bson:"email"`
+	Role        Role            `json:"role" bson:"role"`
+	CreatedAt   time.Time       `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   *time.Time      `json:"updatedAt,omitempty" bson:"updatedAt"`
+	DisplayName *string         `json:"displayName,omitempty" bson:"displayName"`
+	Bio         *string         `json:"bio,omitempty" bson:"bio"`
+	AvatarURL   *string         `json:"avatarUrl,omitempty" bson:"avatarUrl"`
+	Followers   *UserConnection `json:"followers" bson:"followers"`
+	Following   *UserConnection `json:"following" bson:"following"`
+	Blogs       *BlogConnection `json:"blogs" bson:"blogs"`
+}
+
+type UserConnection struct {
+	Edges    []*UserEdge `json:"edges" bson:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo" bson:"pageInfo"`
+}
+
+type UserEdge struct {
+	Cursor     string    `json:"cursor" bson:"cursor"`
+	Node       *User     `json:"node" bson:"node"`
+	FollowedAt time.Time `json:"followedAt" bson:"followedAt"`
+}
+
+type AuditAction string
+
+const (
+	AuditActionLoginLocked   AuditAction = "LOGIN_LOCKED"
+	AuditActionIPLocked      AuditAction = "IP_LOCKED"
+	AuditActionLoginUnlocked AuditAction = "LOGIN_UNLOCKED"
+)
+
+var AllAuditAction = []AuditAction{
+	AuditActionLoginLocked,
+	AuditActionIPLocked,
+	AuditActionLoginUnlocked,
+}
+
+func (e AuditAction) IsValid() bool {
+	switch e {
+	case AuditActionLoginLocked, AuditActionIPLocked, AuditActionLoginUnlocked:
+		return true
+	}
+	return false
+}
+
+func (e AuditAction) String() string {
+	return string(e)
+}
+
+func (e *AuditAction) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditAction", str)
+	}
+	return nil
+}
+
+func (e AuditAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type BlogOrderField string
+
+const (
+	BlogOrderFieldTitle     BlogOrderField = "TITLE"
+	BlogOrderFieldCreatedAt BlogOrderField = "CREATED_AT"
+	BlogOrderFieldUpdatedAt BlogOrderField = "UPDATED_AT"
+)
+
+var AllBlogOrderField = []BlogOrderField{
+	BlogOrderFieldTitle,
+	BlogOrderFieldCreatedAt,
+	BlogOrderFieldUpdatedAt,
+}
+
+func (e BlogOrderField) IsValid() bool {
+	switch e {
+	case BlogOrderFieldTitle, BlogOrderFieldCreatedAt, BlogOrderFieldUpdatedAt:
+		return true
+	}
+	return false
+}
+
+func (e BlogOrderField) String() string {
+	return string(e)
+}
+
+func (e *BlogOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BlogOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BlogOrderField", str)
+	}
+	return nil
+}
+
+func (e BlogOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type BlogStatus string
+
+const (
+	BlogStatusDraft     BlogStatus = "DRAFT"
+	BlogStatusScheduled BlogStatus = "SCHEDULED"
+	BlogStatusPublished BlogStatus = "PUBLISHED"
+	BlogStatusArchived  BlogStatus = "ARCHIVED"
+)
+
+var AllBlogStatus = []BlogStatus{
+	BlogStatusDraft,
+	BlogStatusScheduled,
+	BlogStatusPublished,
+	BlogStatusArchived,
+}
+
+func (e BlogStatus) IsValid() bool {
+	switch e {
+	case BlogStatusDraft, BlogStatusScheduled, BlogStatusPublished, BlogStatusArchived:
+		return true
+	}
+	return false
+}
+
+func (e BlogStatus) String() string {
+	return string(e)
+}
+
+func (e *BlogStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BlogStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BlogStatus", str)
+	}
+	return nil
+}
+
+func (e BlogStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderDirection string
+
+const (
+	OrderDirectionAsc  OrderDirection = "ASC"
+	OrderDirectionDesc OrderDirection = "DESC"
+)
+
+var AllOrderDirection = []OrderDirection{
+	OrderDirectionAsc,
+	OrderDirectionDesc,
+}
+
+func (e OrderDirection) IsValid() bool {
+	switch e {
+	case OrderDirectionAsc, OrderDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e OrderDirection) String() string {
+	return string(e)
+}
+
+func (e *OrderDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderDirection", str)
+	}
+	return nil
+}
+
+func (e OrderDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReactionKind string
+
+const (
+	ReactionKindLike       ReactionKind = "LIKE"
+	ReactionKindLove       ReactionKind = "LOVE"
+	ReactionKindLaugh      ReactionKind = "LAUGH"
+	ReactionKindInsightful ReactionKind = "INSIGHTFUL"
+	ReactionKindSad        ReactionKind = "SAD"
+)
+
+var AllReactionKind = []ReactionKind{
+	ReactionKindLike,
+	ReactionKindLove,
+	ReactionKindLaugh,
+	ReactionKindInsightful,
+	ReactionKindSad,
+}
+
+func (e ReactionKind) IsValid() bool {
+	switch e {
+	case ReactionKindLike, ReactionKindLove, ReactionKindLaugh, ReactionKindInsightful, ReactionKindSad:
+		return true
+	}
+	return false
+}
+
+func (e ReactionKind) String() string {
+	return string(e)
+}
+
+func (e *ReactionKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReactionKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReactionKind", str)
+	}
+	return nil
+}
+
+func (e ReactionKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleReader Role = "READER"
+	RoleAuthor Role = "AUTHOR"
+	RoleEditor Role = "EDITOR"
+	RoleAdmin  Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleReader,
+	RoleAuthor,
+	RoleEditor,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleReader, RoleAuthor, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
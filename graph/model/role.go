@@ -0,0 +1,23 @@
+package model
+
+// roleRanks orders the roles, a role is allowed everything a lower role is allowed
+var roleRanks = map[Role]int{
+	RoleReader: 1,
+	RoleAuthor: 2,
+	RoleEditor: 3,
+	RoleAdmin:  4,
+}
+
+// Includes reports whether the role is allowed everything the other role is allowed
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[other]
+}
+
+// EffectiveRole returns the role of the user
+// the users stored before the roles were added are authors
+func (u *User) EffectiveRole() Role {
+	if u.Role == "" {
+		return RoleAuthor
+	}
+	return u.Role
+}
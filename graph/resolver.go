@@ -2,13 +2,62 @@
 //go:generate go run ./../cmd/gen/generate.go
 package graph
 
-import "github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/middleware"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/service"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	blogService service.BlogService
-	userService service.UserService
+	blogService     *service.BlogService
+	userService     *service.UserService
+	commentService  *service.CommentService
+	reactionService *service.ReactionService
+	followService   *service.FollowService
+}
+
+// NewResolver returns a resolver whose services use the repositories of the store
+// the services publish their changes to the events, which the subscriptions of the resolver read
+// and the logins are guarded by the policy
+func NewResolver(store database.Store, events *service.Events, loginPolicy service.LoginPolicy) *Resolver {
+	return &Resolver{
+		blogService:     service.NewBlogService(store.Blogs(), store.Comments(), store.Revisions(), store.Reactions(), events),
+		userService:     service.NewUserService(store.Users(), store.Tokens(), service.NewLoginGuard(store.LoginAttempts(), store.Audit(), loginPolicy)),
+		commentService:  service.NewCommentService(store.Comments(), store.Blogs(), events),
+		reactionService: service.NewReactionService(store.Reactions(), store.Blogs()),
+		followService:   service.NewFollowService(store.Follows(), store.Users()),
+	}
+}
+
+// loadUser returns the user of the snapshot stored with a blog, a comment or a revision
+// the users are loaded in batches when the response has loaders,
+// and the snapshot is kept when the user cannot be found
+func (r *Resolver) loadUser(ctx context.Context, snapshot *model.User) *model.User {
+	if snapshot == nil {
+		return nil
+	}
+
+	var (
+		user *model.User
+		err  error
+	)
+
+	if loaders := middleware.LoadersForContext(ctx); loaders != nil {
+		user, err = loaders.Users.Load(ctx, snapshot.ID)
+	} else {
+		user, err = r.userService.GetUser(ctx, snapshot.ID)
+	}
+
+	if err != nil {
+		return database.AuthorSnapshot(snapshot)
+	}
+
+	return user
 }
@@ -6,51 +6,99 @@ package graph
 
 import (
 	"context"
-	"errors"
 
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/middleware"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
 )
 
-// Register is the resolver for the register field.
-func (r *mutationResolver) Register(ctx context.Context, input model.NewUser) (string, error) {
-	var token string = r.userService.Register(input)
+// Author is the resolver for the author field.
+func (r *blogResolver) Author(ctx context.Context, obj *model.Blog) (*model.User, error) {
+	// the blog only stores a snapshot of the author
+	return r.loadUser(ctx, obj.Author), nil
+}
 
-	if token == "" {
-		return "", errors.New("registration failed")
-	}
+// Comments is the resolver for the comments field.
+func (r *blogResolver) Comments(ctx context.Context, obj *model.Blog, first *int, after *string) (*model.CommentConnection, error) {
+	return r.commentService.GetCommentsConnection(ctx, obj.ID, nil, first, after)
+}
 
-	return token, nil
+// CommentCount is the resolver for the commentCount field.
+func (r *blogResolver) CommentCount(ctx context.Context, obj *model.Blog) (int, error) {
+	return r.commentService.CountComments(ctx, obj.ID)
+}
+
+// Revisions is the resolver for the revisions field.
+func (r *blogResolver) Revisions(ctx context.Context, obj *model.Blog) ([]*model.BlogRevision, error) {
+	return r.blogService.GetRevisions(ctx, obj, middleware.ForContext(ctx))
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *blogResolver) Reactions(ctx context.Context, obj *model.Blog) (*model.ReactionSummary, error) {
+	return r.reactionService.GetReactionSummary(ctx, obj.ID, middleware.ForContext(ctx))
+}
+
+// Editor is the resolver for the editor field.
+func (r *blogRevisionResolver) Editor(ctx context.Context, obj *model.BlogRevision) (*model.User, error) {
+	// the revision only stores a snapshot of the editor
+	return r.loadUser(ctx, obj.Editor), nil
+}
+
+// Author is the resolver for the author field.
+func (r *commentResolver) Author(ctx context.Context, obj *model.Comment) (*model.User, error) {
+	// the comment only stores a snapshot of the author
+	return r.loadUser(ctx, obj.Author), nil
+}
+
+// Replies is the resolver for the replies field.
+func (r *commentResolver) Replies(ctx context.Context, obj *model.Comment, first *int, after *string) (*model.CommentConnection, error) {
+	return r.commentService.GetCommentsConnection(ctx, obj.BlogID, &obj.ID, first, after)
+}
+
+// ReplyCount is the resolver for the replyCount field.
+func (r *commentResolver) ReplyCount(ctx context.Context, obj *model.Comment) (int, error) {
+	return r.commentService.CountReplies(ctx, obj.ID)
+}
+
+// Register is the resolver for the register field.
+func (r *mutationResolver) Register(ctx context.Context, input model.NewUser) (*model.AuthToken, error) {
+	return r.userService.Register(ctx, input)
 }
 
 // Login is the resolver for the login field.
-func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (string, error) {
-	var token string = r.userService.Login(input)
+func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.AuthToken, error) {
+	// the failed logins are also counted for the IP address of the client
+	return r.userService.Login(ctx, input, middleware.ClientIPForContext(ctx))
+}
 
-	if token == "" {
-		return "", errors.New("login failed, invalid email or password")
-	}
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, input model.RefreshTokenInput) (*model.AuthToken, error) {
+	return r.userService.RefreshToken(ctx, input)
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context, input *model.LogoutInput) (bool, error) {
+	// the @auth directive guarantees the request is authenticated
+	token := middleware.TokenForContext(ctx)
+	return r.userService.Logout(ctx, *token, input)
+}
 
-	return token, nil
+// LogoutAllSessions is the resolver for the logoutAllSessions field.
+func (r *mutationResolver) LogoutAllSessions(ctx context.Context) (bool, error) {
+	user := middleware.ForContext(ctx)
+	return r.userService.LogoutAllSessions(ctx, user.ID)
 }
 
 // NewBlog is the resolver for the newBlog field.
 func (r *mutationResolver) NewBlog(ctx context.Context, input model.NewBlog) (*model.Blog, error) {
 	user := middleware.ForContext(ctx)
-	if user == nil {
-		return &model.Blog{}, errors.New("access denied")
-	}
-	blog, err := r.blogService.CreateBlog(input, *user)
+	blog, err := r.blogService.CreateBlog(ctx, input, *user)
 	return blog, err
 }
 
 // EditBlog is the resolver for the editBlog field.
 func (r *mutationResolver) EditBlog(ctx context.Context, input model.EditBlog) (*model.Blog, error) {
 	user := middleware.ForContext(ctx)
-	if user == nil {
-		return &model.Blog{}, errors.New("access denied")
-	}
-	blog, err := r.blogService.EditBlog(input, *user)
+	blog, err := r.blogService.EditBlog(ctx, input, *user)
 	if err != nil {
 		return &model.Blog{}, err
 	}
@@ -60,34 +108,268 @@ func (r *mutationResolver) EditBlog(ctx context.Context, input model.EditBlog) (
 // DeleteBlog is the resolver for the deleteBlog field.
 func (r *mutationResolver) DeleteBlog(ctx context.Context, input model.DeleteBlog) (bool, error) {
 	user := middleware.ForContext(ctx)
-	if user == nil {
-		return false, errors.New("access denied")
-	}
-	result := r.blogService.DeleteBlog(input, *user)
-	return result, nil
+	return r.blogService.DeleteBlog(ctx, input, *user)
+}
+
+// RestoreBlog is the resolver for the restoreBlog field.
+func (r *mutationResolver) RestoreBlog(ctx context.Context, input model.RestoreBlog) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.RestoreBlog(ctx, input, *user)
+}
+
+// PublishBlog is the resolver for the publishBlog field.
+func (r *mutationResolver) PublishBlog(ctx context.Context, input model.PublishBlog) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.PublishBlog(ctx, input, *user)
+}
+
+// UnpublishBlog is the resolver for the unpublishBlog field.
+func (r *mutationResolver) UnpublishBlog(ctx context.Context, input model.UnpublishBlog) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.UnpublishBlog(ctx, input, *user)
+}
+
+// ScheduleBlog is the resolver for the scheduleBlog field.
+func (r *mutationResolver) ScheduleBlog(ctx context.Context, input model.ScheduleBlog) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.ScheduleBlog(ctx, input, *user)
+}
+
+// ArchiveBlog is the resolver for the archiveBlog field.
+func (r *mutationResolver) ArchiveBlog(ctx context.Context, input model.ArchiveBlog) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.ArchiveBlog(ctx, input, *user)
+}
+
+// RestoreBlogRevision is the resolver for the restoreBlogRevision field.
+func (r *mutationResolver) RestoreBlogRevision(ctx context.Context, input model.RestoreBlogRevision) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.RestoreBlogRevision(ctx, input, *user)
+}
+
+// ReactToBlog is the resolver for the reactToBlog field.
+func (r *mutationResolver) ReactToBlog(ctx context.Context, input model.ReactToBlog) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.reactionService.ReactToBlog(ctx, input, *user)
+}
+
+// RemoveReaction is the resolver for the removeReaction field.
+func (r *mutationResolver) RemoveReaction(ctx context.Context, input model.RemoveReaction) (*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.reactionService.RemoveReaction(ctx, input, *user)
+}
+
+// AddComment is the resolver for the addComment field.
+func (r *mutationResolver) AddComment(ctx context.Context, input model.NewComment) (*model.Comment, error) {
+	user := middleware.ForContext(ctx)
+	return r.commentService.AddComment(ctx, input, *user)
+}
+
+// EditComment is the resolver for the editComment field.
+func (r *mutationResolver) EditComment(ctx context.Context, input model.EditComment) (*model.Comment, error) {
+	user := middleware.ForContext(ctx)
+	return r.commentService.EditComment(ctx, input, *user)
+}
+
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, input model.DeleteComment) (bool, error) {
+	user := middleware.ForContext(ctx)
+	return r.commentService.DeleteComment(ctx, input, *user)
 }
 
 // Blogs is the resolver for the blogs field.
-func (r *queryResolver) Blogs(ctx context.Context) ([]*model.Blog, error) {
-	blogs := r.blogService.GetAllBlogs()
+func (r *queryResolver) Blogs(ctx context.Context, filter *model.BlogFilter, orderBy *model.BlogOrder) ([]*model.Blog, error) {
+	return r.blogService.GetAllBlogs(ctx, filter, orderBy, middleware.ForContext(ctx))
+}
+
+// BlogsConnection is the resolver for the blogsConnection field.
+func (r *queryResolver) BlogsConnection(ctx context.Context, first *int, after *string, last *int, before *string, filter *model.BlogFilter, orderBy *model.BlogOrder) (*model.BlogConnection, error) {
+	return r.blogService.GetBlogsConnection(ctx, first, after, last, before, filter, orderBy, middleware.ForContext(ctx))
+}
+
+// SearchBlogs is the resolver for the searchBlogs field.
+func (r *queryResolver) SearchBlogs(ctx context.Context, query string, first *int, after *string) (*model.BlogSearchConnection, error) {
+	return r.blogService.SearchBlogs(ctx, query, first, after)
+}
+
+// Tags is the resolver for the tags field.
+func (r *queryResolver) Tags(ctx context.Context) ([]*model.TagCount, error) {
+	return r.blogService.GetTags(ctx)
+}
 
-	return blogs, nil
+// BlogsByTag is the resolver for the blogsByTag field.
+func (r *queryResolver) BlogsByTag(ctx context.Context, tag string, first *int, after *string) (*model.BlogConnection, error) {
+	return r.blogService.GetBlogsByTag(ctx, tag, first, after, middleware.ForContext(ctx))
 }
 
 // Blog is the resolver for the blog field.
 func (r *queryResolver) Blog(ctx context.Context, id string) (*model.Blog, error) {
-	blog, err := r.blogService.GetBlogByID(id)
+	blog, err := r.blogService.GetBlogByID(ctx, id, middleware.ForContext(ctx))
 	if err != nil {
 		return &model.Blog{}, err
 	}
 	return blog, nil
 }
 
+// BlogRevision is the resolver for the blogRevision field.
+func (r *queryResolver) BlogRevision(ctx context.Context, id string, version int) (*model.BlogRevision, error) {
+	return r.blogService.GetRevision(ctx, id, version, middleware.ForContext(ctx))
+}
+
+// BlogRevisionDiff is the resolver for the blogRevisionDiff field.
+func (r *queryResolver) BlogRevisionDiff(ctx context.Context, id string, fromVersion int, toVersion int) (*model.BlogRevisionDiff, error) {
+	return r.blogService.GetRevisionDiff(ctx, id, fromVersion, toVersion, middleware.ForContext(ctx))
+}
+
+// TrashedBlogs is the resolver for the trashedBlogs field.
+func (r *queryResolver) TrashedBlogs(ctx context.Context) ([]*model.Blog, error) {
+	user := middleware.ForContext(ctx)
+	return r.blogService.GetTrashedBlogs(ctx, *user)
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error) {
+	return r.userService.GetUser(ctx, id)
+}
+
+// AuditRecords is the resolver for the auditRecords field.
+func (r *queryResolver) AuditRecords(ctx context.Context, userID *string, first *int) ([]*model.AuditRecord, error) {
+	return r.userService.GetAuditRecords(ctx, userID, first)
+}
+
+// Feed is the resolver for the feed field.
+func (r *queryResolver) Feed(ctx context.Context, first *int, after *string) (*model.BlogConnection, error) {
+	user := middleware.ForContext(ctx)
+
+	authorIDs, err := r.followService.FollowingIDs(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.blogService.GetFeed(ctx, authorIDs, first, after)
+}
+
+// UpdateProfile is the resolver for the updateProfile field.
+func (r *mutationResolver) UpdateProfile(ctx context.Context, input model.UpdateProfile) (*model.User, error) {
+	user := middleware.ForContext(ctx)
+	return r.userService.UpdateProfile(ctx, input, user.ID)
+}
+
+// FollowUser is the resolver for the followUser field.
+func (r *mutationResolver) FollowUser(ctx context.Context, input model.FollowUser) (*model.User, error) {
+	user := middleware.ForContext(ctx)
+	return r.followService.FollowUser(ctx, input, *user)
+}
+
+// UnfollowUser is the resolver for the unfollowUser field.
+func (r *mutationResolver) UnfollowUser(ctx context.Context, input model.UnfollowUser) (*model.User, error) {
+	user := middleware.ForContext(ctx)
+	return r.followService.UnfollowUser(ctx, input, *user)
+}
+
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, input model.SetUserRole) (*model.User, error) {
+	return r.userService.SetUserRole(ctx, input)
+}
+
+// UnlockUser is the resolver for the unlockUser field.
+func (r *mutationResolver) UnlockUser(ctx context.Context, input model.UnlockUser) (*model.User, error) {
+	admin := middleware.ForContext(ctx)
+	return r.userService.UnlockUser(ctx, input, *admin)
+}
+
+// BlogCreated is the resolver for the blogCreated field.
+func (r *subscriptionResolver) BlogCreated(ctx context.Context) (<-chan *model.Blog, error) {
+	return r.blogService.SubscribeBlogCreated(ctx, middleware.ForContext(ctx))
+}
+
+// BlogUpdated is the resolver for the blogUpdated field.
+func (r *subscriptionResolver) BlogUpdated(ctx context.Context, id string) (<-chan *model.Blog, error) {
+	return r.blogService.SubscribeBlogUpdated(ctx, id, middleware.ForContext(ctx))
+}
+
+// BlogDeleted is the resolver for the blogDeleted field.
+func (r *subscriptionResolver) BlogDeleted(ctx context.Context) (<-chan *model.Blog, error) {
+	return r.blogService.SubscribeBlogDeleted(ctx, middleware.ForContext(ctx))
+}
+
+// CommentAdded is the resolver for the commentAdded field.
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, blogID string) (<-chan *model.Comment, error) {
+	return r.commentService.SubscribeCommentAdded(ctx, blogID, middleware.ForContext(ctx))
+}
+
+// Email is the resolver for the email field.
+func (r *userResolver) Email(ctx context.Context, obj *model.User) (*string, error) {
+	// only the user and the admins can see the email
+	viewer := middleware.ForContext(ctx)
+	if viewer == nil || (viewer.ID != obj.ID && !viewer.EffectiveRole().Includes(model.RoleAdmin)) {
+		return nil, nil
+	}
+
+	return obj.Email, nil
+}
+
+// Role is the resolver for the role field.
+func (r *userResolver) Role(ctx context.Context, obj *model.User) (model.Role, error) {
+	return obj.EffectiveRole(), nil
+}
+
+// Followers is the resolver for the followers field.
+func (r *userResolver) Followers(ctx context.Context, obj *model.User, first *int, after *string) (*model.UserConnection, error) {
+	return r.followService.GetFollowers(ctx, obj.ID, first, after)
+}
+
+// Following is the resolver for the following field.
+func (r *userResolver) Following(ctx context.Context, obj *model.User, first *int, after *string) (*model.UserConnection, error) {
+	return r.followService.GetFollowing(ctx, obj.ID, first, after)
+}
+
+// Blogs is the resolver for the blogs field.
+func (r *userResolver) Blogs(ctx context.Context, obj *model.User, first *int, after *string) (*model.BlogConnection, error) {
+	filter := &model.BlogFilter{AuthorID: &obj.ID}
+	return r.blogService.GetBlogsConnection(ctx, first, after, nil, nil, filter, nil, middleware.ForContext(ctx))
+}
+
+// User is the resolver for the user field.
+func (r *auditRecordResolver) User(ctx context.Context, obj *model.AuditRecord) (*model.User, error) {
+	// the record only stores the ID of the user
+	return r.loadUser(ctx, obj.User), nil
+}
+
+// Actor is the resolver for the actor field.
+func (r *auditRecordResolver) Actor(ctx context.Context, obj *model.AuditRecord) (*model.User, error) {
+	return r.loadUser(ctx, obj.Actor), nil
+}
+
+// AuditRecord returns AuditRecordResolver implementation.
+func (r *Resolver) AuditRecord() AuditRecordResolver { return &auditRecordResolver{r} }
+
+// Blog returns BlogResolver implementation.
+func (r *Resolver) Blog() BlogResolver { return &blogResolver{r} }
+
+// BlogRevision returns BlogRevisionResolver implementation.
+func (r *Resolver) BlogRevision() BlogRevisionResolver { return &blogRevisionResolver{r} }
+
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+type auditRecordResolver struct{ *Resolver }
+type blogResolver struct{ *Resolver }
+type blogRevisionResolver struct{ *Resolver }
+type commentResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -3,133 +3,372 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
 	"github.com/0x726f6f6b6965/go-simple-graphql/database"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
 	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // create a new service
-type UserService struct{}
+type UserService struct {
+	repository database.UserRepository
+	tokens     database.TokenRepository
+	guard      *LoginGuard
+}
+
+// NewUserService returns a user service backed by the given repositories
+// the failed logins are not limited without a login guard
+func NewUserService(repository database.UserRepository, tokens database.TokenRepository, guard *LoginGuard) *UserService {
+	return &UserService{repository: repository, tokens: tokens, guard: guard}
+}
 
-// Register returns JWT token for authentication
-func (u *UserService) Register(input model.NewUser) string {
+// Register returns the tokens for authentication
+// an email can only be registered once
+func (u *UserService) Register(ctx context.Context, input model.NewUser) (*model.AuthToken, error) {
 	// create a password with bcrypt encryption
 	bs, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return ""
+		return nil, apperror.Internal("registration failed", err)
 	}
 
 	// create a variable to store the encrypted password
 	var password string = string(bs)
 
-	// create a new user
-	var user model.User = model.User{
-		Username:  input.Username,
-		Email:     input.Email,
-		Password:  password,
-		CreatedAt: time.Now(),
+	// create a new user, the email is stored in the form it is looked up
+	var user database.User = database.User{
+		Username:     input.Username,
+		Email:        normalizeEmail(input.Email),
+		PasswordHash: password,
+		Role:         model.RoleAuthor,
+		CreatedAt:    time.Now(),
 	}
 
-	// get the "users" collection
-	var collection *mongo.Collection = database.GetCollection(utils.USER_COLLECTION)
+	// add a new user to the repository
+	userId, err := u.repository.CreateUser(ctx, user)
 
-	// add a new user to the "users" collection
-	res, err := collection.InsertOne(context.TODO(), user)
+	// the repository rejects an email that is already registered
+	if errors.Is(err, database.ErrDuplicate) {
+		return nil, apperror.Conflict("email is already registered")
+	}
 
-	// if a user failed to add, return an empty string
+	// if a user failed to add, return no tokens
 	if err != nil {
-		return ""
+		return nil, apperror.Internal("registration failed", err)
 	}
 
-	// convert ObjectID into the string
-	var userId string = res.InsertedID.(primitive.ObjectID).Hex()
+	// start a new session for the user
+	token, err := u.issueTokens(ctx, userId, "")
 
-	// generate a new JWT token
-	token, err := utils.GenerateNewAccessToken(userId)
+	// if token generation failed, return no tokens
+	if err != nil {
+		return nil, apperror.Internal("registration failed", err)
+	}
+
+	// return the tokens
+	return token.AuthToken, nil
+}
+
+// Login returns the tokens for authentication
+// the failed logins are counted for the email and the IP address of the client,
+// and the answers are the same whether the email is registered or not
+func (u *UserService) Login(ctx context.Context, input model.LoginInput, ip string) (*model.AuthToken, error) {
+	// refuse the logins of a locked email or IP address
+	if err := u.guard.check(ctx, input.Email, ip); err != nil {
+		return nil, err
+	}
 
-	// if token generation failed, return an empty string
+	// find the user data by email
+	user, err := u.repository.GetUserByEmail(ctx, normalizeEmail(input.Email))
+
+	// the password is compared even when a user is not found, so the answer takes as long
+	var hash []byte = dummyPasswordHash()
+	if err == nil {
+		hash = []byte(user.PasswordHash)
+	}
+
+	// if a user is not found or the password does not match, count the failure
+	if bcrypt.CompareHashAndPassword(hash, []byte(input.Password)) != nil || err != nil {
+		var userID string
+		if err == nil {
+			userID = user.ID
+		}
+
+		u.guard.fail(ctx, input.Email, ip, userID)
+		return nil, errLoginFailed
+	}
+
+	u.guard.succeed(ctx, input.Email)
+
+	// start a new session for the user
+	token, err := u.issueTokens(ctx, user.ID, "")
+
+	// if token generation failed, return no tokens
 	if err != nil {
-		return ""
+		return nil, errLoginFailed
 	}
 
-	// return the JWT token
-	return token
+	// return the tokens
+	return token.AuthToken, nil
 }
 
-// Login returns JWT token for authentication
-func (u *UserService) Login(input model.LoginInput) string {
-	// get the "users" collection from the database
-	var collection *mongo.Collection = database.GetCollection(utils.USER_COLLECTION)
+// UnlockUser allows the logins of the user again after too many failed attempts
+func (u *UserService) UnlockUser(ctx context.Context, input model.UnlockUser, admin model.User) (*model.User, error) {
+	user, err := u.repository.GetUserByID(ctx, input.UserID)
 
-	// create a variable to store a user from the database
-	var user *model.User = &model.User{}
-	// create a filter query to filter data by user email
-	filter := bson.M{"email": input.Email}
+	// if the ID is invalid or the user is not found, return an error
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("user not found")
+		}
+		return nil, apperror.Internal("get user failed", err)
+	}
 
-	// find the user data by email
-	var res *mongo.SingleResult = collection.FindOne(context.TODO(), filter)
+	if err := u.guard.unlock(ctx, user, admin); err != nil {
+		return nil, err
+	}
+
+	return user.Model(), nil
+}
+
+// GetAuditRecords returns the latest security events, of the user when userID is given
+func (u *UserService) GetAuditRecords(ctx context.Context, userID *string, first *int) ([]*model.AuditRecord, error) {
+	size, _, err := pageSize(first, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	if userID != nil {
+		id = *userID
+	}
+
+	return u.guard.records(ctx, id, size)
+}
+
+// RefreshToken exchanges a refresh token for a new pair of tokens
+// each refresh token can be used once, using it again revokes the whole session
+func (u *UserService) RefreshToken(ctx context.Context, input model.RefreshTokenInput) (*model.AuthToken, error) {
+	var now time.Time = time.Now()
+
+	// find the stored token by its hash
+	stored, err := u.tokens.GetRefreshTokenByHash(ctx, utils.HashToken(input.RefreshToken))
+	if err != nil {
+		return nil, apperror.Unauthenticated("refresh token is invalid")
+	}
+
+	// a revoked token that is used again may have been stolen,
+	// so every token of the session is revoked
+	if stored.RevokedAt != nil {
+		if err := u.tokens.RevokeTokenFamily(ctx, stored.FamilyID, now); err != nil {
+			return nil, apperror.Internal("refresh token failed", err)
+		}
+		return nil, apperror.Unauthenticated("refresh token has been revoked")
+	}
+
+	// check if the token is expired
+	if now.After(stored.ExpiresAt) {
+		return nil, apperror.Unauthenticated("refresh token is expired")
+	}
 
-	// if a user is not found, return the empty string
-	if err := res.Decode(user); err != nil {
-		return ""
+	// check if the user still exists
+	if _, err := u.repository.GetUserByID(ctx, stored.UserID); err != nil {
+		return nil, apperror.Unauthenticated("refresh token is invalid")
 	}
 
-	// compare the user password with the password from the input
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
+	// issue the next tokens of the session
+	token, err := u.issueTokens(ctx, stored.UserID, stored.FamilyID)
+	if err != nil {
+		return nil, apperror.Internal("refresh token failed", err)
+	}
+
+	// revoke the used token, the token may have been used concurrently
+	// in which case the session is revoked as well
+	if err := u.tokens.RotateRefreshToken(ctx, stored.ID, token.refreshTokenID, now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			u.tokens.RevokeTokenFamily(ctx, stored.FamilyID, now)
+			return nil, apperror.Unauthenticated("refresh token has been revoked")
+		}
+		return nil, apperror.Internal("refresh token failed", err)
+	}
+
+	// return the tokens
+	return token.AuthToken, nil
+}
 
-	// If the password does not match, return the empty string
+// SetUserRole changes the role of a user
+func (u *UserService) SetUserRole(ctx context.Context, input model.SetUserRole) (*model.User, error) {
+	user, err := u.repository.UpdateUserRole(ctx, input.UserID, input.Role, time.Now())
+
+	// if the ID is invalid or the user is not found, return an error
 	if err != nil {
-		return ""
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("user not found")
+		}
+		return nil, apperror.Internal("update user failed", err)
 	}
 
-	// generate a JWT token
-	token, err := utils.GenerateNewAccessToken(user.ID)
+	return user.Model(), nil
+}
+
+// Logout revokes the access token of the request
+// the session of the refresh token is revoked as well when it is given
+func (u *UserService) Logout(ctx context.Context, token utils.TokenMetadata, input *model.LogoutInput) (bool, error) {
+	var now time.Time = time.Now()
+
+	// the tokens issued before the token IDs were added can only be revoked all together
+	if token.ID == "" {
+		return u.LogoutAllSessions(ctx, token.UserId)
+	}
 
-	// if token generation failed, return the empty string
+	// revoke the access token until it expires
+	err := u.tokens.RevokeAccessToken(ctx, database.RevokedToken{
+		ID:        token.ID,
+		UserID:    token.UserId,
+		RevokedAt: now,
+		ExpiresAt: time.Unix(token.Expires, 0),
+	})
 	if err != nil {
-		return ""
+		return false, apperror.Internal("logout failed", err)
 	}
 
-	// return the JWT token
-	return token
+	if input == nil || input.RefreshToken == nil {
+		return true, nil
+	}
+
+	// revoke the session of the refresh token, the token of another user is ignored
+	stored, err := u.tokens.GetRefreshTokenByHash(ctx, utils.HashToken(*input.RefreshToken))
+	if err != nil || stored.UserID != token.UserId {
+		return true, nil
+	}
+
+	if err := u.tokens.RevokeTokenFamily(ctx, stored.FamilyID, now); err != nil {
+		return false, apperror.Internal("logout failed", err)
+	}
+
+	return true, nil
 }
 
-func (u *UserService) GetUser(id string) (*model.User, error) {
-	// create an ObjectID from id
-	userID, err := primitive.ObjectIDFromHex(id)
+// LogoutAllSessions revokes every access and refresh token issued to the user
+func (u *UserService) LogoutAllSessions(ctx context.Context, userId string) (bool, error) {
+	var now time.Time = time.Now()
+
+	// the revocation is kept until the last access token issued now has expired
+	if err := u.tokens.RevokeUserSessions(ctx, userId, now, now.Add(utils.AccessTokenLifetime())); err != nil {
+		return false, apperror.Internal("logout failed", err)
+	}
+
+	return true, nil
+}
 
-	// if ObjectID is failed to create, return an error
+// IsTokenRevoked reports whether the access token has been revoked by a logout
+func (u *UserService) IsTokenRevoked(ctx context.Context, token utils.TokenMetadata) (bool, error) {
+	return u.tokens.IsAccessTokenRevoked(ctx, token.ID, token.UserId, token.IssuedAt)
+}
+
+// PruneTokens removes the refresh tokens and revocations that are no longer needed
+// the pruning is repeated at every interval until the context is done
+func (u *UserService) PruneTokens(ctx context.Context, interval time.Duration) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := u.tokens.PruneTokens(ctx, now); err != nil {
+				log.Printf("prune tokens failed: %v", err)
+			}
+		}
+	}
+}
+
+// issuedToken represents the tokens of a session with the ID of the stored refresh token
+type issuedToken struct {
+	*model.AuthToken
+	refreshTokenID string
+}
+
+// issueTokens generates an access token and stores a new refresh token
+// an empty family ID starts a new session
+func (u *UserService) issueTokens(ctx context.Context, userId string, familyID string) (*issuedToken, error) {
+	// generate a new JWT token
+	accessToken, expiresAt, err := utils.GenerateAccessToken(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// generate a new refresh token
+	refreshToken, hash, err := utils.GenerateRefreshToken()
 	if err != nil {
-		return &model.User{}, errors.New("id is invalid")
+		return nil, err
 	}
 
-	// create a query to filter data by id (_id)
-	var query primitive.D = bson.D{{Key: "_id", Value: userID}}
+	// store the hash of the refresh token
+	var now time.Time = time.Now()
+	id, err := u.tokens.CreateRefreshToken(ctx, database.RefreshToken{
+		FamilyID:  familyID,
+		UserID:    userId,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(utils.RefreshTokenLifetime()),
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// get the "users" collection from the database
-	var collection *mongo.Collection = database.GetCollection(utils.USER_COLLECTION)
+	return &issuedToken{
+		AuthToken: &model.AuthToken{
+			AccessToken:          accessToken,
+			RefreshToken:         refreshToken,
+			AccessTokenExpiresAt: expiresAt,
+		},
+		refreshTokenID: id,
+	}, nil
+}
 
+func (u *UserService) GetUser(ctx context.Context, id string) (*model.User, error) {
 	// get the user data by ID
-	var userData *mongo.SingleResult = collection.FindOne(context.TODO(), query)
+	user, err := u.repository.GetUserByID(ctx, id)
 
-	// if user data is not found return an error
-	if userData.Err() != nil {
-		return &model.User{}, errors.New("user not found")
+	// if the ID is invalid or the user is not found, return an error
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return &model.User{}, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return &model.User{}, apperror.NotFound("user not found")
+		}
+		return &model.User{}, apperror.Internal("get user failed", err)
 	}
 
-	// create a variable to store the user from the database
-	var user *model.User = &model.User{}
+	// return the user without its credentials
+	return user.Model(), nil
+}
 
-	// decode the user data from the database into the "user" variable
-	userData.Decode(user)
+// GetUsers returns the users with the given IDs by ID
+// the IDs without a user are left out
+func (u *UserService) GetUsers(ctx context.Context, ids []string) (map[string]*model.User, error) {
+	users, err := u.repository.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, apperror.Internal("get users failed", err)
+	}
+
+	var models map[string]*model.User = make(map[string]*model.User, len(users))
+	for _, user := range users {
+		models[user.ID] = user.Model()
+	}
 
-	// return the user from the database
-	return user, nil
+	return models, nil
 }
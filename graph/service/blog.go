@@ -3,158 +3,455 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
 	"github.com/0x726f6f6b6965/go-simple-graphql/database"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
-	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // BlogService represents service component
-type BlogService struct{}
-
-func (b *BlogService) GetAllBlogs() []*model.Blog {
-	var (
-		query       primitive.D          = bson.D{{}}
-		findOptions *options.FindOptions = options.Find()
-	)
+type BlogService struct {
+	repository database.BlogRepository
+	comments   database.CommentRepository
+	revisions  database.RevisionRepository
+	reactions  database.ReactionRepository
+	events     *Events
+}
 
-	findOptions.SetSort(bson.D{{Key: "createdAt", Value: -1}})
+// NewBlogService returns a blog service backed by the given repositories
+// the changes of the blogs are published to the events, nil publishes nothing
+func NewBlogService(repository database.BlogRepository, comments database.CommentRepository, revisions database.RevisionRepository, reactions database.ReactionRepository, events *Events) *BlogService {
+	return &BlogService{repository: repository, comments: comments, revisions: revisions, reactions: reactions, events: events}
+}
 
-	cursor, err := database.GetCollection(utils.BLOG_COLLECTION).Find(context.TODO(), query, findOptions)
+func (b *BlogService) GetAllBlogs(ctx context.Context, filter *model.BlogFilter, orderBy *model.BlogOrder, viewer *model.User) ([]*model.Blog, error) {
+	blogs, err := b.repository.ListBlogs(ctx, blogQuery(filter, orderBy, viewer))
 	if err != nil {
-		return []*model.Blog{}
+		return nil, apperror.Internal("get blogs failed", err)
 	}
 
-	blogs := make([]*model.Blog, 0)
+	return blogs, nil
+}
+
+// GetBlogsConnection returns a page of blogs, newest first unless another order is given
+func (b *BlogService) GetBlogsConnection(ctx context.Context, first *int, after *string, last *int, before *string, filter *model.BlogFilter, orderBy *model.BlogOrder, viewer *model.User) (*model.BlogConnection, error) {
+	return b.blogsConnection(ctx, blogQuery(filter, orderBy, viewer), first, after, last, before)
+}
 
-	if err := cursor.All(context.TODO(), &blogs); err != nil {
-		return []*model.Blog{}
+// GetBlogsByTag returns a page of the blogs with the tag, newest first
+func (b *BlogService) GetBlogsByTag(ctx context.Context, tag string, first *int, after *string, viewer *model.User) (*model.BlogConnection, error) {
+	var query database.BlogQuery = database.BlogQuery{
+		Filter: database.BlogFilter{Tag: slugify(tag), Visibility: blogVisibility(viewer)},
+		SortBy: database.SortByCreatedAt,
 	}
 
-	return blogs
+	if query.Filter.Tag == "" {
+		return nil, apperror.BadUserInput("tag is invalid")
+	}
+
+	return b.blogsConnection(ctx, query, first, after, nil, nil)
 }
 
-func (b *BlogService) GetBlogByID(id string) (*model.Blog, error) {
-	blogID, err := primitive.ObjectIDFromHex(id)
+// GetTags returns the tags used by the blogs, the most used first
+func (b *BlogService) GetTags(ctx context.Context) ([]*model.TagCount, error) {
+	tags, err := b.repository.ListTags(ctx)
+	if err != nil {
+		return nil, apperror.Internal("get tags failed", err)
+	}
+
+	return tags, nil
+}
+
+// blogsConnection returns a page of the blogs selected by the query
+func (b *BlogService) blogsConnection(ctx context.Context, query database.BlogQuery, first *int, after *string, last *int, before *string) (*model.BlogConnection, error) {
+	size, backward, err := pageSize(first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	afterCursor, err := decodeCursor(after, query.SortBy)
 	if err != nil {
-		return &model.Blog{}, errors.New("id is invalid")
+		return nil, err
 	}
 
-	var (
-		query      primitive.D       = bson.D{{Key: "_id", Value: blogID}}
-		collection *mongo.Collection = database.GetCollection(utils.BLOG_COLLECTION)
-	)
+	beforeCursor, err := decodeCursor(before, query.SortBy)
+	if err != nil {
+		return nil, err
+	}
 
-	blogData := collection.FindOne(context.TODO(), query)
+	// read one more blog to know whether another page exists
+	query.After = afterCursor
+	query.Before = beforeCursor
+	query.Limit = size + 1
 
-	if blogData.Err() != nil {
-		return &model.Blog{}, errors.New("blog not found")
+	// a page read from the end is read in the reverse order
+	if backward {
+		query.Ascending = !query.Ascending
+		query.After, query.Before = beforeCursor, afterCursor
 	}
 
-	blog := &model.Blog{}
-	blogData.Decode(blog)
+	blogs, err := b.repository.ListBlogs(ctx, query)
+	if err != nil {
+		return nil, apperror.Internal("get blogs failed", err)
+	}
 
-	return blog, nil
+	var hasMore bool = len(blogs) > size
+	if hasMore {
+		blogs = blogs[:size]
+	}
+
+	// check whether blogs exist on the other side of the page
+	var hasOther bool = query.After != nil
+	if len(blogs) > 0 {
+		var cursor database.BlogCursor = database.CursorOf(blogs[0])
+
+		other, err := b.repository.ListBlogs(ctx, database.BlogQuery{
+			Filter:    query.Filter,
+			SortBy:    query.SortBy,
+			Ascending: !query.Ascending,
+			After:     &cursor,
+			Limit:     1,
+		})
+		if err != nil {
+			return nil, apperror.Internal("get blogs failed", err)
+		}
+		hasOther = len(other) > 0
+	}
+
+	if backward {
+		for i, j := 0, len(blogs)-1; i < j; i, j = i+1, j-1 {
+			blogs[i], blogs[j] = blogs[j], blogs[i]
+		}
+	}
+
+	var connection *model.BlogConnection = &model.BlogConnection{
+		Edges: make([]*model.BlogEdge, 0, len(blogs)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     hasMore,
+			HasPreviousPage: hasOther,
+		},
+	}
+
+	if backward {
+		connection.PageInfo.HasNextPage, connection.PageInfo.HasPreviousPage = hasOther, hasMore
+	}
+
+	for _, blog := range blogs {
+		connection.Edges = append(connection.Edges, &model.BlogEdge{
+			Cursor: encodeCursor(database.CursorOf(blog), query.SortBy),
+			Node:   blog,
+		})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
 }
 
-func (b *BlogService) CreateBlog(input model.NewBlog, user model.User) (*model.Blog, error) {
-	var (
-		blog model.Blog = model.Blog{
-			Title:     input.Title,
-			Content:   input.Content,
-			Author:    &user,
-			CreatedAt: time.Now(),
+// blogQuery translates the filter and the order of the schema into a repository query
+// of the blogs the viewer can read
+func blogQuery(filter *model.BlogFilter, orderBy *model.BlogOrder, viewer *model.User) database.BlogQuery {
+	var query database.BlogQuery = database.BlogQuery{SortBy: database.SortByCreatedAt}
+
+	if filter != nil {
+		query.Filter = database.BlogFilter{
+			CreatedAfter:  filter.CreatedAfter,
+			CreatedBefore: filter.CreatedBefore,
+			UpdatedAfter:  filter.UpdatedAfter,
+			UpdatedBefore: filter.UpdatedBefore,
+			Edited:        filter.HasBeenEdited,
 		}
-		collection *mongo.Collection = database.GetCollection(utils.BLOG_COLLECTION)
-	)
+		if filter.AuthorID != nil {
+			query.Filter.AuthorID = *filter.AuthorID
+		}
+		if filter.TitleContains != nil {
+			query.Filter.TitleContains = *filter.TitleContains
+		}
+		if filter.Status != nil {
+			query.Filter.Status = *filter.Status
+		}
+	}
 
-	result, err := collection.InsertOne(context.TODO(), blog)
+	query.Filter.Visibility = blogVisibility(viewer)
+
+	if orderBy != nil {
+		switch orderBy.Field {
+		case model.BlogOrderFieldTitle:
+			query.SortBy = database.SortByTitle
+		case model.BlogOrderFieldUpdatedAt:
+			query.SortBy = database.SortByUpdatedAt
+		}
+		query.Ascending = orderBy.Direction == model.OrderDirectionAsc
+	}
 
+	return query
+}
+
+func (b *BlogService) GetBlogByID(ctx context.Context, id string, viewer *model.User) (*model.Blog, error) {
+	blog, err := b.repository.GetBlogByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return &model.Blog{}, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return &model.Blog{}, apperror.NotFound("blog not found")
+		}
+		return &model.Blog{}, apperror.Internal("get blog failed", err)
+	}
+
+	// the blogs the viewer cannot read are not revealed
+	if !canReadBlog(blog, viewer) {
+		return &model.Blog{}, apperror.NotFound("blog not found")
+	}
+
+	return blog, nil
+}
+
+func (b *BlogService) CreateBlog(ctx context.Context, input model.NewBlog, user model.User) (*model.Blog, error) {
+	tags, err := normalizeTags(input.Tags)
 	if err != nil {
-		return &model.Blog{}, errors.New("create blog failed")
+		return &model.Blog{}, err
 	}
 
-	filter := bson.D{{Key: "_id", Value: result.InsertedID}}
-	createdRecord := collection.FindOne(context.TODO(), filter)
+	var blog model.Blog = model.Blog{
+		Title:     input.Title,
+		Content:   input.Content,
+		Tags:      tags,
+		Status:    model.BlogStatusDraft,
+		Author:    &user,
+		CreatedAt: time.Now(),
+	}
 
-	createdBlog := &model.Blog{}
+	createdBlog, err := b.repository.CreateBlog(ctx, blog)
+	if err != nil {
+		return &model.Blog{}, apperror.Internal("create blog failed", err)
+	}
 
-	createdRecord.Decode(createdBlog)
+	b.events.publishBlog(blogCreatedTopic, createdBlog)
 
 	return createdBlog, nil
 }
 
-func (b *BlogService) EditBlog(input model.EditBlog, user model.User) (*model.Blog, error) {
-	blogID, err := primitive.ObjectIDFromHex(input.BlogID)
+func (b *BlogService) EditBlog(ctx context.Context, input model.EditBlog, user model.User) (*model.Blog, error) {
+	tags, err := normalizeTags(input.Tags)
 	if err != nil {
-		return &model.Blog{}, errors.New("id is invalid")
+		return &model.Blog{}, err
 	}
 
-	var (
-		query primitive.D = bson.D{
-			{Key: "_id", Value: blogID},
-			{Key: "author._id", Value: user.ID},
+	return b.updateBlog(ctx, input.BlogID, user, database.BlogUpdate{
+		Title:   input.Title,
+		Content: input.Content,
+		Tags:    tags,
+	})
+}
+
+// updateBlog changes a blog and records the edited version as a new revision
+// the edit is not stored when its revision cannot be stored
+func (b *BlogService) updateBlog(ctx context.Context, id string, user model.User, update database.BlogUpdate) (*model.Blog, error) {
+	update.UpdatedAt = time.Now()
+	update.Editor = &user
+
+	// editors can edit the blogs of every author
+	editedBlog, err := b.repository.UpdateBlog(ctx, id, ownerFilter(user, model.RoleEditor), update)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return &model.Blog{}, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return &model.Blog{}, apperror.NotFound("blog not found")
 		}
+		return &model.Blog{}, apperror.Internal("update blog failed", err)
+	}
+
+	b.events.publishBlog(blogUpdatedTopic+editedBlog.ID, editedBlog)
+
+	return editedBlog, nil
+}
+
+// DeleteBlog moves a blog to the trash, its comments and revisions are kept until it is purged
+func (b *BlogService) DeleteBlog(ctx context.Context, input model.DeleteBlog, user model.User) (bool, error) {
+	// the deleted blog is sent to the subscribers who could read it
+	blog, err := b.GetBlogByID(ctx, input.BlogID, &user)
+	if err != nil {
+		return false, err
+	}
+
+	// admins can delete the blogs of every author
+	if !user.EffectiveRole().Includes(model.RoleAdmin) && (blog.Author == nil || blog.Author.ID != user.ID) {
+		return false, apperror.Forbidden("access denied")
+	}
+
+	var now time.Time = time.Now()
 
-		update primitive.D = bson.D{{
-			Key: "$set",
-			Value: bson.D{
-				{Key: "title", Value: input.Title},
-				{Key: "content", Value: input.Content},
-				{Key: "updatedAt", Value: time.Now()},
-			},
-		}}
-
-		collection *mongo.Collection = database.GetCollection(utils.BLOG_COLLECTION)
-	)
-
-	updateResult := collection.FindOneAndUpdate(
-		context.TODO(),
-		query,
-		update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	)
-
-	if updateResult.Err() != nil {
-		if err == mongo.ErrNoDocuments {
-			return &model.Blog{}, errors.New("blog not found")
+	if err := b.repository.TrashBlog(ctx, input.BlogID, ownerFilter(user, model.RoleAdmin), now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return false, apperror.NotFound("blog not found")
 		}
-		return &model.Blog{}, errors.New("update blog failed")
+		return false, apperror.Internal("delete blog failed", err)
 	}
 
-	var editedBlog *model.Blog = &model.Blog{}
+	blog.DeletedAt = &now
+	b.events.publishBlog(blogDeletedTopic, blog)
 
-	updateResult.Decode(editedBlog)
+	return true, nil
+}
 
-	return editedBlog, nil
+// PublishBlog publishes a blog now
+func (b *BlogService) PublishBlog(ctx context.Context, input model.PublishBlog, user model.User) (*model.Blog, error) {
+	var now time.Time = time.Now()
+
+	return b.updateStatus(ctx, input.BlogID, user, database.BlogStatusUpdate{
+		Status:      model.BlogStatusPublished,
+		PublishedAt: &now,
+	})
+}
+
+// UnpublishBlog turns a blog back into a draft
+func (b *BlogService) UnpublishBlog(ctx context.Context, input model.UnpublishBlog, user model.User) (*model.Blog, error) {
+	return b.updateStatus(ctx, input.BlogID, user, database.BlogStatusUpdate{Status: model.BlogStatusDraft})
 }
 
-func (b *BlogService) DeleteBlog(input model.DeleteBlog, user model.User) bool {
-	blogID, err := primitive.ObjectIDFromHex(input.BlogID)
+// ScheduleBlog publishes a blog automatically at the given time
+func (b *BlogService) ScheduleBlog(ctx context.Context, input model.ScheduleBlog, user model.User) (*model.Blog, error) {
+	if !input.PublishAt.After(time.Now()) {
+		return &model.Blog{}, apperror.BadUserInput("publication time must be in the future")
+	}
+
+	return b.updateStatus(ctx, input.BlogID, user, database.BlogStatusUpdate{
+		Status:    model.BlogStatusScheduled,
+		PublishAt: &input.PublishAt,
+	})
+}
+
+// ArchiveBlog withdraws a blog from the readers
+func (b *BlogService) ArchiveBlog(ctx context.Context, input model.ArchiveBlog, user model.User) (*model.Blog, error) {
+	return b.updateStatus(ctx, input.BlogID, user, database.BlogStatusUpdate{Status: model.BlogStatusArchived})
+}
+
+// updateStatus changes the publication state of a blog
+func (b *BlogService) updateStatus(ctx context.Context, id string, user model.User, update database.BlogStatusUpdate) (*model.Blog, error) {
+	// a blog published for the first time is announced as a new blog
+	var first bool
+	if update.Status == model.BlogStatusPublished {
+		previous, err := b.repository.GetBlogByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, database.ErrInvalidID):
+				return &model.Blog{}, apperror.BadUserInput("id is invalid")
+			case errors.Is(err, database.ErrNotFound):
+				return &model.Blog{}, apperror.NotFound("blog not found")
+			}
+			return &model.Blog{}, apperror.Internal("get blog failed", err)
+		}
+		first = previous.PublishedAt == nil
+	}
+
+	// editors can publish the blogs of every author
+	blog, err := b.repository.UpdateBlogStatus(ctx, id, ownerFilter(user, model.RoleEditor), update)
 	if err != nil {
-		return false
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return &model.Blog{}, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return &model.Blog{}, apperror.NotFound("blog not found")
+		}
+		return &model.Blog{}, apperror.Internal("update blog status failed", err)
 	}
 
-	var (
-		query primitive.D = bson.D{
-			{Key: "_id", Value: blogID},
-			{Key: "author._id", Value: user.ID},
+	b.publishStatus(blog, first)
+
+	return blog, nil
+}
+
+// publishStatus delivers the new state of the blog to its subscribers
+// the drafts are only announced to the viewers who can read them when they are created,
+// so a blog published for the first time is announced to every viewer as a new blog
+func (b *BlogService) publishStatus(blog *model.Blog, first bool) {
+	b.events.publishBlog(blogUpdatedTopic+blog.ID, blog)
+
+	if first && blog.Status == model.BlogStatusPublished {
+		b.events.publishBlog(blogCreatedTopic, blog)
+	}
+}
+
+// PublishScheduledBlogs publishes the scheduled blogs at every interval until the context is done
+func (b *BlogService) PublishScheduledBlogs(ctx context.Context, interval time.Duration) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			published, err := b.repository.PublishScheduledBlogs(ctx, now)
+			if err != nil {
+				log.Printf("publish scheduled blogs failed: %v", err)
+			}
+
+			// the blogs published before a failure are announced as well
+			for _, publication := range published {
+				b.publishStatus(publication.Blog, publication.First)
+			}
 		}
-		collection *mongo.Collection = database.GetCollection(utils.BLOG_COLLECTION)
-	)
+	}
+}
+
+// blogVisibility returns the blogs the viewer can read
+// editors read every blog, the other users read the published blogs and their own blogs
+func blogVisibility(viewer *model.User) *database.BlogVisibility {
+	if viewer == nil {
+		return &database.BlogVisibility{}
+	}
+
+	if viewer.EffectiveRole().Includes(model.RoleEditor) {
+		return nil
+	}
 
-	result, err := collection.DeleteOne(context.TODO(), query)
-	isFailed := err != nil || result.DeletedCount < 1
+	return &database.BlogVisibility{AuthorID: viewer.ID}
+}
+
+// canReadBlog reports whether the viewer can read the blog
+func canReadBlog(blog *model.Blog, viewer *model.User) bool {
+	if blog.Status == model.BlogStatusPublished {
+		return true
+	}
+
+	if viewer == nil {
+		return false
+	}
+
+	return viewer.EffectiveRole().Includes(model.RoleEditor) || (blog.Author != nil && blog.Author.ID == viewer.ID)
+}
+
+// ownerFilter returns the author whose blogs the user can change
+// nil means the blogs of every author when the user has the given role
+func ownerFilter(user model.User, role model.Role) *string {
+	if user.EffectiveRole().Includes(role) {
+		return nil
+	}
+	return &user.ID
+}
+
+// GetFeed returns a page of the published blogs of the authors, newest first
+func (b *BlogService) GetFeed(ctx context.Context, authorIDs []string, first *int, after *string) (*model.BlogConnection, error) {
+	// a viewer following nobody has an empty feed
+	if len(authorIDs) == 0 {
+		if _, _, err := pageSize(first, nil); err != nil {
+			return nil, err
+		}
+		return &model.BlogConnection{Edges: []*model.BlogEdge{}, PageInfo: &model.PageInfo{HasPreviousPage: after != nil}}, nil
+	}
 
-	if isFailed {
-		return !isFailed
+	var query database.BlogQuery = database.BlogQuery{
+		Filter: database.BlogFilter{AuthorIDs: authorIDs, Status: model.BlogStatusPublished},
+		SortBy: database.SortByCreatedAt,
 	}
 
-	return true
+	return b.blogsConnection(ctx, query, first, after, nil, nil)
 }
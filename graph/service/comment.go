@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// MaxCommentDepth is the deepest nesting level of a reply, top-level comments are at level 0
+const MaxCommentDepth = 3
+
+// CommentService represents the comment component
+type CommentService struct {
+	repository database.CommentRepository
+	blogs      database.BlogRepository
+	events     *Events
+}
+
+// NewCommentService returns a comment service backed by the given repositories
+// the new comments are published to the events, nil publishes nothing
+func NewCommentService(repository database.CommentRepository, blogs database.BlogRepository, events *Events) *CommentService {
+	return &CommentService{repository: repository, blogs: blogs, events: events}
+}
+
+// GetCommentsConnection returns a page of the comments replying to the parent, oldest first
+// a nil parent selects the top-level comments of the blog
+func (c *CommentService) GetCommentsConnection(ctx context.Context, blogID string, parentID *string, first *int, after *string) (*model.CommentConnection, error) {
+	size, _, err := pageSize(first, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	afterCursor, err := decodeCursor(after, database.SortByCreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// read one more comment to know whether another page exists
+	var query database.CommentQuery = database.CommentQuery{
+		BlogID:   blogID,
+		ParentID: parentID,
+		Limit:    size + 1,
+	}
+
+	if afterCursor != nil {
+		query.After = &database.CommentCursor{CreatedAt: afterCursor.CreatedAt, ID: afterCursor.ID}
+	}
+
+	comments, err := c.repository.ListComments(ctx, query)
+	if err != nil {
+		return nil, apperror.Internal("get comments failed", err)
+	}
+
+	var hasMore bool = len(comments) > size
+	if hasMore {
+		comments = comments[:size]
+	}
+
+	var connection *model.CommentConnection = &model.CommentConnection{
+		Edges: make([]*model.CommentEdge, 0, len(comments)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     hasMore,
+			HasPreviousPage: query.After != nil,
+		},
+	}
+
+	for _, comment := range comments {
+		var cursor database.BlogCursor = database.BlogCursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+
+		connection.Edges = append(connection.Edges, &model.CommentEdge{
+			Cursor: encodeCursor(cursor, database.SortByCreatedAt),
+			Node:   comment,
+		})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
+}
+
+// CountComments returns the number of comments of the blog, including the replies
+func (c *CommentService) CountComments(ctx context.Context, blogID string) (int, error) {
+	count, err := c.repository.CountComments(ctx, blogID)
+	if err != nil {
+		return 0, apperror.Internal("count comments failed", err)
+	}
+
+	return count, nil
+}
+
+// CountReplies returns the number of direct replies of the comment
+func (c *CommentService) CountReplies(ctx context.Context, commentID string) (int, error) {
+	count, err := c.repository.CountReplies(ctx, commentID)
+	if err != nil {
+		return 0, apperror.Internal("count replies failed", err)
+	}
+
+	return count, nil
+}
+
+// AddComment comments on a blog or replies to a comment of the blog
+func (c *CommentService) AddComment(ctx context.Context, input model.NewComment, user model.User) (*model.Comment, error) {
+	// check that the blog exists and the user can read it
+	if _, err := c.getBlog(ctx, input.BlogID, &user); err != nil {
+		return nil, err
+	}
+
+	var comment model.Comment = model.Comment{
+		BlogID:    input.BlogID,
+		Content:   input.Content,
+		Author:    &user,
+		CreatedAt: time.Now(),
+	}
+
+	// a reply is placed one level below its parent
+	if input.ParentID != nil {
+		parent, err := c.getComment(ctx, *input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.BlogID != input.BlogID {
+			return nil, apperror.NotFound("comment not found")
+		}
+
+		if parent.Depth >= MaxCommentDepth {
+			return nil, apperror.BadUserInput("replies are nested too deeply")
+		}
+
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
+	createdComment, err := c.repository.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, apperror.Internal("add comment failed", err)
+	}
+
+	c.events.publishComment(createdComment)
+
+	return createdComment, nil
+}
+
+// EditComment changes the content of a comment, only its author can edit it
+func (c *CommentService) EditComment(ctx context.Context, input model.EditComment, user model.User) (*model.Comment, error) {
+	comment, err := c.getComment(ctx, input.CommentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.Author == nil || comment.Author.ID != user.ID {
+		return nil, apperror.Forbidden("access denied")
+	}
+
+	editedComment, err := c.repository.UpdateComment(ctx, comment.ID, input.Content, time.Now())
+	if err != nil {
+		return nil, apperror.Internal("update comment failed", err)
+	}
+
+	return editedComment, nil
+}
+
+// DeleteComment removes a comment and its replies
+// the author of the comment, the author of the blog and the admins can delete it
+func (c *CommentService) DeleteComment(ctx context.Context, input model.DeleteComment, user model.User) (bool, error) {
+	comment, err := c.getComment(ctx, input.CommentID)
+	if err != nil {
+		return false, err
+	}
+
+	if !c.canModerate(ctx, comment, user) {
+		return false, apperror.Forbidden("access denied")
+	}
+
+	if err := c.repository.DeleteComment(ctx, comment.ID); err != nil {
+		return false, apperror.Internal("delete comment failed", err)
+	}
+
+	return true, nil
+}
+
+// canModerate reports whether the user can delete the comment
+func (c *CommentService) canModerate(ctx context.Context, comment *model.Comment, user model.User) bool {
+	if comment.Author != nil && comment.Author.ID == user.ID {
+		return true
+	}
+
+	if user.EffectiveRole().Includes(model.RoleAdmin) {
+		return true
+	}
+
+	blog, err := c.blogs.GetBlogByID(ctx, comment.BlogID)
+	if err != nil {
+		return false
+	}
+
+	return blog.Author != nil && blog.Author.ID == user.ID
+}
+
+// getBlog returns the blog when the viewer can read it
+func (c *CommentService) getBlog(ctx context.Context, id string, viewer *model.User) (*model.Blog, error) {
+	blog, err := c.blogs.GetBlogByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("blog not found")
+		}
+		return nil, apperror.Internal("get blog failed", err)
+	}
+
+	if !canReadBlog(blog, viewer) {
+		return nil, apperror.NotFound("blog not found")
+	}
+
+	return blog, nil
+}
+
+// getComment returns the comment with the given ID
+func (c *CommentService) getComment(ctx context.Context, id string) (*model.Comment, error) {
+	comment, err := c.repository.GetCommentByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("comment not found")
+		}
+		return nil, apperror.Internal("get comment failed", err)
+	}
+
+	return comment, nil
+}
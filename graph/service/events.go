@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/pubsub"
+)
+
+// the topics of the events
+const (
+	blogCreatedTopic  = "blogCreated"
+	blogUpdatedTopic  = "blogUpdated:"
+	blogDeletedTopic  = "blogDeleted"
+	commentAddedTopic = "commentAdded:"
+)
+
+// Events delivers the changes of the blogs and the comments to the subscribers
+// the events are published by the services after the changes are stored
+type Events struct {
+	blogs    *pubsub.Broker[*model.Blog]
+	comments *pubsub.Broker[*model.Comment]
+}
+
+// NewEvents returns an event bus without subscribers
+func NewEvents() *Events {
+	return &Events{
+		blogs:    pubsub.NewBroker[*model.Blog](pubsub.DefaultBufferSize),
+		comments: pubsub.NewBroker[*model.Comment](pubsub.DefaultBufferSize),
+	}
+}
+
+// publishBlog delivers the change of a blog to the subscribers of the topic
+// nothing is published without an event bus
+func (e *Events) publishBlog(topic string, blog *model.Blog) {
+	if e == nil {
+		return
+	}
+	e.blogs.Publish(topic, blog)
+}
+
+// publishComment delivers a new comment to the subscribers of its blog
+// nothing is published without an event bus
+func (e *Events) publishComment(comment *model.Comment) {
+	if e == nil {
+		return
+	}
+	e.comments.Publish(commentAddedTopic+comment.BlogID, comment)
+}
+
+// subscribeBlogs returns the changes of the blogs on the topic the viewer can read
+func (e *Events) subscribeBlogs(ctx context.Context, topic string, viewer *model.User) <-chan *model.Blog {
+	return e.blogs.Subscribe(ctx, topic, func(blog *model.Blog) bool {
+		return canReadBlog(blog, viewer)
+	})
+}
+
+// SubscribeBlogCreated returns the blogs created from now on that the viewer can read
+func (b *BlogService) SubscribeBlogCreated(ctx context.Context, viewer *model.User) (<-chan *model.Blog, error) {
+	return b.events.subscribeBlogs(ctx, blogCreatedTopic, viewer), nil
+}
+
+// SubscribeBlogUpdated returns the changes of the blog while the viewer can read it
+func (b *BlogService) SubscribeBlogUpdated(ctx context.Context, id string, viewer *model.User) (<-chan *model.Blog, error) {
+	blog, err := b.GetBlogByID(ctx, id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.events.subscribeBlogs(ctx, blogUpdatedTopic+blog.ID, viewer), nil
+}
+
+// SubscribeBlogDeleted returns the blogs moved to the trash from now on that the viewer could read
+func (b *BlogService) SubscribeBlogDeleted(ctx context.Context, viewer *model.User) (<-chan *model.Blog, error) {
+	return b.events.subscribeBlogs(ctx, blogDeletedTopic, viewer), nil
+}
+
+// SubscribeCommentAdded returns the comments added to the blog from now on
+func (c *CommentService) SubscribeCommentAdded(ctx context.Context, blogID string, viewer *model.User) (<-chan *model.Comment, error) {
+	// the comments of the blogs the viewer cannot read are not revealed
+	if _, err := c.getBlog(ctx, blogID, viewer); err != nil {
+		return nil, err
+	}
+
+	return c.events.comments.Subscribe(ctx, commentAddedTopic+blogID, nil), nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// FollowService represents the follow component
+type FollowService struct {
+	repository database.FollowRepository
+	users      database.UserRepository
+}
+
+// NewFollowService returns a follow service backed by the given repositories
+func NewFollowService(repository database.FollowRepository, users database.UserRepository) *FollowService {
+	return &FollowService{repository: repository, users: users}
+}
+
+// FollowUser makes the user follow another user, following the user again changes nothing
+func (f *FollowService) FollowUser(ctx context.Context, input model.FollowUser, user model.User) (*model.User, error) {
+	if input.UserID == user.ID {
+		return nil, apperror.BadUserInput("cannot follow yourself")
+	}
+
+	followee, err := f.getUser(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var follow database.Follow = database.Follow{
+		FollowerID: user.ID,
+		FolloweeID: followee.ID,
+		CreatedAt:  time.Now(),
+	}
+
+	if _, err := f.repository.Follow(ctx, follow); err != nil {
+		return nil, apperror.Internal("follow user failed", err)
+	}
+
+	return followee.Model(), nil
+}
+
+// UnfollowUser makes the user stop following another user
+func (f *FollowService) UnfollowUser(ctx context.Context, input model.UnfollowUser, user model.User) (*model.User, error) {
+	followee, err := f.getUser(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := f.repository.Unfollow(ctx, user.ID, followee.ID); err != nil {
+		return nil, apperror.Internal("unfollow user failed", err)
+	}
+
+	return followee.Model(), nil
+}
+
+// GetFollowers returns a page of the users following the user, the last follower first
+func (f *FollowService) GetFollowers(ctx context.Context, userID string, first *int, after *string) (*model.UserConnection, error) {
+	return f.followsConnection(ctx, database.FollowQuery{FolloweeID: userID}, first, after)
+}
+
+// GetFollowing returns a page of the users followed by the user, the last followed first
+func (f *FollowService) GetFollowing(ctx context.Context, userID string, first *int, after *string) (*model.UserConnection, error) {
+	return f.followsConnection(ctx, database.FollowQuery{FollowerID: userID}, first, after)
+}
+
+// FollowingIDs returns the IDs of every user followed by the user
+func (f *FollowService) FollowingIDs(ctx context.Context, userID string) ([]string, error) {
+	follows, err := f.repository.ListFollows(ctx, database.FollowQuery{FollowerID: userID})
+	if err != nil {
+		return nil, apperror.Internal("get following failed", err)
+	}
+
+	ids := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		ids = append(ids, follow.FolloweeID)
+	}
+
+	return ids, nil
+}
+
+// followsConnection returns a page of the users listed by the query
+func (f *FollowService) followsConnection(ctx context.Context, query database.FollowQuery, first *int, after *string) (*model.UserConnection, error) {
+	size, _, err := pageSize(first, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	afterCursor, err := decodeCursor(after, database.SortByCreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if afterCursor != nil {
+		query.After = &database.FollowCursor{CreatedAt: afterCursor.CreatedAt, UserID: afterCursor.ID}
+	}
+
+	// read one more follow to know whether another page exists
+	query.Limit = size + 1
+
+	follows, err := f.repository.ListFollows(ctx, query)
+	if err != nil {
+		return nil, apperror.Internal("get follows failed", err)
+	}
+
+	var hasMore bool = len(follows) > size
+	if hasMore {
+		follows = follows[:size]
+	}
+
+	var connection *model.UserConnection = &model.UserConnection{
+		Edges: make([]*model.UserEdge, 0, len(follows)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     hasMore,
+			HasPreviousPage: query.After != nil,
+		},
+	}
+
+	for _, follow := range follows {
+		var position database.FollowCursor = query.CursorOf(follow)
+
+		user, err := f.users.GetUserByID(ctx, position.UserID)
+		if err != nil {
+			log.Printf("get user %s of a follow failed: %v", position.UserID, err)
+			continue
+		}
+
+		connection.Edges = append(connection.Edges, &model.UserEdge{
+			Cursor:     encodeCursor(database.BlogCursor{CreatedAt: position.CreatedAt, ID: position.UserID}, database.SortByCreatedAt),
+			Node:       user.Model(),
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
+}
+
+// getUser returns the stored user with the given ID
+func (f *FollowService) getUser(ctx context.Context, id string) (*database.User, error) {
+	user, err := f.users.GetUserByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("user not found")
+		}
+		return nil, apperror.Internal("get user failed", err)
+	}
+
+	return user, nil
+}
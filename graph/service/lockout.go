@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginPolicy represents the protection of the logins against password guessing
+type LoginPolicy struct {
+	// MaxAccountFailures is the number of failed logins after which an email is locked
+	MaxAccountFailures int
+	// MaxIPFailures is the number of failed logins after which an IP address is locked
+	MaxIPFailures int
+	// FailureWindow is how long a failed login is counted
+	FailureWindow time.Duration
+	// LockoutDuration is how long the logins are refused once locked
+	LockoutDuration time.Duration
+	// BaseDelay delays the answer to the second failure of an email in a row, it doubles with every failure
+	BaseDelay time.Duration
+	// MaxDelay is the longest delay of an answer
+	MaxDelay time.Duration
+}
+
+// DefaultLoginPolicy locks an email after five failures and an IP address after twenty failures
+// for fifteen minutes
+var DefaultLoginPolicy LoginPolicy = LoginPolicy{
+	MaxAccountFailures: 5,
+	MaxIPFailures:      20,
+	FailureWindow:      15 * time.Minute,
+	LockoutDuration:    15 * time.Minute,
+	BaseDelay:          200 * time.Millisecond,
+	MaxDelay:           3 * time.Second,
+}
+
+var (
+	// errLoginFailed is returned for an unknown email and a wrong password alike
+	errLoginFailed = apperror.Unauthenticated("login failed, invalid email or password")
+	// errLoginLocked is returned while the email or the IP address is locked, registered or not
+	errLoginLocked = apperror.Forbidden("login locked after too many failed attempts, try again later")
+)
+
+// LoginGuard counts the failed logins of the emails and the IP addresses
+// the emails are counted whether they are registered or not, so the answers never tell
+type LoginGuard struct {
+	attempts database.LoginAttemptRepository
+	audit    database.AuditRepository
+	policy   LoginPolicy
+}
+
+// NewLoginGuard returns a login guard backed by the given repositories
+func NewLoginGuard(attempts database.LoginAttemptRepository, audit database.AuditRepository, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{attempts: attempts, audit: audit, policy: policy}
+}
+
+// check returns an error when the logins of the email or the IP address are locked
+// every login is allowed without a guard
+func (g *LoginGuard) check(ctx context.Context, email string, ip string) error {
+	if g == nil {
+		return nil
+	}
+
+	var now time.Time = time.Now()
+
+	for _, key := range loginKeys(email, ip) {
+		failures, err := g.attempts.GetLoginFailures(ctx, key)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return apperror.Internal("login failed", err)
+		}
+
+		if failures.Locked(now) {
+			return errLoginLocked
+		}
+	}
+
+	return nil
+}
+
+// fail counts a failed login, locks the email or the IP address past their thresholds
+// and delays the answer longer after every failure of the email
+// userID is empty when the email is not registered
+func (g *LoginGuard) fail(ctx context.Context, email string, ip string, userID string) {
+	if g == nil {
+		return
+	}
+
+	var (
+		now   time.Time = time.Now()
+		since time.Time = now.Add(-g.policy.FailureWindow)
+		count int
+	)
+
+	failures, err := g.attempts.RecordLoginFailure(ctx, emailLoginKey(email), now, since)
+	if err != nil {
+		log.Printf("record failed login failed: %v", err)
+	} else {
+		count = failures.Count
+		if failures.Count >= g.policy.MaxAccountFailures {
+			g.lock(ctx, emailLoginKey(email), database.AuditRecord{
+				Action:    model.AuditActionLoginLocked,
+				UserID:    userID,
+				Email:     normalizeEmail(email),
+				IP:        ip,
+				CreatedAt: now,
+			})
+		}
+	}
+
+	if ip != "" {
+		failures, err := g.attempts.RecordLoginFailure(ctx, ipLoginKey(ip), now, since)
+		if err != nil {
+			log.Printf("record failed login failed: %v", err)
+		} else if failures.Count >= g.policy.MaxIPFailures {
+			g.lock(ctx, ipLoginKey(ip), database.AuditRecord{
+				Action:    model.AuditActionIPLocked,
+				IP:        ip,
+				CreatedAt: now,
+			})
+		}
+	}
+
+	g.delay(ctx, count)
+}
+
+// succeed forgets the failed logins of the email
+// the failures of the IP address are kept, so a client cannot clear them with its own account
+func (g *LoginGuard) succeed(ctx context.Context, email string) {
+	if g == nil {
+		return
+	}
+
+	if err := g.attempts.ResetLoginFailures(ctx, emailLoginKey(email)); err != nil {
+		log.Printf("reset failed logins failed: %v", err)
+	}
+}
+
+// unlock allows the logins of the email of the user again and records the admin who unlocked them
+func (g *LoginGuard) unlock(ctx context.Context, user *database.User, actor model.User) error {
+	if g == nil {
+		return nil
+	}
+
+	if err := g.attempts.ResetLoginFailures(ctx, emailLoginKey(user.Email)); err != nil {
+		return apperror.Internal("unlock user failed", err)
+	}
+
+	g.record(ctx, database.AuditRecord{
+		Action:    model.AuditActionLoginUnlocked,
+		UserID:    user.ID,
+		ActorID:   actor.ID,
+		Email:     normalizeEmail(user.Email),
+		CreatedAt: time.Now(),
+	})
+
+	return nil
+}
+
+// lock refuses the logins of the key for the lockout duration and records the lockout
+func (g *LoginGuard) lock(ctx context.Context, key string, record database.AuditRecord) {
+	if err := g.attempts.LockLogin(ctx, key, record.CreatedAt.Add(g.policy.LockoutDuration)); err != nil {
+		log.Printf("lock logins failed: %v", err)
+		return
+	}
+
+	g.record(ctx, record)
+}
+
+// record stores the audit record, the change it describes is kept when it cannot be stored
+func (g *LoginGuard) record(ctx context.Context, record database.AuditRecord) {
+	if _, err := g.audit.CreateAuditRecord(ctx, record); err != nil {
+		log.Printf("record %s failed: %v", record.Action, err)
+	}
+}
+
+// delay waits longer after every failure in a row, starting from the second one
+// the wait ends early when the client goes away
+func (g *LoginGuard) delay(ctx context.Context, count int) {
+	if count < 2 || g.policy.BaseDelay <= 0 {
+		return
+	}
+
+	var wait time.Duration = g.policy.MaxDelay
+	if shift := count - 2; shift < 16 && g.policy.BaseDelay<<shift < g.policy.MaxDelay {
+		wait = g.policy.BaseDelay << shift
+	}
+
+	var timer *time.Timer = time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// records returns the latest audit records, of the user when userID is set
+func (g *LoginGuard) records(ctx context.Context, userID string, limit int) ([]*model.AuditRecord, error) {
+	if g == nil {
+		return []*model.AuditRecord{}, nil
+	}
+
+	records, err := g.audit.ListAuditRecords(ctx, userID, limit)
+	if err != nil {
+		return nil, apperror.Internal("get audit records failed", err)
+	}
+
+	var result []*model.AuditRecord = make([]*model.AuditRecord, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.Model())
+	}
+
+	return result, nil
+}
+
+// loginKeys returns the keys the failed logins are counted for
+func loginKeys(email string, ip string) []string {
+	if ip == "" {
+		return []string{emailLoginKey(email)}
+	}
+
+	return []string{emailLoginKey(email), ipLoginKey(ip)}
+}
+
+// emailLoginKey returns the key of the failed logins of an email
+func emailLoginKey(email string) string {
+	return "email:" + normalizeEmail(email)
+}
+
+// ipLoginKey returns the key of the failed logins from an IP address
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// normalizeEmail returns the email in the form it is registered, looked up and counted for the failed logins
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash returns a hash compared to the password when the email is unknown,
+// so the answer takes as long as for a registered email
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("password of an unknown email"), bcrypt.DefaultCost)
+	})
+
+	return dummyHash
+}
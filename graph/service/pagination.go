@@ -0,0 +1,147 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+)
+
+const (
+	// DefaultPageSize is used when neither first nor last is given
+	DefaultPageSize = 20
+	// MaxPageSize is the largest page a client can request
+	MaxPageSize = 100
+)
+
+// cursorData represents the content of an opaque cursor
+// only the value of the sort field is kept
+type cursorData struct {
+	Field     database.BlogSortField `json:"f,omitempty"`
+	CreatedAt *time.Time             `json:"c,omitempty"`
+	UpdatedAt *time.Time             `json:"u,omitempty"`
+	Title     *string                `json:"t,omitempty"`
+	ID        string                 `json:"i"`
+}
+
+// encodeCursor returns the opaque cursor of a position in the order of the field
+func encodeCursor(cursor database.BlogCursor, field database.BlogSortField) string {
+	var data cursorData = cursorData{Field: field, ID: cursor.ID}
+
+	switch field {
+	case database.SortByTitle:
+		data.Title = &cursor.Title
+	case database.SortByUpdatedAt:
+		if cursor.UpdatedAt != nil {
+			var updatedAt time.Time = cursor.UpdatedAt.UTC()
+			data.UpdatedAt = &updatedAt
+		}
+	default:
+		var createdAt time.Time = cursor.CreatedAt.UTC()
+		data.CreatedAt = &createdAt
+	}
+
+	raw, _ := json.Marshal(data)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor returns the position of an opaque cursor in the order of the field
+// an empty cursor means no position
+func decodeCursor(cursor *string, field database.BlogSortField) (*database.BlogCursor, error) {
+	if cursor == nil || *cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return nil, apperror.BadUserInput("cursor is invalid")
+	}
+
+	var data cursorData
+	if err := json.Unmarshal(raw, &data); err != nil || data.ID == "" {
+		return nil, apperror.BadUserInput("cursor is invalid")
+	}
+
+	// cursors without a field were created for the default order
+	if data.Field == "" {
+		data.Field = database.SortByCreatedAt
+	}
+
+	// a cursor can only be used with the order it was created for
+	if data.Field != field {
+		return nil, apperror.BadUserInput("cursor does not match the order")
+	}
+
+	var position *database.BlogCursor = &database.BlogCursor{ID: data.ID, UpdatedAt: data.UpdatedAt}
+
+	switch field {
+	case database.SortByTitle:
+		if data.Title == nil {
+			return nil, apperror.BadUserInput("cursor is invalid")
+		}
+		position.Title = *data.Title
+	case database.SortByCreatedAt:
+		if data.CreatedAt == nil {
+			return nil, apperror.BadUserInput("cursor is invalid")
+		}
+		position.CreatedAt = *data.CreatedAt
+	}
+
+	return position, nil
+}
+
+// offsetCursorData represents the content of an opaque cursor of ranked results
+type offsetCursorData struct {
+	Offset int `json:"o"`
+}
+
+// encodeOffsetCursor returns the opaque cursor of a position in ranked results
+func encodeOffsetCursor(offset int) string {
+	raw, _ := json.Marshal(offsetCursorData{Offset: offset})
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeOffsetCursor returns the position of an opaque cursor in ranked results
+// an empty cursor means no position
+func decodeOffsetCursor(cursor *string) (*int, error) {
+	if cursor == nil || *cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return nil, apperror.BadUserInput("cursor is invalid")
+	}
+
+	var data offsetCursorData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Offset < 0 {
+		return nil, apperror.BadUserInput("cursor is invalid")
+	}
+
+	return &data.Offset, nil
+}
+
+// pageSize returns the number of items of a page
+// and whether the page is read from the end of the range
+func pageSize(first *int, last *int) (int, bool, error) {
+	switch {
+	case first != nil && last != nil:
+		return 0, false, apperror.BadUserInput("first and last cannot be used together")
+	case first != nil:
+		if *first < 0 || *first > MaxPageSize {
+			return 0, false, apperror.BadUserInput("first must be between 0 and 100")
+		}
+		return *first, false, nil
+	case last != nil:
+		if *last < 0 || *last > MaxPageSize {
+			return 0, false, apperror.BadUserInput("last must be between 0 and 100")
+		}
+		return *last, true, nil
+	}
+
+	return DefaultPageSize, false, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// UpdateProfile replaces the profile of the user, a missing field is cleared
+func (u *UserService) UpdateProfile(ctx context.Context, input model.UpdateProfile, userId string) (*model.User, error) {
+	// the lengths and the avatar URL are checked by the constraints of the input
+	user, err := u.repository.UpdateUserProfile(ctx, userId, normalizeProfile(input), time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("user not found")
+		}
+		return nil, apperror.Internal("update profile failed", err)
+	}
+
+	return user.Model(), nil
+}
+
+// normalizeProfile trims the fields of the profile
+func normalizeProfile(input model.UpdateProfile) database.UserProfile {
+	return database.UserProfile{
+		DisplayName: trimOptional(input.DisplayName),
+		Bio:         trimOptional(input.Bio),
+		AvatarURL:   trimOptional(input.AvatarURL),
+	}
+}
+
+// trimOptional returns the value without the surrounding spaces, a missing value is empty
+func trimOptional(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
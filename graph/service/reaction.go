@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// ReactionService represents the reaction component
+type ReactionService struct {
+	repository database.ReactionRepository
+	blogs      database.BlogRepository
+}
+
+// NewReactionService returns a reaction service backed by the given repositories
+func NewReactionService(repository database.ReactionRepository, blogs database.BlogRepository) *ReactionService {
+	return &ReactionService{repository: repository, blogs: blogs}
+}
+
+// GetReactionSummary returns the number of reactions of every kind to the blog
+// and whether the viewer reacted with each kind
+func (s *ReactionService) GetReactionSummary(ctx context.Context, blogID string, viewer *model.User) (*model.ReactionSummary, error) {
+	counts, err := s.repository.CountReactions(ctx, blogID)
+	if err != nil {
+		return nil, apperror.Internal("get reactions failed", err)
+	}
+
+	var reacted map[model.ReactionKind]bool = make(map[model.ReactionKind]bool)
+	if viewer != nil {
+		kinds, err := s.repository.ListUserReactions(ctx, blogID, viewer.ID)
+		if err != nil {
+			return nil, apperror.Internal("get reactions failed", err)
+		}
+		for _, kind := range kinds {
+			reacted[kind] = true
+		}
+	}
+
+	var summary *model.ReactionSummary = &model.ReactionSummary{
+		Counts: make([]*model.ReactionCount, 0, len(model.AllReactionKind)),
+	}
+
+	for _, kind := range model.AllReactionKind {
+		summary.Total += counts[kind]
+		summary.Counts = append(summary.Counts, &model.ReactionCount{
+			Kind:    kind,
+			Count:   counts[kind],
+			Reacted: reacted[kind],
+		})
+	}
+
+	return summary, nil
+}
+
+// ReactToBlog adds the reaction of the user to a blog, reacting again with the same kind changes nothing
+func (s *ReactionService) ReactToBlog(ctx context.Context, input model.ReactToBlog, user model.User) (*model.Blog, error) {
+	blog, err := s.getBlog(ctx, input.BlogID, user)
+	if err != nil {
+		return &model.Blog{}, err
+	}
+
+	var reaction database.Reaction = database.Reaction{
+		BlogID:    blog.ID,
+		UserID:    user.ID,
+		Kind:      input.Kind,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := s.repository.AddReaction(ctx, reaction); err != nil {
+		return &model.Blog{}, apperror.Internal("react to blog failed", err)
+	}
+
+	return blog, nil
+}
+
+// RemoveReaction removes the reaction of the user to a blog
+func (s *ReactionService) RemoveReaction(ctx context.Context, input model.RemoveReaction, user model.User) (*model.Blog, error) {
+	blog, err := s.getBlog(ctx, input.BlogID, user)
+	if err != nil {
+		return &model.Blog{}, err
+	}
+
+	if _, err := s.repository.RemoveReaction(ctx, blog.ID, user.ID, input.Kind); err != nil {
+		return &model.Blog{}, apperror.Internal("remove reaction failed", err)
+	}
+
+	return blog, nil
+}
+
+// getBlog returns the blog when the user can read it
+func (s *ReactionService) getBlog(ctx context.Context, id string, user model.User) (*model.Blog, error) {
+	blog, err := s.blogs.GetBlogByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return nil, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return nil, apperror.NotFound("blog not found")
+		}
+		return nil, apperror.Internal("get blog failed", err)
+	}
+
+	if !canReadBlog(blog, &user) {
+		return nil, apperror.NotFound("blog not found")
+	}
+
+	return blog, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/diff"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// GetRevisions returns the revisions of the blog, newest first
+// the revisions are only visible to the author and the editors
+func (b *BlogService) GetRevisions(ctx context.Context, blog *model.Blog, viewer *model.User) ([]*model.BlogRevision, error) {
+	if !canEditBlog(blog, viewer) {
+		return nil, apperror.Forbidden("access denied")
+	}
+
+	revisions, err := b.revisions.ListRevisions(ctx, blog.ID)
+	if err != nil {
+		return nil, apperror.Internal("get revisions failed", err)
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns a version of the blog
+func (b *BlogService) GetRevision(ctx context.Context, id string, version int, viewer *model.User) (*model.BlogRevision, error) {
+	blog, err := b.GetBlogByID(ctx, id, viewer)
+	if err != nil {
+		return &model.BlogRevision{}, err
+	}
+
+	if !canEditBlog(blog, viewer) {
+		return &model.BlogRevision{}, apperror.Forbidden("access denied")
+	}
+
+	revision, err := b.revisions.GetRevision(ctx, id, version)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return &model.BlogRevision{}, apperror.NotFound("revision not found")
+		}
+		return &model.BlogRevision{}, apperror.Internal("get revision failed", err)
+	}
+
+	return revision, nil
+}
+
+// GetRevisionDiff compares two versions of the blog line by line
+func (b *BlogService) GetRevisionDiff(ctx context.Context, id string, fromVersion int, toVersion int, viewer *model.User) (*model.BlogRevisionDiff, error) {
+	from, err := b.GetRevision(ctx, id, fromVersion, viewer)
+	if err != nil {
+		return &model.BlogRevisionDiff{}, err
+	}
+
+	to, err := b.GetRevision(ctx, id, toVersion, viewer)
+	if err != nil {
+		return &model.BlogRevisionDiff{}, err
+	}
+
+	var lines []diff.Line = diff.Lines(from.Content, to.Content)
+	added, removed := diff.Count(lines)
+
+	return &model.BlogRevisionDiff{
+		BlogID:       id,
+		FromVersion:  from.Version,
+		ToVersion:    to.Version,
+		FromTitle:    from.Title,
+		ToTitle:      to.Title,
+		Diff:         diff.Format(lines),
+		LinesAdded:   added,
+		LinesRemoved: removed,
+	}, nil
+}
+
+// RestoreBlogRevision brings back the title and the content of a version of the blog
+// the restored version is recorded as a new revision, the tags are kept
+func (b *BlogService) RestoreBlogRevision(ctx context.Context, input model.RestoreBlogRevision, user model.User) (*model.Blog, error) {
+	revision, err := b.GetRevision(ctx, input.BlogID, input.Version, &user)
+	if err != nil {
+		return &model.Blog{}, err
+	}
+
+	blog, err := b.repository.GetBlogByID(ctx, input.BlogID)
+	if err != nil {
+		return &model.Blog{}, apperror.NotFound("blog not found")
+	}
+
+	return b.updateBlog(ctx, input.BlogID, user, database.BlogUpdate{
+		Title:   revision.Title,
+		Content: revision.Content,
+		Tags:    blog.Tags,
+	})
+}
+
+// canEditBlog reports whether the viewer can edit the blog
+func canEditBlog(blog *model.Blog, viewer *model.User) bool {
+	if viewer == nil {
+		return false
+	}
+
+	return viewer.EffectiveRole().Includes(model.RoleEditor) || (blog.Author != nil && blog.Author.ID == viewer.ID)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+	"github.com/0x726f6f6b6965/go-simple-graphql/search"
+)
+
+// snippetSize is the number of characters of a search snippet
+const snippetSize = 160
+
+// SearchBlogs returns a page of the blogs matching the query, the most relevant first
+func (b *BlogService) SearchBlogs(ctx context.Context, query string, first *int, after *string) (*model.BlogSearchConnection, error) {
+	var terms []string = search.Terms(query)
+	if len(terms) == 0 {
+		return nil, apperror.BadUserInput("search query is empty")
+	}
+
+	size, _, err := pageSize(first, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cursor of a result is its position, the next page starts after it
+	var offset int
+	position, err := decodeOffsetCursor(after)
+	if err != nil {
+		return nil, err
+	}
+	if position != nil {
+		offset = *position + 1
+	}
+
+	// read one more blog to know whether another page exists
+	hits, err := b.repository.SearchBlogs(ctx, query, offset, size+1)
+	if err != nil {
+		return nil, apperror.Internal("search blogs failed", err)
+	}
+
+	var hasMore bool = len(hits) > size
+	if hasMore {
+		hits = hits[:size]
+	}
+
+	var connection *model.BlogSearchConnection = &model.BlogSearchConnection{
+		Edges: make([]*model.BlogSearchEdge, 0, len(hits)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     hasMore,
+			HasPreviousPage: offset > 0,
+		},
+	}
+
+	for i, hit := range hits {
+		connection.Edges = append(connection.Edges, searchEdge(hit, terms, offset+i))
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
+}
+
+// searchEdge returns the edge of a search result with the matched words highlighted
+func searchEdge(hit database.BlogSearchHit, terms []string, position int) *model.BlogSearchEdge {
+	return &model.BlogSearchEdge{
+		Cursor:         encodeOffsetCursor(position),
+		Node:           hit.Blog,
+		Score:          hit.Score,
+		TitleHighlight: search.Highlight(hit.Blog.Title, terms, len(hit.Blog.Title)),
+		Snippet:        search.Highlight(hit.Blog.Content, terms, snippetSize),
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+)
+
+const (
+	// MaxBlogTags is the largest number of tags of a blog
+	MaxBlogTags = 10
+	// MaxTagLength is the longest tag slug, in characters
+	MaxTagLength = 32
+)
+
+// normalizeTags returns the sorted slugs of the tags without duplicates
+func normalizeTags(tags []string) ([]string, error) {
+	var (
+		seen  map[string]bool = make(map[string]bool, len(tags))
+		slugs []string        = make([]string, 0, len(tags))
+	)
+
+	for _, tag := range tags {
+		var slug string = slugify(tag)
+
+		if slug == "" || len([]rune(slug)) > MaxTagLength {
+			return nil, apperror.BadUserInput("tag is invalid")
+		}
+
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+
+	if len(slugs) > MaxBlogTags {
+		return nil, apperror.BadUserInput("too many tags")
+	}
+
+	sort.Strings(slugs)
+
+	return slugs, nil
+}
+
+// slugify returns the slug of the tag, the lowercase letters and digits
+// of the tag with every other run of characters replaced by a dash
+func slugify(tag string) string {
+	var (
+		builder strings.Builder
+		dash    bool
+	)
+
+	for _, char := range strings.ToLower(tag) {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			if dash && builder.Len() > 0 {
+				builder.WriteRune('-')
+			}
+			builder.WriteRune(char)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/0x726f6f6b6965/go-simple-graphql/apperror"
+	"github.com/0x726f6f6b6965/go-simple-graphql/database"
+	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
+)
+
+// GetTrashedBlogs returns the deleted blogs of the user, the last deleted first
+func (b *BlogService) GetTrashedBlogs(ctx context.Context, user model.User) ([]*model.Blog, error) {
+	blogs, err := b.repository.ListTrashedBlogs(ctx, user.ID)
+	if err != nil {
+		return nil, apperror.Internal("get blogs failed", err)
+	}
+
+	return blogs, nil
+}
+
+// RestoreBlog brings a deleted blog back from the trash
+func (b *BlogService) RestoreBlog(ctx context.Context, input model.RestoreBlog, user model.User) (*model.Blog, error) {
+	// admins can restore the blogs of every author
+	blog, err := b.repository.RestoreBlog(ctx, input.BlogID, ownerFilter(user, model.RoleAdmin))
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidID):
+			return &model.Blog{}, apperror.BadUserInput("id is invalid")
+		case errors.Is(err, database.ErrNotFound):
+			return &model.Blog{}, apperror.NotFound("blog not found")
+		}
+		return &model.Blog{}, apperror.Internal("restore blog failed", err)
+	}
+
+	b.events.publishBlog(blogUpdatedTopic+blog.ID, blog)
+
+	return blog, nil
+}
+
+// PurgeTrashedBlogs removes the blogs kept in the trash for longer than the retention
+// at every interval until the context is done
+func (b *BlogService) PurgeTrashedBlogs(ctx context.Context, interval time.Duration, retention time.Duration) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := b.purgeTrashedBlogs(ctx, now.Add(-retention)); err != nil {
+				log.Printf("purge trashed blogs failed: %v", err)
+			}
+		}
+	}
+}
+
+// purgeTrashedBlogs removes the blogs moved to the trash before the given time
+// together with their comments, revisions and reactions
+// the rows of a blog are removed before the blog, so a blog whose rows fail to be removed
+// stays in the trash and is purged again by the next run
+func (b *BlogService) purgeTrashedBlogs(ctx context.Context, before time.Time) error {
+	ids, err := b.repository.ListExpiredBlogs(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := b.comments.DeleteBlogComments(ctx, id); err != nil {
+			return fmt.Errorf("delete comments of blog %s: %w", id, err)
+		}
+
+		if err := b.revisions.DeleteBlogRevisions(ctx, id); err != nil {
+			return fmt.Errorf("delete revisions of blog %s: %w", id, err)
+		}
+
+		if err := b.reactions.DeleteBlogReactions(ctx, id); err != nil {
+			return fmt.Errorf("delete reactions of blog %s: %w", id, err)
+		}
+
+		// a blog restored in the meantime is kept
+		if err := b.repository.PurgeTrashedBlog(ctx, id, before); err != nil && !errors.Is(err, database.ErrNotFound) {
+			return fmt.Errorf("purge blog %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
@@ -9,12 +9,8 @@ import (
 
 	"github.com/0x726f6f6b6965/go-simple-graphql/database"
 	"github.com/0x726f6f6b6965/go-simple-graphql/graph/model"
-	"github.com/0x726f6f6b6965/go-simple-graphql/utils"
 
 	"github.com/go-faker/faker/v4"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -63,14 +59,23 @@ func CreateFaker[T any]() (T, error) {
 	return *fakerData, nil
 }
 
-func SeedUser() (model.User, error) {
+func SeedUser(store database.Store) (model.User, error) {
+	// create a new user, the password is not needed
+	user, _, err := SeedUserWithPassword(store)
+
+	// return the recently created user
+	return user, err
+}
+
+// SeedUserWithPassword creates a new user and returns its unencrypted password
+func SeedUserWithPassword(store database.Store) (model.User, string, error) {
 	// create a faker for user data
 	// this faker data will be stored in the database
 	userFaker, err := CreateFaker[UserFaker]()
 
 	// if faker creation failed, return an error
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, "", err
 	}
 
 	// create a password
@@ -78,42 +83,37 @@ func SeedUser() (model.User, error) {
 
 	// if password creation failed, return an error
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, "", err
 	}
 
 	// get the password into the string format
 	var password string = string(bs)
 
 	// create a new user in the "user" variable
-	var user model.User = model.User{
-		Username:  userFaker.Username,
-		Email:     userFaker.Email,
-		Password:  password,
-		CreatedAt: time.Now(),
+	var user database.User = database.User{
+		Username:     userFaker.Username,
+		Email:        userFaker.Email,
+		PasswordHash: password,
+		Role:         model.RoleAuthor,
+		CreatedAt:    time.Now(),
 	}
 
-	// get the "users" collection
-	var collection *mongo.Collection = database.GetCollection(utils.USER_COLLECTION)
-
-	// insert the user into the "users" collection
-	result, err := collection.InsertOne(context.TODO(), user)
+	// insert the user into the user repository
+	userId, err := store.Users().CreateUser(context.TODO(), user)
 
 	// if user insertion is failed, return an error
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, "", err
 	}
 
-	// assign the user password in original format (unencrypted)
-	user.Password = userFaker.Password
+	// assign the user ID
+	user.ID = userId
 
-	// assign the user ID in string format
-	user.ID = result.InsertedID.(primitive.ObjectID).Hex()
-
-	// return the recently created user
-	return user, nil
+	// return the recently created user with the password in original format (unencrypted)
+	return *user.Model(), userFaker.Password, nil
 }
 
-func SeedBlog() (model.Blog, error) {
+func SeedBlog(store database.Store) (model.Blog, error) {
 	// create a faker for blog data
 	// this faker data will be stored in the database
 	blogFaker, err := CreateFaker[BlogFaker]()
@@ -124,66 +124,74 @@ func SeedBlog() (model.Blog, error) {
 	}
 
 	// create data for the author
-	author, err := SeedUser()
+	author, err := SeedUser(store)
 
 	// if author creation failed, return an error
 	if err != nil {
 		return model.Blog{}, err
 	}
 
-	// create a new blog in the "blog" variable
-	var blog model.Blog = model.Blog{
-		Title:     blogFaker.Title,
-		Content:   blogFaker.Content,
-		Author:    &author,
-		CreatedAt: time.Now(),
-	}
-
-	// get the "blogs" collection
-	var collection *mongo.Collection = database.GetCollection(utils.BLOG_COLLECTION)
+	// create a new published blog in the "blog" variable
+	var (
+		now  time.Time  = time.Now()
+		blog model.Blog = model.Blog{
+			Title:       blogFaker.Title,
+			Content:     blogFaker.Content,
+			Author:      &author,
+			Status:      model.BlogStatusPublished,
+			PublishedAt: &now,
+			CreatedAt:   now,
+		}
+	)
 
-	// insert the blog into the "blogs" collection
-	result, err := collection.InsertOne(context.TODO(), blog)
+	// insert the blog into the blog repository
+	createdBlog, err := store.Blogs().CreateBlog(context.TODO(), blog)
 
 	// if blog insertion is failed, return an error
 	if err != nil {
 		return model.Blog{}, errors.New("create blog failed")
 	}
 
-	// create a filter to get the blog data by ID
-	var filter primitive.D = bson.D{{Key: "_id", Value: result.InsertedID}}
-
-	// get the recently created blog from the collection
-	var createdRecord *mongo.SingleResult = collection.FindOne(context.TODO(), filter)
-
-	// create a variable called "createdBlog" to store the recently created blog
-	var createdBlog *model.Blog = &model.Blog{}
-
-	// decode the recently created blog to the "createdBlog" variable
-	createdRecord.Decode(createdBlog)
-
 	// return the recently created blog
 	return *createdBlog, nil
 }
 
-func CleanSeeders() {
-	// get the "users" collection
-	var userCollection *mongo.Collection = database.GetCollection(utils.USER_COLLECTION)
+// SeedComment creates a new comment on the blog written by a new user
+func SeedComment(store database.Store, blog model.Blog) (model.Comment, error) {
+	// create data for the author
+	author, err := SeedUser(store)
 
-	// get the "blogs" collection
-	var blogCollection *mongo.Collection = database.GetCollection(utils.BLOG_COLLECTION)
+	// if author creation failed, return an error
+	if err != nil {
+		return model.Comment{}, err
+	}
 
-	// delete all data inside the "users" collection
-	userErr := userCollection.Drop(context.TODO())
+	// create a new top-level comment in the "comment" variable
+	var comment model.Comment = model.Comment{
+		BlogID:    blog.ID,
+		Content:   faker.Sentence(),
+		Author:    &author,
+		CreatedAt: time.Now(),
+	}
 
-	// delete all data inside the "blogs" collection
-	blogErr := blogCollection.Drop(context.TODO())
+	// insert the comment into the comment repository
+	createdComment, err := store.Comments().CreateComment(context.TODO(), comment)
 
-	// check if both operations are failed
-	var isFailed bool = userErr != nil || blogErr != nil
+	// if comment insertion is failed, return an error
+	if err != nil {
+		return model.Comment{}, errors.New("create comment failed")
+	}
 
-	// if both operations are failed, return an error
-	if isFailed {
+	// return the recently created comment
+	return *createdComment, nil
+}
+
+func CleanSeeders(store database.Store) {
+	// delete all data inside the store
+	err := store.Drop(context.TODO())
+
+	// if the operation is failed, return an error
+	if err != nil {
 		panic("error when deleting all data inside collection")
 	}
 }
@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBufferSize is the number of events kept for a subscriber that has not read them yet
+const DefaultBufferSize = 16
+
+// Broker delivers the events published on a topic to the subscribers of the topic
+// the events are only kept in memory, a subscriber that does not keep up misses events
+// instead of slowing down the publishers
+type Broker[T any] struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*subscriber[T]]struct{}
+	bufferSize  int
+}
+
+// subscriber represents a subscription to a topic
+type subscriber[T any] struct {
+	events chan T
+	accept func(T) bool
+}
+
+// NewBroker returns a broker without subscribers
+// every subscriber can fall behind by bufferSize events
+func NewBroker[T any](bufferSize int) *Broker[T] {
+	return &Broker[T]{
+		subscribers: make(map[string]map[*subscriber[T]]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe returns the events published on the topic until the context is done
+// the channel is closed when the context is done
+// accept selects the events delivered to the subscriber, nil accepts every event
+func (b *Broker[T]) Subscribe(ctx context.Context, topic string, accept func(T) bool) <-chan T {
+	var s *subscriber[T] = &subscriber[T]{
+		events: make(chan T, b.bufferSize),
+		accept: accept,
+	}
+
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[*subscriber[T]]struct{})
+	}
+	b.subscribers[topic][s] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		// the channel is closed while no event is being published
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[topic], s)
+		if len(b.subscribers[topic]) == 0 {
+			delete(b.subscribers, topic)
+		}
+		close(s.events)
+	}()
+
+	return s.events
+}
+
+// Publish delivers the event to the subscribers of the topic without waiting for them
+func (b *Broker[T]) Publish(topic string, event T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subscribers[topic] {
+		if s.accept != nil && !s.accept(event) {
+			continue
+		}
+
+		select {
+		case s.events <- event:
+		default:
+			// the buffer of the subscriber is full
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers of the topic
+func (b *Broker[T]) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers[topic])
+}
@@ -0,0 +1,90 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBroker_Publish(t *testing.T) {
+	var broker *Broker[int] = NewBroker[int](2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	all := broker.Subscribe(ctx, "numbers", nil)
+	even := broker.Subscribe(ctx, "numbers", func(n int) bool { return n%2 == 0 })
+	other := broker.Subscribe(ctx, "letters", nil)
+
+	broker.Publish("numbers", 1)
+	broker.Publish("numbers", 2)
+
+	if n := <-all; n != 1 {
+		t.Fatalf("expected 1, got %d", n)
+	}
+	if n := <-all; n != 2 {
+		t.Fatalf("expected 2, got %d", n)
+	}
+	if n := <-even; n != 2 {
+		t.Fatalf("expected 2, got %d", n)
+	}
+
+	select {
+	case n := <-other:
+		t.Fatalf("expected no event on another topic, got %d", n)
+	default:
+	}
+}
+
+func TestBroker_SlowSubscriber(t *testing.T) {
+	var broker *Broker[int] = NewBroker[int](1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := broker.Subscribe(ctx, "numbers", nil)
+
+	// the publisher does not wait for a subscriber whose buffer is full
+	broker.Publish("numbers", 1)
+	broker.Publish("numbers", 2)
+
+	if n := <-events; n != 1 {
+		t.Fatalf("expected 1, got %d", n)
+	}
+
+	select {
+	case n := <-events:
+		t.Fatalf("expected the second event to be dropped, got %d", n)
+	default:
+	}
+}
+
+func TestBroker_Unsubscribe(t *testing.T) {
+	var broker *Broker[int] = NewBroker[int](1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := broker.Subscribe(ctx, "numbers", nil)
+
+	if count := broker.Subscribers("numbers"); count != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", count)
+	}
+
+	cancel()
+
+	// the channel is closed once the context is done
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the channel to be closed")
+	}
+
+	if count := broker.Subscribers("numbers"); count != 0 {
+		t.Fatalf("expected no subscriber, got %d", count)
+	}
+
+	// publishing without subscribers does nothing
+	broker.Publish("numbers", 1)
+}
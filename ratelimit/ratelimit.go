@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate represents the number of requests allowed during a period
+// the requests refill continuously, so a client may burst up to the limit
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// PerMinute returns a rate of limit requests per minute
+func PerMinute(limit int) Rate {
+	return Rate{Limit: limit, Period: time.Minute}
+}
+
+// Result represents the decision for a request and the state of the bucket after it
+type Result struct {
+	// Allowed reports whether the request can proceed
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of requests that can be made now
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when Allowed
+	RetryAfter time.Duration
+}
+
+// Limiter applies a token bucket to every key
+// the buckets that are full again are forgotten, so idle clients use no memory
+type Limiter struct {
+	rate Rate
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket represents the tokens left to a key
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter returns a limiter allowing the rate to every key
+func NewLimiter(rate Rate) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Rate returns the rate allowed to every key
+func (l *Limiter) Rate() Rate {
+	return l.rate
+}
+
+// Allow takes a token from the bucket of the key
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var now time.Time = l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Limit), updated: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	var result Result = Result{Limit: l.rate.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = l.duration(float64(l.rate.Limit) - b.tokens)
+
+	return result
+}
+
+// refill adds the tokens earned since the last request of the bucket
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(l.rate.Limit), b.tokens+float64(l.rate.Limit)*elapsed.Seconds()/l.rate.Period.Seconds())
+		b.updated = now
+	}
+}
+
+// duration returns the time needed to earn the tokens
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if tokens <= 0 || l.rate.Limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(tokens * float64(l.rate.Period) / float64(l.rate.Limit)))
+}
+
+// sweep forgets the full buckets once per period
+// the caller must hold the lock of the limiter
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Period {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.rate.Limit) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock returns a limiter whose time only moves when advanced
+func fakeClock(rate Rate) (*Limiter, func(time.Duration)) {
+	var (
+		limiter *Limiter  = NewLimiter(rate)
+		now     time.Time = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+	limiter.now = func() time.Time { return now }
+
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiter_Burst(t *testing.T) {
+	limiter, _ := fakeClock(PerMinute(3))
+
+	for i := 0; i < 3; i++ {
+		result := limiter.Allow("client")
+		if !result.Allowed {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("request %d: expected %d remaining, got %d", i, 2-i, result.Remaining)
+		}
+	}
+
+	result := limiter.Allow("client")
+	if result.Allowed {
+		t.Fatal("expected the fourth request to be refused")
+	}
+	if result.RetryAfter != 20*time.Second {
+		t.Errorf("expected to retry after 20s, got %v", result.RetryAfter)
+	}
+	if result.Reset != time.Minute {
+		t.Errorf("expected a full bucket after 1m, got %v", result.Reset)
+	}
+
+	// the other keys have their own bucket
+	if !limiter.Allow("other").Allowed {
+		t.Error("expected another key to be allowed")
+	}
+}
+
+func TestLimiter_Refill(t *testing.T) {
+	limiter, advance := fakeClock(PerMinute(2))
+
+	limiter.Allow("client")
+	limiter.Allow("client")
+
+	// a token is earned every 30 seconds
+	advance(29 * time.Second)
+	if limiter.Allow("client").Allowed {
+		t.Fatal("expected the request to be refused before a token is earned")
+	}
+
+	advance(time.Second)
+	if !limiter.Allow("client").Allowed {
+		t.Fatal("expected the request to be allowed once a token is earned")
+	}
+
+	// the bucket never holds more than the limit
+	advance(time.Hour)
+	if remaining := limiter.Allow("client").Remaining; remaining != 1 {
+		t.Errorf("expected 1 remaining, got %d", remaining)
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	limiter, advance := fakeClock(PerMinute(2))
+
+	limiter.Allow("idle")
+	advance(2 * time.Minute)
+	limiter.Allow("client")
+
+	// the bucket of the idle key was full again, so it is forgotten
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("expected the full bucket to be forgotten")
+	}
+	if _, ok := limiter.buckets["client"]; !ok {
+		t.Error("expected the bucket in use to be kept")
+	}
+}
@@ -0,0 +1,175 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// titleWeight is how much more a term of the title counts than a term of the content
+	titleWeight = 2.0
+	// k1 and b are the BM25 parameters
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Hit represents a document matching a search
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// posting represents the occurrences of a term in a document
+type posting struct {
+	title   int
+	content int
+}
+
+// document represents the indexed form of a document
+type document struct {
+	terms  []string
+	length float64
+}
+
+// Index represents an in-process inverted index of titled documents
+// it is safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]posting
+	docs     map[string]document
+	length   float64
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{
+		postings: map[string]map[string]posting{},
+		docs:     map[string]document{},
+	}
+}
+
+// Add indexes the document, an existing document with the same ID is replaced
+func (i *Index) Add(id string, title string, content string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+
+	var occurrences map[string]posting = map[string]posting{}
+
+	var titleTerms []string = Tokenize(title)
+	for _, term := range titleTerms {
+		p := occurrences[term]
+		p.title++
+		occurrences[term] = p
+	}
+
+	var contentTerms []string = Tokenize(content)
+	for _, term := range contentTerms {
+		p := occurrences[term]
+		p.content++
+		occurrences[term] = p
+	}
+
+	var doc document = document{
+		terms:  make([]string, 0, len(occurrences)),
+		length: titleWeight*float64(len(titleTerms)) + float64(len(contentTerms)),
+	}
+
+	for term, p := range occurrences {
+		if i.postings[term] == nil {
+			i.postings[term] = map[string]posting{}
+		}
+		i.postings[term][id] = p
+		doc.terms = append(doc.terms, term)
+	}
+
+	i.docs[id] = doc
+	i.length += doc.length
+}
+
+// Remove removes the document from the index
+func (i *Index) Remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+}
+
+// Clear removes all documents from the index
+func (i *Index) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.postings = map[string]map[string]posting{}
+	i.docs = map[string]document{}
+	i.length = 0
+}
+
+// remove removes the document, the caller must hold the lock
+func (i *Index) remove(id string) {
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range doc.terms {
+		delete(i.postings[term], id)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+
+	delete(i.docs, id)
+	i.length -= doc.length
+}
+
+// Search returns the documents containing any term of the query
+// the best matches come first, documents with the same score are sorted by ID
+func (i *Index) Search(query string) []Hit {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if len(i.docs) == 0 {
+		return []Hit{}
+	}
+
+	var (
+		count   float64            = float64(len(i.docs))
+		average float64            = i.length / count
+		scores  map[string]float64 = map[string]float64{}
+	)
+
+	for _, term := range Terms(query) {
+		var postings map[string]posting = i.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		// rare terms are worth more than common ones
+		var idf float64 = math.Log(1 + (count-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+
+		for id, p := range postings {
+			var (
+				frequency float64 = titleWeight*float64(p.title) + float64(p.content)
+				norm      float64 = 1 - b + b*i.docs[id].length/average
+			)
+			scores[id] += idf * frequency * (k1 + 1) / (frequency + k1*norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	return hits
+}
@@ -0,0 +1,52 @@
+package search
+
+import "testing"
+
+func TestIndex_Search(t *testing.T) {
+	var index *Index = NewIndex()
+
+	index.Add("1", "Learning Go", "Go is a simple language.")
+	index.Add("2", "Cooking", "A recipe that takes time, like learning go.")
+	index.Add("3", "Gardening", "Nothing to see here.")
+
+	hits := index.Search("GO learning")
+	if len(hits) != 2 {
+		t.Fatalf("expected 2 hits, got %v", hits)
+	}
+
+	// a match in the title is worth more than a match in the content
+	if hits[0].ID != "1" || hits[0].Score <= hits[1].Score {
+		t.Fatalf("unexpected ranking: %v", hits)
+	}
+
+	// replacing a document removes its old terms
+	index.Add("1", "Learning Rust", "Rust is a language.")
+	if hits := index.Search("simple"); len(hits) != 0 {
+		t.Fatalf("expected no hits, got %v", hits)
+	}
+
+	index.Remove("2")
+	if hits := index.Search("recipe"); len(hits) != 0 {
+		t.Fatalf("expected no hits, got %v", hits)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	var cases = []struct {
+		text     string
+		terms    []string
+		size     int
+		expected string
+	}{
+		{"Go is <simple>", []string{"simple"}, 100, "Go is &lt;<mark>simple</mark>&gt;"},
+		{"one two three four five six seven", []string{"six"}, 16, "…five <mark>six</mark> seven"},
+		{"one two three four five", []string{"missing"}, 9, "one two…"},
+		{"Über über", []string{"über"}, 100, "<mark>Über</mark> <mark>über</mark>"},
+	}
+
+	for _, c := range cases {
+		if got := Highlight(c.text, c.terms, c.size); got != c.expected {
+			t.Errorf("Highlight(%q): expected %q, got %q", c.text, c.expected, got)
+		}
+	}
+}
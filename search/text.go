@@ -0,0 +1,158 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// highlightStart and highlightEnd wrap the matched words of a snippet
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	// ellipsis marks text cut from a snippet
+	ellipsis = "…"
+)
+
+// span represents the byte offsets of a word in a text
+type span struct {
+	start int
+	end   int
+}
+
+// isWordRune reports whether the rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// words returns the positions of the words of the text
+func words(text string) []span {
+	var (
+		spans []span
+		start int = -1
+	)
+
+	for offset, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = offset
+		case !isWordRune(r) && start >= 0:
+			spans = append(spans, span{start: start, end: offset})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, span{start: start, end: len(text)})
+	}
+
+	return spans
+}
+
+// Tokenize returns the lower-cased words of the text in order
+func Tokenize(text string) []string {
+	var spans []span = words(text)
+
+	tokens := make([]string, 0, len(spans))
+	for _, s := range spans {
+		tokens = append(tokens, strings.ToLower(text[s.start:s.end]))
+	}
+
+	return tokens
+}
+
+// Terms returns the distinct lower-cased words of a query
+func Terms(query string) []string {
+	var (
+		seen  map[string]bool = map[string]bool{}
+		terms []string
+	)
+
+	for _, token := range Tokenize(query) {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+
+	return terms
+}
+
+// Highlight returns an HTML-escaped excerpt of the text of about size runes
+// the excerpt starts near the first word matching a term
+// and every matching word is wrapped in <mark> tags
+func Highlight(text string, terms []string, size int) string {
+	var (
+		spans   []span          = words(text)
+		wanted  map[string]bool = map[string]bool{}
+		matched []bool          = make([]bool, len(spans))
+		first   int             = -1
+	)
+
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	for i, s := range spans {
+		if wanted[strings.ToLower(text[s.start:s.end])] {
+			matched[i] = true
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+
+	// place the first match after a short lead-in
+	var start int
+	if first >= 0 {
+		start = spans[first].start
+		for i := first - 1; i >= 0 && utf8.RuneCountInString(text[spans[i].start:spans[first].start]) <= size/3; i-- {
+			start = spans[i].start
+		}
+	}
+
+	// cut the excerpt after the last word that fits
+	var end int = len(text)
+	if utf8.RuneCountInString(text[start:]) > size {
+		end = start
+		for _, s := range spans {
+			if s.start < start {
+				continue
+			}
+			if utf8.RuneCountInString(text[start:s.end]) > size {
+				break
+			}
+			end = s.end
+		}
+		// a single word longer than the excerpt is cut
+		if end == start {
+			end = start + len(string([]rune(text[start:])[:size]))
+		}
+	}
+
+	var builder strings.Builder
+
+	if start > 0 {
+		builder.WriteString(ellipsis)
+	}
+
+	var position int = start
+	for i, s := range spans {
+		if !matched[i] || s.start < start || s.end > end {
+			continue
+		}
+		builder.WriteString(html.EscapeString(text[position:s.start]))
+		builder.WriteString(highlightStart)
+		builder.WriteString(html.EscapeString(text[s.start:s.end]))
+		builder.WriteString(highlightEnd)
+		position = s.end
+	}
+	builder.WriteString(html.EscapeString(text[position:end]))
+
+	if end < len(text) {
+		builder.WriteString(ellipsis)
+	}
+
+	return builder.String()
+}
@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"os"
 	"strconv"
@@ -12,14 +17,21 @@ import (
 
 // TokenMetadata represents JWT token metadata
 type TokenMetadata struct {
-	Expires int64
-	UserId  string
+	Expires  int64
+	UserId   string
+	ID       string
+	IssuedAt time.Time
 }
 
 // ExtractTokenMetadata extracts JWT token metadata
 func ExtractTokenMetadata(r *http.Request) (*TokenMetadata, error) {
+	return extractHeaderMetadata(r.Header.Get("Authorization"))
+}
+
+// extractHeaderMetadata extracts the metadata of the JWT token of an Authorization header
+func extractHeaderMetadata(header string) (*TokenMetadata, error) {
 	// verify the JWT token
-	token, err := verifyToken(r)
+	token, err := verifyToken(header)
 
 	// if verification is failed, return an error
 	if err != nil {
@@ -38,11 +50,17 @@ func ExtractTokenMetadata(r *http.Request) (*TokenMetadata, error) {
 		expires := int64(claims["exp"].(float64))
 		// set user ID for the token
 		userId := claims["userId"].(string)
+		// set token ID, the tokens issued before it was added have none
+		id, _ := claims["jti"].(string)
+		// set token issue time with millisecond precision
+		issuedAt, _ := claims["iat"].(float64)
 
 		// return the JWT token metadata
 		return &TokenMetadata{
-			Expires: expires,
-			UserId:  userId,
+			Expires:  expires,
+			UserId:   userId,
+			ID:       id,
+			IssuedAt: time.UnixMilli(int64(issuedAt * 1000)),
 		}, nil
 	}
 
@@ -52,42 +70,117 @@ func ExtractTokenMetadata(r *http.Request) (*TokenMetadata, error) {
 
 // GenerateNewAccessToken generates a new JWT token
 func GenerateNewAccessToken(userId string) (string, error) {
+	token, _, err := GenerateAccessToken(userId)
+	return token, err
+}
+
+// GenerateAccessToken generates a new JWT token and returns its expiration time
+func GenerateAccessToken(userId string) (string, time.Time, error) {
 	// get the JWT secret key from .env file
 	secret := os.Getenv("JWT_SECRET_KEY")
 
-	// get the JWT secret key expiration in minutes from .env file
-	minutesCount, _ := strconv.Atoi(os.Getenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT"))
+	// generate a unique token ID so the token can be revoked
+	id, err := randomString(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// get the issue and expiration times of the token
+	var (
+		issuedAt  time.Time = time.Now()
+		expiresAt time.Time = issuedAt.Add(AccessTokenLifetime())
+	)
 
 	// create a JWT claim
 	claims := jwt.MapClaims{}
 
 	// assign an expiration time for the token
-	claims["exp"] = time.Now().Add(time.Minute * time.Duration(minutesCount)).Unix()
+	claims["exp"] = expiresAt.Unix()
+	// assign the issue time, the milliseconds order the tokens issued in the same second
+	claims["iat"] = float64(issuedAt.UnixMilli()) / 1000
+	// assign the token ID
+	claims["jti"] = id
 	// assign a data for user ID
 	claims["userId"] = userId
 
-	// create a JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// convert the JWT token into the string
-	t, err := token.SignedString([]byte(secret))
+	// sign the JWT token with the HS256 secret unless signing keys are used
+	var t string
+	if keys := CurrentKeySet(); keys != nil {
+		t, err = signWithKeySet(keys, claims, issuedAt)
+	} else {
+		t, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	}
 
 	// if conversion is failed, return an error
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	// return the generated JWT token
-	return t, nil
+	return t, expiresAt, nil
+}
+
+// GenerateRefreshToken generates a new opaque refresh token
+// only the hash of the token should be stored
+func GenerateRefreshToken() (token string, hash string, err error) {
+	token, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, HashToken(token), nil
+}
+
+// randomString returns size random bytes encoded so they can be sent in JSON and URLs
+func randomString(size int) (string, error) {
+	var data []byte = make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// AccessTokenLifetime returns how long an access token is valid
+func AccessTokenLifetime() time.Duration {
+	// get the JWT secret key expiration in minutes from .env file
+	minutesCount, _ := strconv.Atoi(os.Getenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT"))
+
+	return time.Minute * time.Duration(minutesCount)
+}
+
+// HashToken returns the hash of a refresh token as it is stored
+func HashToken(token string) string {
+	var sum [32]byte = sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenLifetime returns how long a refresh token is valid
+func RefreshTokenLifetime() time.Duration {
+	// get the refresh token expiration in hours from .env file
+	hoursCount, err := strconv.Atoi(os.Getenv("JWT_REFRESH_KEY_EXPIRE_HOURS_COUNT"))
+
+	// use 30 days if the expiration is not configured
+	if err != nil || hoursCount <= 0 {
+		hoursCount = 720
+	}
+
+	return time.Hour * time.Duration(hoursCount)
 }
 
 // CheckToken checks JWT token
 func CheckToken(r *http.Request) (*TokenMetadata, error) {
+	return CheckAuthorization(r.Header.Get("Authorization"))
+}
+
+// CheckAuthorization checks the JWT token of an Authorization header value
+// the websocket clients send it when the connection is initialized
+func CheckAuthorization(header string) (*TokenMetadata, error) {
 	// get the current time
 	var now int64 = time.Now().Unix()
 
 	// extract the JWT token metadata
-	claims, err := ExtractTokenMetadata(r)
+	claims, err := extractHeaderMetadata(header)
 	// if extraction is failed, return an error
 	if err != nil {
 		return nil, err
@@ -106,9 +199,9 @@ func CheckToken(r *http.Request) (*TokenMetadata, error) {
 }
 
 // verifyToken verifies JWT token
-func verifyToken(r *http.Request) (*jwt.Token, error) {
+func verifyToken(header string) (*jwt.Token, error) {
 	// get the token
-	var tokenString string = extractToken(r)
+	var tokenString string = extractToken(header)
 
 	// parse the JWT token
 	token, err := jwt.Parse(tokenString, jwtKeyFunc)
@@ -123,9 +216,7 @@ func verifyToken(r *http.Request) (*jwt.Token, error) {
 }
 
 // extractToken extracts JWT token from the Authorization header
-func extractToken(r *http.Request) string {
-	// get the Authorization header
-	var header string = r.Header.Get("Authorization")
+func extractToken(header string) string {
 	// split the content inside the header to get the JWT token
 	token := strings.Split(header, " ")
 
@@ -141,7 +232,39 @@ func extractToken(r *http.Request) string {
 	return token[1]
 }
 
-// jwtKeyFunc return JWT secret key
+// signWithKeySet signs the claims with the active key of the key set
+func signWithKeySet(keys *KeySet, claims jwt.MapClaims, now time.Time) (string, error) {
+	key, err := keys.SigningKey(now)
+	if err != nil {
+		return "", err
+	}
+
+	// create a JWT token identifying its key
+	token := jwt.NewWithClaims(key.signingMethod(), claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Private)
+}
+
+// jwtKeyFunc returns the key verifying the JWT token
+// the algorithm of the token must be the algorithm of the key
 func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	// find the public key by the key ID of the token
+	if keys := CurrentKeySet(); keys != nil {
+		id, _ := token.Header["kid"].(string)
+		key, ok := keys.Key(id)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Private.Public(), nil
+	}
+
+	if token.Method != jwt.SigningMethodHS256 {
+		return nil, errors.New("unexpected signing method")
+	}
+
 	return []byte(os.Getenv("JWT_SECRET_KEY")), nil
 }
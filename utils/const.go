@@ -5,3 +5,27 @@ const USER_COLLECTION = "users"
 
 // blog collection
 const BLOG_COLLECTION = "blogs"
+
+// refresh token collection
+const REFRESH_TOKEN_COLLECTION = "refresh_tokens"
+
+// revoked token collection
+const REVOKED_TOKEN_COLLECTION = "revoked_tokens"
+
+// comment collection
+const COMMENT_COLLECTION = "comments"
+
+// blog revision collection
+const REVISION_COLLECTION = "blog_revisions"
+
+// blog reaction collection
+const REACTION_COLLECTION = "blog_reactions"
+
+// follow collection
+const FOLLOW_COLLECTION = "follows"
+
+// failed login collection
+const LOGIN_FAILURE_COLLECTION = "login_failures"
+
+// audit record collection
+const AUDIT_COLLECTION = "audit_records"
@@ -0,0 +1,427 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// keyFileExtension is the extension of the key files, the file name is the key ID
+const keyFileExtension = ".pem"
+
+// keyTimeLayout is the layout of the activation time starting the key IDs
+const keyTimeLayout = "20060102T150405Z"
+
+// JWKSMaxAge is how long the verifiers may cache the published keys
+// a new key is published at least this long before it signs any token
+const JWKSMaxAge = 5 * time.Minute
+
+// supported asymmetric signing algorithms
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// SigningKey represents a private key used to sign the access tokens
+type SigningKey struct {
+	// ID is sent in the "kid" header of the tokens
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	// ActiveFrom is when the key starts signing, it is read from the start of the key ID
+	ActiveFrom time.Time
+}
+
+// KeySet represents the signing keys loaded from a key directory
+// the active key with the latest activation time signs the new tokens,
+// the other keys still verify the tokens they have signed
+// it is safe for concurrent use
+type KeySet struct {
+	dir       string
+	algorithm string
+	rotation  time.Duration
+
+	mu   sync.RWMutex
+	keys []*SigningKey
+}
+
+// currentKeySet is the key set used to sign and verify the access tokens
+// the tokens are signed with the HS256 secret when it is nil
+var currentKeySet atomic.Pointer[KeySet]
+
+// UseKeySet makes the key set sign and verify the access tokens
+func UseKeySet(keys *KeySet) {
+	currentKeySet.Store(keys)
+}
+
+// CurrentKeySet returns the key set signing the access tokens, nil if the HS256 secret is used
+func CurrentKeySet() *KeySet {
+	return currentKeySet.Load()
+}
+
+// NewKeySet loads the keys of the directory
+// when rotation is positive, a new key of the algorithm is generated
+// every rotation period and the keys that cannot have signed a valid token are removed
+func NewKeySet(dir string, algorithm string, rotation time.Duration) (*KeySet, error) {
+	if algorithm != RS256 && algorithm != ES256 && algorithm != EdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	var keys *KeySet = &KeySet{dir: dir, algorithm: algorithm, rotation: rotation}
+
+	if err := keys.Rotate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Load reads the keys of the directory, replacing the keys read before
+func (s *KeySet) Load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var keys []*SigningKey
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != keyFileExtension {
+			continue
+		}
+
+		key, err := readSigningKey(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("read key %s: %w", entry.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+
+	// sort the keys by activation time
+	sort.Slice(keys, func(a, b int) bool {
+		if !keys[a].ActiveFrom.Equal(keys[b].ActiveFrom) {
+			return keys[a].ActiveFrom.Before(keys[b].ActiveFrom)
+		}
+		return keys[a].ID < keys[b].ID
+	})
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Rotate reloads the directory, schedules a new key when the signing key is
+// older than the rotation period and removes the retired keys
+// the new key is published for the time the verifiers cache the keys before it signs,
+// only a directory without any active key gets a key signing right away
+func (s *KeySet) Rotate(now time.Time) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+
+	var activeFrom time.Time
+	current, err := s.SigningKey(now)
+	switch {
+	case err != nil:
+		activeFrom = now.Truncate(time.Second)
+	case s.rotation <= 0 || now.Sub(current.ActiveFrom) < s.rotation || s.scheduled(now):
+		return s.retire(now)
+	default:
+		// the activation time is rounded up to the second of the key ID
+		activeFrom = now.Add(JWKSMaxAge + time.Second).Truncate(time.Second)
+	}
+
+	// generate the next signing key
+	if _, err := generateSigningKey(s.dir, s.algorithm, activeFrom); err != nil {
+		return err
+	}
+
+	if err := s.Load(); err != nil {
+		return err
+	}
+
+	return s.retire(now)
+}
+
+// Run rotates the keys at every interval until the context is done
+func (s *KeySet) Run(ctx context.Context, interval time.Duration) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.Rotate(now); err != nil {
+				log.Printf("rotate signing keys failed: %v", err)
+			}
+		}
+	}
+}
+
+// retire removes the keys replaced long enough ago that every token they signed has expired
+// the keys are only removed when the rotation is automatic
+func (s *KeySet) retire(now time.Time) error {
+	if s.rotation <= 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	var retired []*SigningKey
+	for i, key := range s.keys {
+		// the key was replaced when the next key became active
+		if i+1 < len(s.keys) && !s.keys[i+1].ActiveFrom.After(now) && now.Sub(s.keys[i+1].ActiveFrom) > AccessTokenLifetime() {
+			retired = append(retired, key)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(retired) == 0 {
+		return nil
+	}
+
+	for _, key := range retired {
+		if err := os.Remove(filepath.Join(s.dir, key.ID+keyFileExtension)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return s.Load()
+}
+
+// scheduled reports whether a key starts signing after the given time
+func (s *KeySet) scheduled(now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// the keys are sorted by activation time
+	return len(s.keys) > 0 && s.keys[len(s.keys)-1].ActiveFrom.After(now)
+}
+
+// SigningKey returns the key signing the new tokens
+func (s *KeySet) SigningKey(now time.Time) (*SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// the keys are sorted by activation time
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].ActiveFrom.After(now) {
+			return s.keys[i], nil
+		}
+	}
+
+	return nil, errors.New("no active signing key")
+}
+
+// Key returns the key with the given ID
+func (s *KeySet) Key(id string) (*SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// JSONWebKey represents a public key in the JWK format (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet represents a set of public keys in the JWK format
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, including the keys that are not active yet
+// so the verifiers know them before they sign any token
+func (s *KeySet) JWKS() JSONWebKeySet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var set JSONWebKeySet = JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(s.keys))}
+	for _, key := range s.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+
+	return set
+}
+
+// jwk returns the public key in the JWK format
+func (k *SigningKey) jwk() JSONWebKey {
+	var (
+		encode = base64.RawURLEncoding.EncodeToString
+		jwk    = JSONWebKey{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	)
+
+	switch public := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		var size int = (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = encode(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(public)
+	}
+
+	return jwk
+}
+
+// signingMethod returns the JWT signing method of the key
+func (k *SigningKey) signingMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// readSigningKey reads a PEM encoded private key, the algorithm depends on the type of key
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var private interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var id string = strings.TrimSuffix(filepath.Base(path), keyFileExtension)
+	var key *SigningKey = &SigningKey{ID: id, ActiveFrom: keyActivation(id)}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.Private = RS256, private
+	case *ecdsa.PrivateKey:
+		if private.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 ECDSA keys are supported")
+		}
+		key.Algorithm, key.Private = ES256, private
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Private = EdDSA, private
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
+
+// keyActivation returns the activation time starting the key ID
+// the keys whose ID does not start with a time sign from the start
+func keyActivation(id string) time.Time {
+	prefix, _, _ := strings.Cut(id, "-")
+
+	activeFrom, err := time.Parse(keyTimeLayout, prefix)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return activeFrom
+}
+
+// generateSigningKey writes a new key of the algorithm into the directory
+// the key ID starts with the activation time so the files sort by age
+func generateSigningKey(dir string, algorithm string, activeFrom time.Time) (string, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	var suffix []byte = make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	var id string = activeFrom.UTC().Format(keyTimeLayout) + "-" + hex.EncodeToString(suffix)
+
+	// write the key into a temporary file first so a partial key is never loaded
+	file, err := os.CreateTemp(dir, ".key-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(file.Name(), filepath.Join(dir, id+keyFileExtension)); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// useTestKeySet signs the tokens with the key set until the test ends
+func useTestKeySet(t *testing.T, keys *KeySet) {
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	t.Setenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT", "15")
+
+	UseKeySet(keys)
+	t.Cleanup(func() { UseKeySet(nil) })
+}
+
+// checkToken verifies the token as it is sent by a client
+func checkToken(token string) (*TokenMetadata, error) {
+	var r = httptest.NewRequest("POST", "/query", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return CheckToken(r)
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{RS256, ES256, EdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			keys, err := NewKeySet(t.TempDir(), algorithm, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			useTestKeySet(t, keys)
+
+			token, _, err := GenerateAccessToken("user")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := checkToken(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.UserId != "user" || data.ID == "" {
+				t.Fatalf("unexpected token data: %+v", data)
+			}
+
+			var set JSONWebKeySet = keys.JWKS()
+			if len(set.Keys) != 1 || set.Keys[0].Algorithm != algorithm || set.Keys[0].KeyID == "" {
+				t.Fatalf("unexpected key set: %+v", set)
+			}
+		})
+	}
+}
+
+func TestKeySet_RejectsSecretTokens(t *testing.T) {
+	keys, err := NewKeySet(t.TempDir(), EdDSA, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTestKeySet(t, keys)
+
+	// a token signed with the shared secret is no longer accepted
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":    time.Now().Add(time.Minute).Unix(),
+		"userId": "user",
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := checkToken(token); err == nil {
+		t.Fatal("expected the token to be rejected")
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	var dir string = t.TempDir()
+
+	keys, err := NewKeySet(dir, ES256, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTestKeySet(t, keys)
+
+	var now time.Time = time.Now()
+	first, err := keys.SigningKey(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new key is scheduled once the rotation period has passed
+	// and signs once the verifiers have had the time to fetch it
+	if err := keys.Rotate(now.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if current, err := keys.SigningKey(now.Add(2 * time.Hour)); err != nil || current.ID != first.ID {
+		t.Fatalf("expected the first key to keep signing, got %+v (%v)", current, err)
+	}
+
+	second, err := keys.SigningKey(now.Add(2*time.Hour + JWKSMaxAge + time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("expected a new signing key")
+	}
+
+	// the scheduled key is not scheduled again
+	if err := keys.Rotate(now.Add(2*time.Hour + time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if set := keys.JWKS(); len(set.Keys) != 2 {
+		t.Fatalf("expected two keys, got %+v", set)
+	}
+
+	// the replaced key verifies the tokens it signed until they expire
+	if _, ok := keys.Key(first.ID); !ok {
+		t.Fatal("expected the replaced key to be kept")
+	}
+
+	if err := keys.Rotate(now.Add(2*time.Hour + 30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keys.Key(first.ID); ok {
+		t.Fatal("expected the replaced key to be retired")
+	}
+	if _, err := os.Stat(dir + "/" + first.ID + keyFileExtension); !os.IsNotExist(err) {
+		t.Fatalf("expected the key file to be removed, got %v", err)
+	}
+}
+
+func TestKeySet_RotationKeepsCachedKeys(t *testing.T) {
+	keys, err := NewKeySet(t.TempDir(), EdDSA, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		now    time.Time     = time.Now()
+		claims jwt.MapClaims = jwt.MapClaims{"exp": now.Add(4 * time.Hour).Unix(), "userId": "user"}
+	)
+
+	// a verifier caches the keys published before the rotation
+	var cached JSONWebKeySet = keys.JWKS()
+
+	if err := keys.Rotate(now.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := signWithKeySet(keys, claims, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyWithKeys(token, cached); err != nil {
+		t.Fatalf("expected the cached keys to verify the token signed after the rotation: %v", err)
+	}
+
+	// a verifier caching the keys right after the rotation knows the next key
+	// before the cache expires
+	cached = keys.JWKS()
+
+	token, err = signWithKeySet(keys, claims, now.Add(2*time.Hour+JWKSMaxAge+time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyWithKeys(token, cached); err != nil {
+		t.Fatalf("expected the cached keys to verify the token of the next key: %v", err)
+	}
+}
+
+func TestKeySet_ActivationInKeyID(t *testing.T) {
+	var (
+		dir        string    = t.TempDir()
+		activeFrom time.Time = time.Now().Add(time.Hour).Truncate(time.Second)
+	)
+
+	keys, err := NewKeySet(dir, EdDSA, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key written with a future activation time is published but does not sign yet
+	id, err := generateSigningKey(dir, EdDSA, activeFrom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	key, ok := keys.Key(id)
+	if !ok || !key.ActiveFrom.Equal(activeFrom) {
+		t.Fatalf("expected the key to be active from %v, got %+v", activeFrom, key)
+	}
+	if current, err := keys.SigningKey(time.Now()); err != nil || current.ID == id {
+		t.Fatalf("expected the scheduled key not to sign yet, got %+v (%v)", current, err)
+	}
+	if current, err := keys.SigningKey(activeFrom); err != nil || current.ID != id {
+		t.Fatalf("expected the scheduled key to sign from its activation time, got %+v (%v)", current, err)
+	}
+
+	// the keys named without a time sign from the start
+	if activation := keyActivation("imported"); !activation.IsZero() {
+		t.Fatalf("expected no activation time, got %v", activation)
+	}
+}
+
+// verifyWithKeys verifies the EdDSA token with the published keys as a verifier does
+func verifyWithKeys(token string, set JSONWebKeySet) error {
+	_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		id, _ := token.Header["kid"].(string)
+		for _, key := range set.Keys {
+			if key.KeyID == id {
+				x, err := base64.RawURLEncoding.DecodeString(key.X)
+				return ed25519.PublicKey(x), err
+			}
+		}
+		return nil, fmt.Errorf("unknown key %q", id)
+	})
+
+	return err
+}